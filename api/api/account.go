@@ -0,0 +1,108 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+)
+
+// PasswordResetInput represents the input to the RequestPasswordReset function
+type PasswordResetInput struct {
+	Email string `json:"email"`
+}
+
+// RequestPasswordReset emails a password reset link to the email address in the HTTP
+// request. It responds the same whether or not a user has that address.
+func (a *API) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var input PasswordResetInput
+
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := json.Unmarshal(body, &input); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := a.App.RequestPasswordReset(input.Email); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err := respondWithJSON(w, http.StatusAccepted, ""); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// ConfirmPasswordResetInput represents the input to the ConfirmPasswordReset function
+type ConfirmPasswordResetInput struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// ConfirmPasswordReset sets a new password for the user the emailed token in the HTTP
+// request was issued to, and signs them out everywhere.
+func (a *API) ConfirmPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var input ConfirmPasswordResetInput
+
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := json.Unmarshal(body, &input); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := a.App.ResetPassword(input.Token, input.Password); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err := respondWithJSON(w, http.StatusNoContent, ""); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// VerifyEmailInput represents the input to the VerifyEmail function
+type VerifyEmailInput struct {
+	Token string `json:"token"`
+}
+
+// VerifyEmail marks the email address the emailed token in the HTTP request was issued
+// for as verified, and responds with its user.
+func (a *API) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var input VerifyEmailInput
+
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := json.Unmarshal(body, &input); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	user, err := a.App.VerifyEmail(input.Token)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err = respondWithJSON(w, http.StatusOK, user); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
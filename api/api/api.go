@@ -3,19 +3,20 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/shaj13/go-guardian/auth"
-	"github.com/shaj13/go-guardian/auth/strategies/token"
-	"github.com/shaj13/go-guardian/store"
 
 	"leggett.dev/devmarks/api/app"
 	myAuth "leggett.dev/devmarks/api/auth"
 	"leggett.dev/devmarks/api/helpers"
 	"leggett.dev/devmarks/api/log"
+	"leggett.dev/devmarks/api/model"
 )
 
 // API is an object representing our API's configuration, and includes a pointer
@@ -23,6 +24,7 @@ import (
 type API struct {
 	App    *app.App
 	Config *Config
+	Logger log.Logger
 }
 
 // New returns a new API object from our App's App object
@@ -37,11 +39,14 @@ func New(a *app.App) (api *API, err error) {
 
 func (a *API) setupGoGuardian() {
 	a.App.Authenticator = auth.New()
-	a.App.AuthCache = store.NewFIFO(context.Background(), time.Minute*10)
 
-	tokenStrategy := token.New(token.NoOpAuthenticate, a.App.AuthCache)
+	// personal access tokens are always opaque tokens, so both strategies are enabled
+	// whichever kind of token logins hand out.
+	tokenStrategy := myAuth.NewTokenStrategy(a.App)
+	jwtStrategy := myAuth.NewJWTStrategy(a.App)
 
-	a.App.Authenticator.EnableStrategy(token.CachedStrategyKey, tokenStrategy)
+	a.App.Authenticator.EnableStrategy(myAuth.TokenStrategyKey, tokenStrategy)
+	a.App.Authenticator.EnableStrategy(myAuth.JWTStrategyKey, jwtStrategy)
 }
 
 // used to set any options on the http traffic, i.e. response headers,
@@ -73,33 +78,160 @@ func (a *API) Init(r *mux.Router) {
 	// authentication
 	a.setupGoGuardian()
 	logger := log.NewLogger(a.Config.ProxyCount)
+	a.Logger = logger
 	r.Use(logger.LoggerMiddleware)
-	authSvc := myAuth.NewAuth(&[]string{"/users", "/auth/token", "/static/openapi.yml", "/static/redoc.html"}, *a.App, &logger)
+	authSvc := myAuth.NewAuth(&[]string{"/users", "/auth/token", "/auth/token/mfa", "/auth/refresh", "/auth/oidc/login", "/auth/oidc/callback", "/auth/password-reset", "/auth/password-reset/confirm", "/auth/verify-email", "/static/openapi.yml", "/static/redoc.html", "/p/", "/folders/*/feed.atom", "/folders/*/feed.rss", "/folders/*/feed.json", "/tags/*/feed.atom", "/tags/*/feed.rss", "/tags/*/feed.json"}, scopeRules(), *a.App, &logger)
 	r.Use(authSvc.AuthMiddleware)
 	r.Use(apiMiddleware)
 
 	r.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.Dir("."))))
 
 	r.HandleFunc("/auth/token", a.createToken).Methods("POST")
+	r.HandleFunc("/auth/token/mfa", a.createTokenWithMFA).Methods("POST")
+	r.HandleFunc("/auth/refresh", a.refreshToken).Methods("POST")
+	r.HandleFunc("/auth/logout", a.Logout).Methods("POST")
+	r.HandleFunc("/auth/oidc/login", a.OIDCLogin).Methods("GET")
+	r.HandleFunc("/auth/oidc/callback", a.OIDCCallback).Methods("GET")
+	r.HandleFunc("/auth/password-reset", a.RequestPasswordReset).Methods("POST")
+	r.HandleFunc("/auth/password-reset/confirm", a.ConfirmPasswordReset).Methods("POST")
+	r.HandleFunc("/auth/verify-email", a.VerifyEmail).Methods("POST")
 
 	// user methods
 	r.HandleFunc("/users", a.CreateUser).Methods("POST")
 	r.HandleFunc("/me", a.GetUser).Methods("GET")
+	r.HandleFunc("/me", a.UpdateProfile).Methods("PATCH")
+	r.HandleFunc("/me", a.DeleteAccount).Methods("DELETE")
+	r.HandleFunc("/me/password", a.ChangePassword).Methods("POST")
+	r.HandleFunc("/me/export", a.ExportArchive).Methods("GET")
+	r.HandleFunc("/me/import", a.ImportArchive).Methods("POST")
+	r.HandleFunc("/me/tokens", a.GetTokens).Methods("GET")
+	r.HandleFunc("/me/tokens", a.CreatePersonalToken).Methods("POST")
+	r.HandleFunc("/me/tokens/{id:[0-9]+}", a.RevokeTokenByID).Methods("DELETE")
+	r.HandleFunc("/me/mfa/totp", a.EnrollTOTP).Methods("POST")
+	r.HandleFunc("/me/mfa/totp/confirm", a.ConfirmTOTP).Methods("POST")
+	r.HandleFunc("/me/mfa/totp", a.DisableTOTP).Methods("DELETE")
 
 	// bookmark methods
 	bookmarksRouter := r.PathPrefix("/bookmarks").Subrouter()
 	bookmarksRouter.HandleFunc("", a.GetBookmarks).Methods("GET")
 	bookmarksRouter.HandleFunc("", a.CreateBookmark).Methods("POST")
+	bookmarksRouter.HandleFunc("/bulk", a.BulkUpdateBookmarks).Methods("POST")
 	bookmarksRouter.HandleFunc("/{id:[0-9]+}", a.GetBookmarkByID).Methods("GET")
 	bookmarksRouter.HandleFunc("/{id:[0-9]+}", a.UpdateBookmarkByID).Methods("PATCH")
 	bookmarksRouter.HandleFunc("/{id:[0-9]+}", a.DeleteBookmarkByID).Methods("DELETE")
+	bookmarksRouter.HandleFunc("/{id:[0-9]+}/follow-redirect", a.FollowBookmarkRedirect).Methods("POST")
 
 	foldersRouter := r.PathPrefix("/folders").Subrouter()
 	foldersRouter.HandleFunc("", a.GetFolders).Methods("GET")
+	foldersRouter.HandleFunc("/tree", a.GetFolderTree).Methods("GET")
+	foldersRouter.HandleFunc("/{id:[0-9]+}/tree", a.GetFolderSubtree).Methods("GET")
 	foldersRouter.HandleFunc("/{id:[0-9]+}", a.GetFolderByID).Methods("GET")
 	foldersRouter.HandleFunc("", a.CreateFolder).Methods("POST")
+	foldersRouter.HandleFunc("/{id:[0-9]+}", a.UpdateFolderByID).Methods("PATCH")
+	foldersRouter.HandleFunc("/{id:[0-9]+}", a.DeleteFolderByID).Methods("DELETE")
 	foldersRouter.HandleFunc("/{id:[0-9]+}/bookmarks/{bid:[0-9]+}", a.AddBookmarkToFolder).Methods("PATCH")
+	foldersRouter.HandleFunc("/{id:[0-9]+}/bookmarks/{bid:[0-9]+}", a.RemoveBookmarkFromFolder).Methods("DELETE")
+	foldersRouter.HandleFunc("/{id:[0-9]+}/shares", a.GetShares).Methods("GET")
+	foldersRouter.HandleFunc("/{id:[0-9]+}/shares", a.ShareFolder).Methods("POST")
+	foldersRouter.HandleFunc("/{id:[0-9]+}/shares/{uid:[0-9]+}", a.UpdateShare).Methods("PATCH")
+	foldersRouter.HandleFunc("/{id:[0-9]+}/shares/{uid:[0-9]+}", a.Unshare).Methods("DELETE")
+	foldersRouter.HandleFunc("/{id:[0-9]+}/public", a.PublishFolder).Methods("POST")
+	foldersRouter.HandleFunc("/{id:[0-9]+}/public", a.UnpublishFolder).Methods("DELETE")
+	foldersRouter.HandleFunc("/{id:[0-9]+}/feed", a.CreateFolderFeedToken).Methods("POST")
+	foldersRouter.HandleFunc("/{id:[0-9]+}/feed", a.RevokeFolderFeedToken).Methods("DELETE")
+	foldersRouter.HandleFunc("/{id:[0-9]+}/feed.{format:atom|rss|json}", a.GetFolderFeed).Methods("GET")
+
+	r.HandleFunc("/shared", a.GetSharedFolders).Methods("GET")
+
+	// public folder pages, which need no authentication
+	r.HandleFunc("/p/{slug:[A-Za-z0-9_-]+}", a.GetPublicFolder).Methods("GET", "POST")
+
+	r.HandleFunc("/search", a.SearchBookmarks).Methods("GET")
+
+	r.HandleFunc("/import/netscape", a.ImportNetscape).Methods("POST")
+	r.HandleFunc("/export/netscape", a.ExportNetscape).Methods("GET")
+
+	organizationsRouter := r.PathPrefix("/organizations").Subrouter()
+	organizationsRouter.HandleFunc("", a.GetOrganizations).Methods("GET")
+	organizationsRouter.HandleFunc("", a.CreateOrganization).Methods("POST")
+	organizationsRouter.HandleFunc("/{id:[0-9]+}", a.GetOrganizationByID).Methods("GET")
+	organizationsRouter.HandleFunc("/{id:[0-9]+}", a.UpdateOrganizationByID).Methods("PATCH")
+	organizationsRouter.HandleFunc("/{id:[0-9]+}", a.DeleteOrganizationByID).Methods("DELETE")
+	organizationsRouter.HandleFunc("/{id:[0-9]+}/folders", a.GetOrganizationFolders).Methods("GET")
+	organizationsRouter.HandleFunc("/{id:[0-9]+}/members", a.GetMembers).Methods("GET")
+	organizationsRouter.HandleFunc("/{id:[0-9]+}/members", a.AddMember).Methods("POST")
+	organizationsRouter.HandleFunc("/{id:[0-9]+}/members/{uid:[0-9]+}", a.UpdateMember).Methods("PATCH")
+	organizationsRouter.HandleFunc("/{id:[0-9]+}/members/{uid:[0-9]+}", a.RemoveMember).Methods("DELETE")
+
+	// tag methods
+	tagsRouter := r.PathPrefix("/tags").Subrouter()
+	tagsRouter.HandleFunc("", a.GetTags).Methods("GET")
+	tagsRouter.HandleFunc("", a.CreateTag).Methods("POST")
+	tagsRouter.HandleFunc("/{id:[0-9]+}", a.GetTagByID).Methods("GET")
+	tagsRouter.HandleFunc("/{id:[0-9]+}", a.UpdateTagByID).Methods("PATCH")
+	tagsRouter.HandleFunc("/{id:[0-9]+}", a.DeleteTagByID).Methods("DELETE")
+	tagsRouter.HandleFunc("/{id:[0-9]+}/feed", a.CreateTagFeedToken).Methods("POST")
+	tagsRouter.HandleFunc("/{id:[0-9]+}/feed", a.RevokeTagFeedToken).Methods("DELETE")
+	tagsRouter.HandleFunc("/{id:[0-9]+}/feed.{format:atom|rss|json}", a.GetTagFeed).Methods("GET")
+
+	// admin methods
+	adminRouter := r.PathPrefix("/admin").Subrouter()
+	adminRouter.HandleFunc("/invites", a.GetInvites).Methods("GET")
+	adminRouter.HandleFunc("/invites", a.CreateInvite).Methods("POST")
 }
+
+// scopeRules returns the scopes personal access tokens need to call each group of routes.
+func scopeRules() []myAuth.ScopeRule {
+	return []myAuth.ScopeRule{
+		{Prefix: "/auth/logout"},
+		{Prefix: "/me", Write: model.ScopeAdmin},
+		{Prefix: "/me/export", Read: model.ScopeBookmarksRead, Write: model.ScopeBookmarksRead},
+		{Prefix: "/me/tokens", Read: model.ScopeAdmin, Write: model.ScopeAdmin},
+		{Prefix: "/me/mfa", Read: model.ScopeAdmin, Write: model.ScopeAdmin},
+		{Prefix: "/bookmarks", Read: model.ScopeBookmarksRead, Write: model.ScopeBookmarksWrite},
+		{Prefix: "/tags", Read: model.ScopeBookmarksRead, Write: model.ScopeBookmarksWrite},
+		{Prefix: "/folders", Read: model.ScopeBookmarksRead, Write: model.ScopeFoldersWrite},
+		{Prefix: "/organizations", Read: model.ScopeBookmarksRead, Write: model.ScopeAdmin},
+		{Prefix: "/shared", Read: model.ScopeBookmarksRead, Write: model.ScopeBookmarksRead},
+		{Prefix: "/search", Read: model.ScopeBookmarksRead, Write: model.ScopeBookmarksRead},
+		{Prefix: "/import", Read: model.ScopeBookmarksWrite, Write: model.ScopeBookmarksWrite},
+		{Prefix: "/export", Read: model.ScopeBookmarksRead, Write: model.ScopeBookmarksRead},
+		{Prefix: "/admin", Read: model.ScopeAdmin, Write: model.ScopeAdmin},
+	}
+}
+
+// newContext returns the app.Context of the request, holding the currently
+// authenticated user, through which handlers access resources.
+func (a *API) newContext(r *http.Request) *app.Context {
+	ctx := a.App.NewContext().WithContext(r.Context()).WithRemoteAddress(r.RemoteAddr)
+	if user := myAuth.GetUser(r.Context()); user != nil {
+		ctx = ctx.WithUser(user)
+	}
+	return ctx
+}
+
+// respondWithAppError writes the error returned by a service method of app.Context with
+// a status code matching its kind.
+func respondWithAppError(w http.ResponseWriter, err error) {
+	switch err := err.(type) {
+	case *app.RateLimitError:
+		setRetryAfter(w, err.RetryAfter)
+		respondWithError(w, http.StatusTooManyRequests, err.Message)
+	case *app.UserError:
+		respondWithError(w, err.StatusCode, err.Message)
+	case *app.ValidationError:
+		respondWithError(w, http.StatusUnprocessableEntity, err.Message)
+	default:
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// setRetryAfter tells the client to wait d, rounded up to whole seconds, before trying
+// again.
+func setRetryAfter(w http.ResponseWriter, d time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(d.Seconds()))))
+}
+
 func respondWithError(w http.ResponseWriter, code int, message string) {
 	respondWithJSON(w, code, map[string]string{"error": message})
 }
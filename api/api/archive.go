@@ -0,0 +1,78 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"leggett.dev/devmarks/api/archive"
+	"leggett.dev/devmarks/api/db"
+)
+
+// ExportArchive writes everything owned by the currently authenticated user to the
+// HTTP response as a devmarks archive, which ImportArchive can restore on any instance.
+func (a *API) ExportArchive(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+
+	exported, err := ctx.ExportArchive()
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	filename := fmt.Sprintf("devmarks-%s.json", exported.ExportedAt.Format("2006-01-02"))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.WriteHeader(http.StatusOK)
+	archive.Write(w, exported)
+}
+
+// ImportArchive restores a devmarks archive for the currently authenticated user. The
+// archive can be sent as the raw request body or as the `file` field of a multipart
+// form. `?conflict=` sets what happens to folders and bookmarks that already exist:
+// skip, the default, overwrite or duplicate.
+func (a *API) ImportArchive(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+	conflict := r.URL.Query().Get("conflict")
+	if conflict == "" {
+		conflict = db.ConflictSkip
+	}
+
+	defer r.Body.Close()
+	var file io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		part, _, err := r.FormFile("file")
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "file is required")
+			return
+		}
+		defer part.Close()
+		file = part
+	}
+
+	imported, err := archive.Read(file)
+	if err != nil {
+		respondWithError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	result, err := ctx.ImportArchive(imported, conflict)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err = respondWithJSON(w, http.StatusCreated, result); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
@@ -5,26 +5,32 @@ import (
 	"io/ioutil"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
-	"leggett.dev/devmarks/api/auth"
 	"leggett.dev/devmarks/api/model"
 )
 
-// GetBookmarks returns the bookmarks corresponding to the currently authenticated user in json form
+// GetBookmarks returns a page of the bookmarks corresponding to the currently authenticated user
+// in json form, sorted and filtered according to the query parameters.
 func (a *API) GetBookmarks(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-	user := auth.GetUser(ctx)
-	if user == nil {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
 		respondWithError(w, http.StatusUnauthorized, "no user signed in")
 		return
 	}
-	bookmarks, err := a.App.Database.GetBookmarksByUserID(ctx, user.ID)
+	opts, err := parseListOptions(r, model.BookmarkValidSorts(), filterColor, filterCreatedAfter, filterFolderID, filterLinkStatus)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	bookmarks, page, err := ctx.GetUserBookmarks(opts)
+	if err != nil {
+		respondWithAppError(w, err)
 		return
 	}
 
+	writePageHeaders(w, r, page)
 	err = respondWithJSON(w, http.StatusOK, bookmarks)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, err.Error())
@@ -34,17 +40,18 @@ func (a *API) GetBookmarks(w http.ResponseWriter, r *http.Request) {
 
 // CreateBookmarkInput represents the input to the CreateBookmark function
 type CreateBookmarkInput struct {
-	Name  string `json:"name"`
-	URL   string `json:"url"`
-	Color string `json:"color"`
+	Name  string   `json:"name"`
+	URL   string   `json:"url"`
+	Color *string  `json:"color"`
+	Notes string   `json:"notes"`
+	Tags  []string `json:"tags"`
 }
 
 // CreateBookmark creates a new bookmark owned by the currently authenticated user based
 // on json from the HTTP Request
 func (a *API) CreateBookmark(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-	user := auth.GetUser(ctx)
-	if user == nil {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
 		respondWithError(w, http.StatusUnauthorized, "no user signed in")
 		return
 	}
@@ -62,18 +69,10 @@ func (a *API) CreateBookmark(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if input.URL == "" {
-		respondWithError(w, http.StatusUnprocessableEntity, "url is required")
-		return
-	}
-	if input.Name == "" {
-		respondWithError(w, http.StatusUnprocessableEntity, "name is required")
-		return
-	}
-	bookmark := &model.Bookmark{Name: input.Name, URL: input.URL, Color: &input.Color, OwnerID: user.ID}
+	bookmark := &model.Bookmark{Name: input.Name, URL: input.URL, Color: input.Color, Notes: input.Notes}
 
-	if err := a.App.Database.CreateBookmark(bookmark); err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+	if err := ctx.CreateBookmark(bookmark, input.Tags); err != nil {
+		respondWithAppError(w, err)
 		return
 	}
 
@@ -87,21 +86,15 @@ func (a *API) CreateBookmark(w http.ResponseWriter, r *http.Request) {
 // GetBookmarkByID writes the json representation of a bookmark to the HTTP Response Header,
 // if the currently authenticated user has access to it.
 func (a *API) GetBookmarkByID(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-	user := auth.GetUser(ctx)
-	if user == nil {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
 		respondWithError(w, http.StatusUnauthorized, "no user signed in")
 		return
 	}
 	id := getIDFromRequest(r)
-	bookmark, err := a.App.Database.GetBookmarkByID(ctx, id)
+	bookmark, err := ctx.GetBookmarkByID(id)
 	if err != nil {
-		respondWithError(w, http.StatusNotFound, err.Error())
-		return
-	}
-
-	if user.ID != bookmark.OwnerID {
-		respondWithError(w, http.StatusForbidden, "permission denied")
+		respondWithAppError(w, err)
 		return
 	}
 
@@ -114,17 +107,19 @@ func (a *API) GetBookmarkByID(w http.ResponseWriter, r *http.Request) {
 
 // UpdateBookmarkInput represents the input to the UpdateBookmark function
 type UpdateBookmarkInput struct {
-	Name  *string `json:"name"`
-	URL   *string `json:"url"`
-	Color *string `json:"color"`
+	Name  *string   `json:"name"`
+	URL   *string   `json:"url"`
+	Color *string   `json:"color"`
+	Notes *string   `json:"notes"`
+	Read  *bool     `json:"read"`
+	Tags  *[]string `json:"tags"`
 }
 
 // UpdateBookmarkByID updates the bookmark whose ID is specified in the HTTP request if it is owned
 // by the currently authenticated user.
 func (a *API) UpdateBookmarkByID(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-	user := auth.GetUser(ctx)
-	if user == nil {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
 		respondWithError(w, http.StatusUnauthorized, "no user signed in")
 		return
 	}
@@ -144,30 +139,39 @@ func (a *API) UpdateBookmarkByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	existingBookmark, err := a.App.Database.GetBookmarkByID(ctx, id)
-	if err != nil || existingBookmark == nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-
-	if user.ID != existingBookmark.OwnerID {
-		respondWithError(w, http.StatusForbidden, "permission denied")
+	existingBookmark, err := ctx.GetBookmarkByID(id)
+	if err != nil {
+		respondWithAppError(w, err)
 		return
 	}
 
 	if input.Name != nil {
 		existingBookmark.Name = *input.Name
 	}
-	if input.URL != nil {
+	if input.URL != nil && *input.URL != existingBookmark.URL {
 		existingBookmark.URL = *input.URL
+		// what was found checking the old URL says nothing about the new one.
+		existingBookmark.Link = model.BookmarkLink{}
 	}
 	if input.Color != nil {
 		existingBookmark.Color = input.Color
 	}
+	if input.Notes != nil {
+		existingBookmark.Notes = *input.Notes
+	}
+	if input.Read != nil {
+		if !*input.Read {
+			existingBookmark.ReadAt = nil
+		} else if existingBookmark.ReadAt == nil {
+			now := time.Now()
+			existingBookmark.ReadAt = &now
+		}
+	}
 
-	err = a.App.Database.UpdateBookmark(existingBookmark)
+	// a tags list replaces the bookmark's tags entirely; omitting it leaves them untouched.
+	err = ctx.UpdateBookmark(existingBookmark, input.Tags)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithAppError(w, err)
 		return
 	}
 
@@ -181,17 +185,16 @@ func (a *API) UpdateBookmarkByID(w http.ResponseWriter, r *http.Request) {
 // DeleteBookmarkByID deletes the bookmark whose ID is specified in the HTTP request if it is
 // owned by the currently authenticated user
 func (a *API) DeleteBookmarkByID(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-	user := auth.GetUser(ctx)
-	if user == nil {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
 		respondWithError(w, http.StatusUnauthorized, "no user signed in")
 		return
 	}
 	id := getIDFromRequest(r)
-	err := a.App.Database.DeleteBookmarkByID(id)
+	err := ctx.DeleteBookmarkByID(id)
 
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithAppError(w, err)
 		return
 	}
 
@@ -225,3 +228,25 @@ func getBIDFromRequest(r *http.Request) uint {
 
 	return uint(intID)
 }
+
+// FollowBookmarkRedirect changes the URL of the bookmark whose ID is specified in the
+// HTTP request to the URL its link was found to permanently redirect to.
+func (a *API) FollowBookmarkRedirect(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+	id := getIDFromRequest(r)
+	bookmark, err := ctx.FollowBookmarkRedirect(id)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	err = respondWithJSON(w, http.StatusOK, bookmark)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
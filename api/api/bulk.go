@@ -0,0 +1,51 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"leggett.dev/devmarks/api/app"
+)
+
+// BulkInput represents the input to the BulkUpdateBookmarks function
+type BulkInput struct {
+	IDs        []uint              `json:"ids"`
+	Operations []app.BulkOperation `json:"operations"`
+}
+
+// BulkUpdateBookmarks applies a list of operations to a list of bookmarks in a single
+// transaction, and responds with the outcome for each bookmark. Bookmarks that do not
+// exist or that the currently authenticated user cannot change are skipped and
+// reported; any other failure rolls back the whole request.
+func (a *API) BulkUpdateBookmarks(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+	var input BulkInput
+
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := json.Unmarshal(body, &input); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	results, err := ctx.BulkUpdateBookmarks(input.IDs, input.Operations)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err = respondWithJSON(w, http.StatusOK, results); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
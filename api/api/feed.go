@@ -0,0 +1,170 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/mux"
+
+	"leggett.dev/devmarks/api/feed"
+	"leggett.dev/devmarks/api/model"
+)
+
+// FeedTokenResponse is written to the HTTP response when a feed token is created,
+// along with the URLs of the feed in each format. It is the only response that ever
+// contains the token itself.
+type FeedTokenResponse struct {
+	*model.FeedToken
+	Secret string `json:"token"`
+	Atom   string `json:"atom_url"`
+	RSS    string `json:"rss_url"`
+	JSON   string `json:"json_url"`
+}
+
+func feedTokenResponse(r *http.Request, resource string, id uint, secret string, token *model.FeedToken) *FeedTokenResponse {
+	base := fmt.Sprintf("%s/%s/%d/feed", requestBaseURL(r), resource, id)
+	query := "?token=" + url.QueryEscape(secret)
+	return &FeedTokenResponse{
+		FeedToken: token,
+		Secret:    secret,
+		Atom:      base + ".atom" + query,
+		RSS:       base + ".rss" + query,
+		JSON:      base + ".json" + query,
+	}
+}
+
+// requestBaseURL returns the scheme and host the request was made to, as seen by the
+// client, to build absolute URLs from.
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// CreateFolderFeedToken issues the currently authenticated user a token for the feed
+// of the folder whose ID is specified in the HTTP request, replacing the previous one.
+func (a *API) CreateFolderFeedToken(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+	id := getIDFromRequest(r)
+
+	secret, token, err := ctx.CreateFolderFeedToken(id)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err = respondWithJSON(w, http.StatusCreated, feedTokenResponse(r, "folders", id, secret, token)); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// RevokeFolderFeedToken revokes the currently authenticated user's token for the feed
+// of the folder whose ID is specified in the HTTP request.
+func (a *API) RevokeFolderFeedToken(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+
+	if err := ctx.RevokeFolderFeedToken(getIDFromRequest(r)); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err := respondWithJSON(w, http.StatusNoContent, ""); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// CreateTagFeedToken issues the currently authenticated user a token for the feed of
+// the tag whose ID is specified in the HTTP request, replacing the previous one.
+func (a *API) CreateTagFeedToken(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+	id := getIDFromRequest(r)
+
+	secret, token, err := ctx.CreateTagFeedToken(id)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err = respondWithJSON(w, http.StatusCreated, feedTokenResponse(r, "tags", id, secret, token)); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// RevokeTagFeedToken revokes the currently authenticated user's token for the feed of
+// the tag whose ID is specified in the HTTP request.
+func (a *API) RevokeTagFeedToken(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+
+	if err := ctx.RevokeTagFeedToken(getIDFromRequest(r)); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err := respondWithJSON(w, http.StatusNoContent, ""); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// GetFolderFeed writes the feed of the folder whose ID is specified in the HTTP request,
+// in the format of its extension. Feed readers cannot sign in, so the request is
+// authenticated by the feed token in its token query parameter instead.
+func (a *API) GetFolderFeed(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	f, err := ctx.GetFolderFeed(getIDFromRequest(r), r.URL.Query().Get("token"))
+	writeFeed(w, r, f, err)
+}
+
+// GetTagFeed writes the feed of the tag whose ID is specified in the HTTP request, in
+// the format of its extension, authenticated like GetFolderFeed.
+func (a *API) GetTagFeed(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	f, err := ctx.GetTagFeed(getIDFromRequest(r), r.URL.Query().Get("token"))
+	writeFeed(w, r, f, err)
+}
+
+func writeFeed(w http.ResponseWriter, r *http.Request, f *feed.Feed, err error) {
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	f.FeedURL = requestBaseURL(r) + r.URL.RequestURI()
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	switch mux.Vars(r)["format"] {
+	case "atom":
+		w.Header().Set("Content-Type", feed.ContentTypeAtom)
+		err = feed.WriteAtom(w, f)
+	case "rss":
+		w.Header().Set("Content-Type", feed.ContentTypeRSS)
+		err = feed.WriteRSS(w, f)
+	default:
+		w.Header().Set("Content-Type", feed.ContentTypeJSON)
+		err = feed.WriteJSON(w, f)
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+	}
+}
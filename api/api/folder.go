@@ -2,26 +2,32 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 
-	"leggett.dev/devmarks/api/auth"
 	"leggett.dev/devmarks/api/model"
 )
 
 func (a *API) GetFolders(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-	user := auth.GetUser(ctx)
-	if user == nil {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
 		respondWithError(w, http.StatusUnauthorized, "no user signed in")
 		return
 	}
-	folders, err := a.App.Database.GetFoldersByUserID(ctx, user.ID)
+	opts, err := parseListOptions(r, model.FolderValidSorts(), filterColor, filterCreatedAfter, filterParentID)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	folders, page, err := ctx.GetUserFolders(opts)
+	if err != nil {
+		respondWithAppError(w, err)
 		return
 	}
 
+	writePageHeaders(w, r, page)
 	if err = respondWithJSON(w, http.StatusOK, folders); err != nil {
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -29,20 +35,15 @@ func (a *API) GetFolders(w http.ResponseWriter, r *http.Request) {
 }
 
 func (a *API) GetFolderByID(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-	user := auth.GetUser(ctx)
+	ctx := a.newContext(r)
 	id := getIDFromRequest(r)
-	if user == nil {
-		respondWithError(w, http.StatusInternalServerError, "no user signed in")
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
 		return
 	}
-	folder, err := a.App.Database.GetFolderByID(ctx, id)
+	folder, err := ctx.GetFolderByID(id)
 	if err != nil {
-		respondWithError(w, http.StatusNotFound, err.Error())
-		return
-	}
-	if user.ID != folder.OwnerID {
-		respondWithError(w, http.StatusForbidden, "permission denied")
+		respondWithAppError(w, err)
 		return
 	}
 	if err = respondWithJSON(w, http.StatusOK, folder); err != nil {
@@ -52,15 +53,15 @@ func (a *API) GetFolderByID(w http.ResponseWriter, r *http.Request) {
 }
 
 type CreateFolderInput struct {
-	Name string `json:"name"`
-	Color string `json:"color"`
-	ParentID *uint `json:"parent_id"`
+	Name           string `json:"name"`
+	Color          string `json:"color"`
+	ParentID       *uint  `json:"parent_id"`
+	OrganizationID *uint  `json:"organization_id"`
 }
 
 func (a *API) CreateFolder(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-	user := auth.GetUser(ctx)
-	if user == nil {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
 		respondWithError(w, http.StatusUnauthorized, "no user signed in")
 		return
 	}
@@ -77,14 +78,11 @@ func (a *API) CreateFolder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if input.Name == "" {
-		respondWithError(w, http.StatusUnprocessableEntity, "name is required")
-		return
-	}
-	folder := &model.Folder{Name: input.Name, Color: input.Color, ParentID:input.ParentID, OwnerID: user.ID}
+	folder := &model.Folder{Name: input.Name, Color: input.Color, ParentID: input.ParentID, OrganizationID: input.OrganizationID}
 
-	if err := a.App.Database.CreateFolder(folder); err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+	if err := ctx.CreateFolder(folder); err != nil {
+		respondWithAppError(w, err)
+		return
 	}
 
 	err = respondWithJSON(w, http.StatusCreated, folder)
@@ -95,27 +93,221 @@ func (a *API) CreateFolder(w http.ResponseWriter, r *http.Request) {
 }
 
 func (a *API) AddBookmarkToFolder(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-	user := auth.GetUser(ctx)
-	if user == nil {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
 		respondWithError(w, http.StatusUnauthorized, "no user is signed in")
 		return
 	}
-	folder_id := getIDFromRequest(r)
-	bookmark_id := getBIDFromRequest(r)
+	folderID := getIDFromRequest(r)
+	bookmarkID := getBIDFromRequest(r)
 
-	if err := a.App.Database.AddBookmarkToFolder(ctx, bookmark_id, folder_id); err != nil {
+	if err := ctx.AddBookmarkToFolder(folderID, bookmarkID); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+	folder, err := ctx.GetFolderByID(folderID)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err = respondWithJSON(w, http.StatusOK, folder); err != nil {
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-	folder, err := a.App.Database.GetFolderByID(ctx, folder_id)
+}
+
+// nullableID distinguishes an ID that was explicitly set to null in a JSON request
+// from one that was left out entirely.
+type nullableID struct {
+	Set   bool
+	Value *uint
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It is only called when the key is present.
+func (n *nullableID) UnmarshalJSON(data []byte) error {
+	n.Set = true
+	return json.Unmarshal(data, &n.Value)
+}
+
+// UpdateFolderInput represents the input to the UpdateFolderByID function. A null
+// parent_id moves the folder to the top level.
+type UpdateFolderInput struct {
+	Name     *string    `json:"name"`
+	Color    *string    `json:"color"`
+	ParentID nullableID `json:"parent_id"`
+}
+
+// UpdateFolderByID renames, recolors or moves the folder whose ID is specified in the
+// HTTP request if the currently authenticated user can change it.
+func (a *API) UpdateFolderByID(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+	id := getIDFromRequest(r)
+
+	var input UpdateFolderInput
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	if err := json.Unmarshal(body, &input); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	folder, err := ctx.GetFolderByID(id)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if input.Name != nil {
+		folder.Name = *input.Name
+	}
+	if input.Color != nil {
+		folder.Color = *input.Color
+	}
+	if input.ParentID.Set {
+		folder.ParentID = input.ParentID.Value
+		// the preloaded parent, if embedded, no longer matches.
+		folder.Parent = nil
+	}
+
+	if err := ctx.UpdateFolder(folder); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
 	if err = respondWithJSON(w, http.StatusOK, folder); err != nil {
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-}
\ No newline at end of file
+}
+
+// DeleteFolderByID deletes the folder whose ID is specified in the HTTP request if it is
+// owned by the currently authenticated user. With `?mode=cascade` every folder below it
+// is deleted too; with `?mode=reparent`, the default, its child folders are moved up to
+// its parent. The bookmarks in deleted folders are kept.
+func (a *API) DeleteFolderByID(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+	id := getIDFromRequest(r)
+
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = "reparent"
+	}
+	if mode != "cascade" && mode != "reparent" {
+		respondWithError(w, http.StatusBadRequest, "mode must be one of cascade, reparent")
+		return
+	}
+
+	if err := ctx.DeleteFolderByID(id, mode == "cascade"); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err := respondWithJSON(w, http.StatusNoContent, ""); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// getDepthFromRequest reads the optional depth query parameter of the folder tree
+// endpoints, returning -1 if it was not given.
+func getDepthFromRequest(r *http.Request) (int, error) {
+	value := r.URL.Query().Get("depth")
+	if value == "" {
+		return -1, nil
+	}
+	depth, err := strconv.Atoi(value)
+	if err != nil || depth < 0 {
+		return 0, errors.New("depth must be a non-negative number")
+	}
+	return depth, nil
+}
+
+// GetFolderTree returns the folders owned by the currently authenticated user as a nested
+// hierarchy, with the number of bookmarks in each folder.
+func (a *API) GetFolderTree(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+	depth, err := getDepthFromRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tree, err := ctx.GetFolderTree(depth)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err = respondWithJSON(w, http.StatusOK, tree); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// GetFolderSubtree returns the folder whose ID is specified in the HTTP request and the
+// hierarchy of folders nested inside it, if the currently authenticated user can see it.
+func (a *API) GetFolderSubtree(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+	id := getIDFromRequest(r)
+	depth, err := getDepthFromRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tree, err := ctx.GetFolderSubtree(id, depth)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err = respondWithJSON(w, http.StatusOK, tree); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// RemoveBookmarkFromFolder removes the bookmark specified in the HTTP request from the folder
+// specified in the HTTP request, if the currently authenticated user can change the folder.
+// The bookmark itself is kept.
+func (a *API) RemoveBookmarkFromFolder(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user is signed in")
+		return
+	}
+	folderID := getIDFromRequest(r)
+	bookmarkID := getBIDFromRequest(r)
+
+	if err := ctx.RemoveBookmarkFromFolder(folderID, bookmarkID); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err := respondWithJSON(w, http.StatusNoContent, ""); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
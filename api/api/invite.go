@@ -0,0 +1,80 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"leggett.dev/devmarks/api/model"
+)
+
+// GetInvites returns every invite in json form if the currently authenticated user is
+// an admin. The invite codes themselves are never included, only how they were used.
+func (a *API) GetInvites(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+
+	invites, err := ctx.GetInvites()
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err = respondWithJSON(w, http.StatusOK, invites); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// InviteInput represents the input to the CreateInvite function. A max_uses of 0 or
+// left out makes a single-use invite.
+type InviteInput struct {
+	Note      string     `json:"note"`
+	MaxUses   int        `json:"max_uses"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// InviteResponse is written to the HTTP response when an invite is created. It is the
+// only response that ever contains the invite code itself.
+type InviteResponse struct {
+	*model.Invite
+	Code string `json:"code"`
+}
+
+// CreateInvite creates an invite people can register with while registration is
+// invite-only, if the currently authenticated user is an admin.
+func (a *API) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+	var input InviteInput
+
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := json.Unmarshal(body, &input); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	code, invite, err := ctx.CreateInvite(input.Note, input.MaxUses, input.ExpiresAt)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err = respondWithJSON(w, http.StatusCreated, &InviteResponse{Invite: invite, Code: code}); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"leggett.dev/devmarks/api/db"
+	"leggett.dev/devmarks/api/model"
+)
+
+// Query parameters that can be used to filter list endpoints, in addition to
+// sort, limit and cursor. Each endpoint states which of them it supports.
+const (
+	filterColor        = "color"
+	filterCreatedAfter = "created_after"
+	filterFolderID     = "folder_id"
+	filterParentID     = "parent_id"
+	filterLinkStatus   = "status"
+)
+
+// parseListOptions reads the sort, limit, cursor and filter query parameters of a
+// list request. Sorts must be one of validSorts, optionally prefixed with a dash to
+// sort in descending order, and only the given filters are read.
+func parseListOptions(r *http.Request, validSorts []string, filters ...string) (*db.ListOptions, error) {
+	values := r.URL.Query()
+	opts := &db.ListOptions{}
+
+	if sort := values.Get("sort"); sort != "" {
+		opts.Descending = strings.HasPrefix(sort, "-")
+		opts.Sort = strings.TrimPrefix(sort, "-")
+		if !contains(validSorts, opts.Sort) {
+			return nil, fmt.Errorf("invalid sort, must be one of %s", strings.Join(validSorts, ", "))
+		}
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > db.MaxListLimit {
+			return nil, fmt.Errorf("limit must be a number between 1 and %d", db.MaxListLimit)
+		}
+		opts.Limit = n
+	}
+
+	if cursor := values.Get("cursor"); cursor != "" {
+		c, err := db.DecodeCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		opts.Cursor = c
+		if err := opts.ValidateCursor(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, filter := range filters {
+		value := values.Get(filter)
+		if value == "" {
+			continue
+		}
+		switch filter {
+		case filterColor:
+			opts.Color = &value
+		case filterCreatedAfter:
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				if t, err = time.Parse("2006-01-02", value); err != nil {
+					return nil, fmt.Errorf("%s must be an RFC 3339 timestamp or a date", filter)
+				}
+			}
+			opts.CreatedAfter = &t
+		case filterFolderID, filterParentID:
+			id, err := strconv.ParseUint(value, 10, 0)
+			if err != nil {
+				return nil, fmt.Errorf("%s must be a numeric id", filter)
+			}
+			uid := uint(id)
+			if filter == filterFolderID {
+				opts.FolderID = &uid
+			} else {
+				opts.ParentID = &uid
+			}
+		case filterLinkStatus:
+			if statuses := model.BookmarkValidLinkStatuses(); !contains(statuses, value) {
+				return nil, fmt.Errorf("invalid %s, must be one of %s", filter, strings.Join(statuses, ", "))
+			}
+			opts.LinkStatus = &value
+		}
+	}
+	return opts, nil
+}
+
+// writePageHeaders sets the X-Total-Count header, and a Link header pointing to the
+// next page if there is one.
+func writePageHeaders(w http.ResponseWriter, r *http.Request, page *db.Page) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(page.Total))
+	if page.Next == nil {
+		return
+	}
+	next := *r.URL
+	values := next.Query()
+	values.Set("cursor", page.Next.Encode())
+	next.RawQuery = values.Encode()
+	w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"next\"", next.RequestURI()))
+}
+
+func contains(array []string, s string) bool {
+	for _, x := range array {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"leggett.dev/devmarks/api/db"
+	"leggett.dev/devmarks/api/model"
+)
+
+func TestParseListOptionsCursor(t *testing.T) {
+	byName := (&db.Cursor{Sort: "name", Value: "devmarks", ID: 3}).Encode()
+	byCreated := (&db.Cursor{Sort: "created_at", Value: "2021-09-01T10:00:00.123Z", ID: 3}).Encode()
+	byCreatedDesc := (&db.Cursor{Sort: "created_at", Descending: true, Value: "2021-09-01T10:00:00Z", ID: 3}).Encode()
+
+	tests := []struct {
+		name   string
+		query  url.Values
+		wantOK bool
+	}{
+		{"same sort", url.Values{"sort": {"name"}, "cursor": {byName}}, true},
+		{"default sort", url.Values{"cursor": {byCreated}}, true},
+		{"explicit default sort", url.Values{"sort": {"created_at"}, "cursor": {byCreated}}, true},
+		{"descending", url.Values{"sort": {"-created_at"}, "cursor": {byCreatedDesc}}, true},
+		{"other sort", url.Values{"cursor": {byName}}, false},
+		{"other direction", url.Values{"sort": {"-name"}, "cursor": {byName}}, false},
+		{"descending cursor ascending list", url.Values{"cursor": {byCreatedDesc}}, false},
+		{"tampered time", url.Values{"cursor": {(&db.Cursor{Sort: "created_at", Value: "foo", ID: 3}).Encode()}}, false},
+		{"tampered sort", url.Values{"sort": {"name"}, "cursor": {(&db.Cursor{Sort: "name; DROP TABLE bookmarks", Value: "a", ID: 3}).Encode()}}, false},
+		{"no sort", url.Values{"cursor": {base64.RawURLEncoding.EncodeToString([]byte(`{"v":"a","id":3}`))}}, false},
+		{"no id", url.Values{"sort": {"name"}, "cursor": {(&db.Cursor{Sort: "name", Value: "a"}).Encode()}}, false},
+		{"not base64", url.Values{"cursor": {"!!!"}}, false},
+		{"not json", url.Values{"cursor": {base64.RawURLEncoding.EncodeToString([]byte("cursor"))}}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/bookmarks?"+test.query.Encode(), nil)
+			opts, err := parseListOptions(r, model.BookmarkValidSorts())
+			if test.wantOK {
+				if err != nil {
+					t.Fatalf("parseListOptions() error = %v", err)
+				}
+				if opts.Cursor == nil || opts.Cursor.ID != 3 {
+					t.Errorf("parseListOptions() cursor = %+v, want the decoded cursor", opts.Cursor)
+				}
+				return
+			}
+			if err != db.ErrInvalidCursor {
+				t.Errorf("parseListOptions() error = %v, want %v", err, db.ErrInvalidCursor)
+			}
+		})
+	}
+}
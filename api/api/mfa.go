@@ -0,0 +1,148 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"leggett.dev/devmarks/api/app"
+)
+
+// MFAChallengeResponse is written to the HTTP response of /auth/token instead of a
+// token when the user has two-factor authentication enabled. The client then sends
+// MFAToken along with a code to /auth/token/mfa.
+type MFAChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+}
+
+// MFAInput represents the input to the createTokenWithMFA function
+type MFAInput struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+	Name     string `json:"name"`
+}
+
+// createTokenWithMFA finishes signing in a user with two-factor authentication enabled,
+// given the challenge from /auth/token and a one-time or recovery code.
+func (a *API) createTokenWithMFA(w http.ResponseWriter, r *http.Request) {
+	var input MFAInput
+
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := json.Unmarshal(body, &input); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	user, err := a.App.CompleteMFAChallenge(input.MFAToken, input.Code, a.Logger.IPAddressForRequest(r))
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	response, err := a.issueLoginToken(user, input.Name)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+	if err = respondWithJSON(w, http.StatusOK, response); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// TOTPCodeInput represents the input to the ConfirmTOTP and DisableTOTP functions
+type TOTPCodeInput struct {
+	Code string `json:"code"`
+}
+
+// RecoveryCodesResponse is written to the HTTP response once two-factor authentication
+// is enabled. It is the only time the recovery codes are available.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// EnrollTOTP starts setting up two-factor authentication for the currently authenticated
+// user, responding with the secret to add to their authenticator app.
+func (a *API) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+
+	enrollment, err := a.App.EnrollTOTP(ctx.User)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err = respondWithJSON(w, http.StatusCreated, enrollment); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// ConfirmTOTP enables two-factor authentication for the currently authenticated user
+// given a code from their authenticator app, and responds with their recovery codes.
+func (a *API) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	a.withTOTPCode(w, r, func(ctx *app.Context, code string) {
+		codes, err := a.App.ConfirmTOTP(ctx.User, code)
+		if err != nil {
+			respondWithAppError(w, err)
+			return
+		}
+
+		if err = respondWithJSON(w, http.StatusOK, &RecoveryCodesResponse{RecoveryCodes: codes}); err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	})
+}
+
+// DisableTOTP turns two-factor authentication off for the currently authenticated user,
+// given a current one-time or recovery code.
+func (a *API) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	a.withTOTPCode(w, r, func(ctx *app.Context, code string) {
+		if err := a.App.DisableTOTP(ctx.User, code); err != nil {
+			respondWithAppError(w, err)
+			return
+		}
+
+		if err := respondWithJSON(w, http.StatusNoContent, ""); err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	})
+}
+
+// withTOTPCode reads the code from the HTTP request of a signed in user and passes it
+// to fn.
+func (a *API) withTOTPCode(w http.ResponseWriter, r *http.Request, fn func(ctx *app.Context, code string)) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+	var input TOTPCodeInput
+
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := json.Unmarshal(body, &input); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	fn(ctx, input.Code)
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"leggett.dev/devmarks/api/netscape"
+)
+
+// ImportNetscape imports a Netscape bookmark file, the bookmarks.html file exported
+// by browsers, for the currently authenticated user. The file can be sent as the raw
+// request body or as the `file` field of a multipart form.
+func (a *API) ImportNetscape(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+
+	defer r.Body.Close()
+	var file io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		part, _, err := r.FormFile("file")
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "file is required")
+			return
+		}
+		defer part.Close()
+		file = part
+	}
+
+	root, err := netscape.Parse(file)
+	if err != nil {
+		respondWithError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	result, err := ctx.ImportNetscape(root)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err = respondWithJSON(w, http.StatusCreated, result); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// ExportNetscape writes everything owned by the currently authenticated user to the
+// HTTP response as a Netscape bookmark file.
+func (a *API) ExportNetscape(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+
+	root, err := ctx.ExportNetscape()
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="bookmarks.html"`)
+	w.WriteHeader(http.StatusOK)
+	netscape.Write(w, root)
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"leggett.dev/devmarks/api/oidc"
+)
+
+const (
+	// oidcCookieName is the cookie holding the state and nonce of a single sign-on
+	// attempt between the redirect to the provider and the callback.
+	oidcCookieName = "devmarks_oidc"
+	oidcCookiePath = "/auth/oidc"
+	oidcCookieAge  = 10 * 60
+)
+
+// OIDCLogin redirects the user to the login page of the single sign-on provider.
+func (a *API) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if a.App.OIDC == nil {
+		respondWithError(w, http.StatusNotFound, "single sign-on is not configured")
+		return
+	}
+
+	state, nonce := oidc.RandomString(), oidc.RandomString()
+	redirect, err := a.App.OIDC.AuthCodeURL(r.Context(), state, nonce)
+	if err != nil {
+		respondWithError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcCookieName,
+		Value:    state + "." + nonce,
+		Path:     oidcCookiePath,
+		MaxAge:   oidcCookieAge,
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(a.App.Config.OIDC.RedirectURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+// OIDCCallback finishes signing in through the single sign-on provider, which redirects
+// the user here with a code, and responds with a token, or an MFA challenge, like
+// /auth/token does.
+func (a *API) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if a.App.OIDC == nil {
+		respondWithError(w, http.StatusNotFound, "single sign-on is not configured")
+		return
+	}
+	query := r.URL.Query()
+	if providerError := query.Get("error"); providerError != "" {
+		respondWithError(w, http.StatusUnauthorized, strings.TrimSpace(providerError+" "+query.Get("error_description")))
+		return
+	}
+
+	cookie, err := r.Cookie(oidcCookieName)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "no single sign-on attempt in progress")
+		return
+	}
+	// the cookie is single use.
+	http.SetCookie(w, &http.Cookie{Name: oidcCookieName, Path: oidcCookiePath, MaxAge: -1})
+
+	parts := strings.SplitN(cookie.Value, ".", 2)
+	if len(parts) != 2 || subtle.ConstantTimeCompare([]byte(parts[0]), []byte(query.Get("state"))) != 1 {
+		respondWithError(w, http.StatusBadRequest, "invalid state")
+		return
+	}
+	state, nonce := parts[0], parts[1]
+	if state == "" || query.Get("code") == "" {
+		respondWithError(w, http.StatusBadRequest, "code is required")
+		return
+	}
+
+	claims, err := a.App.OIDC.Exchange(r.Context(), query.Get("code"), nonce)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	user, err := a.App.SignInWithOIDC(claims)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	a.respondWithLogin(w, user, "single sign-on")
+}
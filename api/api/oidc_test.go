@@ -0,0 +1,177 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+
+	"leggett.dev/devmarks/api/app"
+	"leggett.dev/devmarks/api/db/dbtest"
+	"leggett.dev/devmarks/api/model"
+	"leggett.dev/devmarks/api/oidc"
+	"leggett.dev/devmarks/api/oidc/oidctest"
+)
+
+// newOIDCAPI returns an API signing users in through a mock provider.
+func newOIDCAPI(t *testing.T) (*API, *oidctest.Provider) {
+	mock := oidctest.NewProvider("devmarks", "secret")
+	t.Cleanup(mock.Close)
+	config := &app.Config{
+		SecretKey:     []byte("secret"),
+		TokenLifetime: time.Hour,
+		AuthStrategy:  app.AuthStrategyToken,
+		Registration:  app.RegistrationOpen,
+		OIDC: &oidc.Config{
+			Issuer:       mock.Issuer(),
+			ClientID:     mock.ClientID,
+			ClientSecret: mock.ClientSecret,
+			RedirectURL:  "https://api.local/auth/oidc/callback",
+		},
+	}
+	a := &app.App{
+		Config:   config,
+		Database: dbtest.New(t, &model.User{}, &model.Identity{}, &model.Token{}),
+		OIDC:     oidc.NewProvider(*config.OIDC, mock.Client()),
+	}
+	return &API{App: a}, mock
+}
+
+// startOIDCLogin starts signing in and returns the cookie set for the attempt, and the
+// state and nonce sent to the provider.
+func startOIDCLogin(t *testing.T, api *API) (*http.Cookie, string, string) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	api.OIDCLogin(w, httptest.NewRequest(http.MethodGet, "/auth/oidc/login", nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("login status = %d, want %d", w.Code, http.StatusFound)
+	}
+	redirect, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("login set %d cookies, want 1", len(cookies))
+	}
+	return cookies[0], redirect.Query().Get("state"), redirect.Query().Get("nonce")
+}
+
+// callback finishes signing in with the given cookie, state and code.
+func callback(api *API, cookie *http.Cookie, state, code string) *httptest.ResponseRecorder {
+	query := url.Values{"state": {state}, "code": {code}}
+	r := httptest.NewRequest(http.MethodGet, "/auth/oidc/callback?"+query.Encode(), nil)
+	r.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	api.OIDCCallback(w, r)
+	return w
+}
+
+func TestOIDCCallbackProvisionsUser(t *testing.T) {
+	api, mock := newOIDCAPI(t)
+	cookie, state, nonce := startOIDCLogin(t, api)
+	code := mock.Code(jwt.MapClaims{"sub": "1", "nonce": nonce, "email": "jane@example.com", "email_verified": true})
+
+	w := callback(api, cookie, state, code)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	var response TokenResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	token, err := api.App.AuthenticateToken(response.Token)
+	if err != nil {
+		t.Fatalf("the token handed out is not valid: %v", err)
+	}
+	if token.User.Email != "jane@example.com" || !token.User.EmailVerified() {
+		t.Errorf("token is for %+v, want the verified user jane@example.com", token.User)
+	}
+}
+
+func TestOIDCCallbackRequiresMFA(t *testing.T) {
+	api, mock := newOIDCAPI(t)
+	now := time.Now()
+	user := &model.User{Email: "jane@example.com", HashedPassword: []byte("hash"), EmailVerifiedAt: &now, TOTPEnabledAt: &now}
+	if err := api.App.Database.CreateUser(user); err != nil {
+		t.Fatal(err)
+	}
+	cookie, state, nonce := startOIDCLogin(t, api)
+	code := mock.Code(jwt.MapClaims{"sub": "1", "nonce": nonce, "email": "jane@example.com", "email_verified": true})
+
+	w := callback(api, cookie, state, code)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	var response struct {
+		MFAChallengeResponse
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if !response.MFARequired || response.MFAToken == "" || response.Token != "" {
+		t.Errorf("response = %s, want an MFA challenge and no token", w.Body)
+	}
+}
+
+func TestOIDCCallbackRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		state  func(state string) string
+		nonce  func(nonce string) string
+		status int
+	}{
+		{
+			name:   "state mismatch",
+			state:  func(state string) string { return "another state" },
+			nonce:  func(nonce string) string { return nonce },
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "missing state",
+			state:  func(state string) string { return "" },
+			nonce:  func(nonce string) string { return nonce },
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "nonce mismatch",
+			state:  func(state string) string { return state },
+			nonce:  func(nonce string) string { return "another nonce" },
+			status: http.StatusUnauthorized,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			api, mock := newOIDCAPI(t)
+			cookie, state, nonce := startOIDCLogin(t, api)
+			code := mock.Code(jwt.MapClaims{"sub": "1", "nonce": test.nonce(nonce), "email": "jane@example.com", "email_verified": true})
+
+			w := callback(api, cookie, test.state(state), code)
+			if w.Code != test.status {
+				t.Errorf("status = %d, want %d: %s", w.Code, test.status, w.Body)
+			}
+			if _, err := api.App.Database.GetUserByEmail("jane@example.com"); err == nil {
+				t.Error("a user was created")
+			}
+		})
+	}
+}
+
+func TestOIDCCallbackRejectsUnverifiedUser(t *testing.T) {
+	api, mock := newOIDCAPI(t)
+	user := &model.User{Email: "jane@example.com", HashedPassword: []byte("hash")}
+	if err := api.App.Database.CreateUser(user); err != nil {
+		t.Fatal(err)
+	}
+	cookie, state, nonce := startOIDCLogin(t, api)
+	code := mock.Code(jwt.MapClaims{"sub": "1", "nonce": nonce, "email": "jane@example.com", "email_verified": true})
+
+	if w := callback(api, cookie, state, code); w.Code != http.StatusConflict {
+		t.Errorf("status = %d, want %d: %s", w.Code, http.StatusConflict, w.Body)
+	}
+}
@@ -0,0 +1,339 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"leggett.dev/devmarks/api/model"
+)
+
+// OrganizationInput represents the input to the CreateOrganization and
+// UpdateOrganizationByID functions
+type OrganizationInput struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+}
+
+// MemberInput represents the input to the AddMember and UpdateMember functions. Email
+// is only used to add a member.
+type MemberInput struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// MemberResponse is a member of an organization as written to the HTTP response.
+type MemberResponse struct {
+	UserID    uint      `json:"user_id"`
+	Email     string    `json:"email"`
+	Name      *string   `json:"name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func memberResponse(member *model.Membership) *MemberResponse {
+	response := &MemberResponse{UserID: member.UserID, Role: member.Role, CreatedAt: member.CreatedAt}
+	if member.User != nil {
+		response.Email = member.User.Email
+		response.Name = member.User.Name
+	}
+	return response
+}
+
+func getUIDFromRequest(r *http.Request) uint {
+	vars := mux.Vars(r)
+	id := vars["uid"]
+
+	intID, err := strconv.ParseInt(id, 10, 0)
+	if err != nil {
+		return 0
+	}
+
+	return uint(intID)
+}
+
+// GetOrganizations returns the organizations the currently authenticated user is a
+// member of.
+func (a *API) GetOrganizations(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+
+	organizations, err := ctx.GetUserOrganizations()
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err = respondWithJSON(w, http.StatusOK, organizations); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// GetOrganizationByID returns the organization whose ID is specified in the HTTP
+// request, if the currently authenticated user is a member of it.
+func (a *API) GetOrganizationByID(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+
+	organization, err := ctx.GetOrganizationByID(getIDFromRequest(r))
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err = respondWithJSON(w, http.StatusOK, organization); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// CreateOrganization creates an organization owned by the currently authenticated user.
+func (a *API) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+	var input OrganizationInput
+
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := json.Unmarshal(body, &input); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	organization := &model.Organization{}
+	if input.Name != nil {
+		organization.Name = *input.Name
+	}
+	if input.Description != nil {
+		organization.Description = *input.Description
+	}
+
+	if err := ctx.CreateOrganization(organization); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err = respondWithJSON(w, http.StatusCreated, organization); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// UpdateOrganizationByID renames or redescribes the organization whose ID is specified
+// in the HTTP request, if the currently authenticated user is one of its admins.
+func (a *API) UpdateOrganizationByID(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+	var input OrganizationInput
+
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := json.Unmarshal(body, &input); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	organization, err := ctx.GetOrganizationByID(getIDFromRequest(r))
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+	if input.Name != nil {
+		organization.Name = *input.Name
+	}
+	if input.Description != nil {
+		organization.Description = *input.Description
+	}
+
+	if err := ctx.UpdateOrganization(organization); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err = respondWithJSON(w, http.StatusOK, organization); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// DeleteOrganizationByID deletes the organization whose ID is specified in the HTTP
+// request and its folders, if the currently authenticated user owns it.
+func (a *API) DeleteOrganizationByID(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+
+	if err := ctx.DeleteOrganizationByID(getIDFromRequest(r)); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err := respondWithJSON(w, http.StatusNoContent, ""); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// GetOrganizationFolders returns the folders of the organization whose ID is specified
+// in the HTTP request, if the currently authenticated user is a member of it.
+func (a *API) GetOrganizationFolders(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+
+	folders, err := ctx.GetOrganizationFolders(getIDFromRequest(r))
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err = respondWithJSON(w, http.StatusOK, folders); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// GetMembers returns the members of the organization whose ID is specified in the HTTP
+// request, if the currently authenticated user is a member of it.
+func (a *API) GetMembers(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+
+	members, err := ctx.GetMembers(getIDFromRequest(r))
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	response := make([]*MemberResponse, 0, len(members))
+	for _, member := range members {
+		response = append(response, memberResponse(member))
+	}
+	if err = respondWithJSON(w, http.StatusOK, response); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// AddMember adds the user with the email address in the HTTP request to the
+// organization whose ID is specified in it, if the currently authenticated user is
+// one of its admins.
+func (a *API) AddMember(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+	var input MemberInput
+
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := json.Unmarshal(body, &input); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	member, err := ctx.AddMember(getIDFromRequest(r), input.Email, input.Role)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err = respondWithJSON(w, http.StatusCreated, memberResponse(member)); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// UpdateMember changes the role of the member specified in the HTTP request, if the
+// currently authenticated user is one of the organization's admins.
+func (a *API) UpdateMember(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+	var input MemberInput
+
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := json.Unmarshal(body, &input); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	member, err := ctx.UpdateMemberRole(getIDFromRequest(r), getUIDFromRequest(r), input.Role)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err = respondWithJSON(w, http.StatusOK, memberResponse(member)); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// RemoveMember removes the member specified in the HTTP request from the organization,
+// if the currently authenticated user is one of its admins or the member leaving.
+func (a *API) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+
+	if err := ctx.RemoveMember(getIDFromRequest(r), getUIDFromRequest(r)); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err := respondWithJSON(w, http.StatusNoContent, ""); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"leggett.dev/devmarks/api/app"
+	myAuth "leggett.dev/devmarks/api/auth"
+)
+
+// nullableString distinguishes a string that was explicitly set to null in a JSON
+// request from one that was left out entirely.
+type nullableString struct {
+	Set   bool
+	Value *string
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It is only called when the key is present.
+func (n *nullableString) UnmarshalJSON(data []byte) error {
+	n.Set = true
+	return json.Unmarshal(data, &n.Value)
+}
+
+// PreferencesInput represents the preferences in the input to the UpdateProfile
+// function. A null default_bookmark_color removes it.
+type PreferencesInput struct {
+	DefaultBookmarkColor nullableString `json:"default_bookmark_color"`
+	Theme                *string        `json:"theme"`
+}
+
+// UpdateProfileInput represents the input to the UpdateProfile function
+type UpdateProfileInput struct {
+	Name        *string          `json:"name"`
+	Email       *string          `json:"email"`
+	Preferences PreferencesInput `json:"preferences"`
+}
+
+// UpdateProfile changes the name, email address or preferences of the currently
+// authenticated user. A new email address has to be verified again.
+func (a *API) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+	var input UpdateProfileInput
+
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := json.Unmarshal(body, &input); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	update := &app.ProfileUpdate{
+		Name:  input.Name,
+		Email: input.Email,
+		Theme: input.Preferences.Theme,
+	}
+	if color := input.Preferences.DefaultBookmarkColor; color.Set {
+		update.DefaultBookmarkColor = color.Value
+		update.ClearDefaultBookmarkColor = color.Value == nil
+	}
+
+	if err := a.App.UpdateProfile(ctx.User, update); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err = respondWithJSON(w, http.StatusOK, ctx.User); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// ChangePasswordInput represents the input to the ChangePassword function
+type ChangePasswordInput struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// ChangePassword sets a new password for the currently authenticated user, who has to
+// give their current one. They stay signed in with the token or session the request
+// was made with and are signed out everywhere else.
+func (a *API) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+	var input ChangePasswordInput
+
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := json.Unmarshal(body, &input); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	err = a.App.ChangePassword(ctx.User, input.CurrentPassword, input.NewPassword, myAuth.GetTokenID(r.Context()), myAuth.GetSession(r.Context()))
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err := respondWithJSON(w, http.StatusNoContent, ""); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// DeleteAccount deletes the currently authenticated user and revokes all of their
+// tokens.
+func (a *API) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+
+	if err := a.App.DeleteAccount(ctx.User); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err := respondWithJSON(w, http.StatusNoContent, ""); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
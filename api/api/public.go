@@ -0,0 +1,221 @@
+package api
+
+import (
+	"encoding/json"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"leggett.dev/devmarks/api/app"
+	"leggett.dev/devmarks/api/model"
+)
+
+// PublishFolderInput represents the input to the PublishFolder function. Both fields
+// are optional.
+type PublishFolderInput struct {
+	Password  string     `json:"password"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// PublishFolder publishes the folder whose ID is specified in the HTTP request at a new
+// public link, if the currently authenticated user can share it.
+func (a *API) PublishFolder(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+	var input PublishFolderInput
+
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &input); err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	folder, err := ctx.PublishFolder(getIDFromRequest(r), input.Password, input.ExpiresAt)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err = respondWithJSON(w, http.StatusOK, folder); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// UnpublishFolder stops publishing the folder whose ID is specified in the HTTP
+// request, if the currently authenticated user can share it.
+func (a *API) UnpublishFolder(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+
+	if err := ctx.UnpublishFolder(getIDFromRequest(r)); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err := respondWithJSON(w, http.StatusNoContent, ""); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// GetPublicFolder shows the folder published under the slug in the HTTP request to
+// anyone, as an HTML page to browsers and as json otherwise. The password of a
+// password protected folder is posted, either from the form on the HTML page or as
+// json.
+func (a *API) GetPublicFolder(w http.ResponseWriter, r *http.Request) {
+	// the slug is a secret, so pages must not pass it on to the sites they link to.
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("X-Robots-Tag", "noindex")
+	html := wantsHTML(r)
+
+	password, err := getPublicPassword(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	folder, err := a.App.GetPublicFolder(mux.Vars(r)["slug"], password, a.Logger.IPAddressForRequest(r))
+	if err != nil {
+		if html {
+			renderPublicError(w, err)
+		} else {
+			respondWithAppError(w, err)
+		}
+		return
+	}
+
+	if html {
+		renderPublicPage(w, http.StatusOK, &publicPage{Folder: folder})
+		return
+	}
+	if err = respondWithJSON(w, http.StatusOK, folder); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// wantsHTML returns true if the request prefers an HTML page to json, as browsers do.
+func wantsHTML(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+// getPublicPassword returns the password posted for a public folder, from either a
+// form or a json body.
+func getPublicPassword(r *http.Request) (string, error) {
+	if r.Method != http.MethodPost {
+		return "", nil
+	}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var input struct {
+			Password string `json:"password"`
+		}
+		defer r.Body.Close()
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			return "", err
+		}
+		return input.Password, nil
+	}
+	if err := r.ParseForm(); err != nil {
+		return "", err
+	}
+	return r.PostForm.Get("password"), nil
+}
+
+// publicPage is the data the public folder page is rendered with. Exactly one of
+// Folder, PasswordRequired and Error is set.
+type publicPage struct {
+	Folder           *model.PublicFolder
+	PasswordRequired bool
+	WrongPassword    bool
+	Error            string
+}
+
+func renderPublicError(w http.ResponseWriter, err error) {
+	if rateErr, ok := err.(*app.RateLimitError); ok {
+		setRetryAfter(w, rateErr.RetryAfter)
+		renderPublicPage(w, http.StatusTooManyRequests, &publicPage{Error: "Too many wrong passwords, please try again later."})
+		return
+	}
+	switch err {
+	case app.ErrPublicPasswordRequired:
+		renderPublicPage(w, http.StatusUnauthorized, &publicPage{PasswordRequired: true})
+	case app.ErrPublicPasswordWrong:
+		renderPublicPage(w, http.StatusUnauthorized, &publicPage{PasswordRequired: true, WrongPassword: true})
+	case app.ErrPublicFolderNotFound:
+		renderPublicPage(w, http.StatusNotFound, &publicPage{Error: "This folder does not exist or is no longer public."})
+	default:
+		renderPublicPage(w, http.StatusInternalServerError, &publicPage{Error: "Something went wrong, please try again later."})
+	}
+}
+
+func renderPublicPage(w http.ResponseWriter, code int, page *publicPage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; form-action 'self'")
+	w.WriteHeader(code)
+	publicTemplate.Execute(w, page)
+}
+
+var publicTemplate = template.Must(template.New("public").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="referrer" content="no-referrer">
+<meta name="robots" content="noindex">
+<title>{{if .Folder}}{{.Folder.Name}}{{else}}devmarks{{end}}</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 48rem; margin: 2rem auto; padding: 0 1rem; color: #222; }
+h1 { border-left: 0.4rem solid #ccc; padding-left: 0.5rem; }
+ul { list-style: none; padding-left: 0; }
+li { margin: 0.75rem 0; }
+p.notes { margin: 0.25rem 0 0; color: #555; }
+section section { margin-left: 1.5rem; }
+footer { margin-top: 3rem; color: #888; font-size: 0.85rem; }
+</style>
+</head>
+<body>
+{{if .Folder}}
+{{template "folder" .Folder}}
+<footer>Last updated {{.Folder.UpdatedAt.Format "January 2, 2006"}}</footer>
+{{else if .PasswordRequired}}
+<h1>This folder is password protected</h1>
+{{if .WrongPassword}}<p>The password is incorrect.</p>{{end}}
+<form method="post">
+<input type="password" name="password" placeholder="Password" autofocus required>
+<button type="submit">Open</button>
+</form>
+{{else}}
+<p>{{.Error}}</p>
+{{end}}
+</body>
+</html>
+{{define "folder"}}
+<section>
+<h1{{if .Color}} style="border-color: {{.Color}}"{{end}}>{{.Name}}</h1>
+<ul>
+{{range .Bookmarks}}
+<li><a href="{{.URL}}" rel="noopener noreferrer nofollow">{{if .Name}}{{.Name}}{{else}}{{.URL}}{{end}}</a>{{if .Notes}}<p class="notes">{{.Notes}}</p>{{end}}</li>
+{{end}}
+</ul>
+{{range .Folders}}{{template "folder" .}}{{end}}
+</section>
+{{end}}`))
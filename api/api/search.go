@@ -0,0 +1,34 @@
+package api
+
+import (
+	"net/http"
+
+	"leggett.dev/devmarks/api/search"
+)
+
+// SearchBookmarks returns the bookmarks owned by the currently authenticated user that
+// match the search query given in the `q` query parameter, in json form.
+func (a *API) SearchBookmarks(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+
+	query, err := search.Parse(r.URL.Query().Get("q"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	bookmarks, err := ctx.SearchBookmarks(query)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err = respondWithJSON(w, http.StatusOK, bookmarks); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
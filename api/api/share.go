@@ -0,0 +1,179 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"leggett.dev/devmarks/api/model"
+)
+
+// ShareInput represents the input to the ShareFolder and UpdateShare functions. Email
+// is only used to share a folder.
+type ShareInput struct {
+	Email  string `json:"email"`
+	Access string `json:"access"`
+}
+
+// ShareResponse is a user a folder is shared with as written to the HTTP response.
+type ShareResponse struct {
+	UserID    uint      `json:"user_id"`
+	Email     string    `json:"email"`
+	Name      *string   `json:"name"`
+	Access    string    `json:"access"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func shareResponse(share *model.Share) *ShareResponse {
+	response := &ShareResponse{UserID: share.UserID, Access: share.Access, CreatedAt: share.CreatedAt}
+	if share.User != nil {
+		response.Email = share.User.Email
+		response.Name = share.User.Name
+	}
+	return response
+}
+
+// SharedFolderResponse is a folder shared with the currently authenticated user, along
+// with the access they were given, as written to the HTTP response.
+type SharedFolderResponse struct {
+	*model.Folder
+	Access string `json:"access"`
+}
+
+// GetSharedFolders returns the folders shared with the currently authenticated user.
+func (a *API) GetSharedFolders(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+
+	shares, err := ctx.GetSharedFolders()
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	response := make([]*SharedFolderResponse, 0, len(shares))
+	for _, share := range shares {
+		response = append(response, &SharedFolderResponse{Folder: share.Folder, Access: share.Access})
+	}
+	if err = respondWithJSON(w, http.StatusOK, response); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// GetShares returns the users the folder whose ID is specified in the HTTP request is
+// shared with, if the currently authenticated user can share it.
+func (a *API) GetShares(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+
+	shares, err := ctx.GetShares(getIDFromRequest(r))
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	response := make([]*ShareResponse, 0, len(shares))
+	for _, share := range shares {
+		response = append(response, shareResponse(share))
+	}
+	if err = respondWithJSON(w, http.StatusOK, response); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// ShareFolder shares the folder whose ID is specified in the HTTP request with the user
+// with the email address in it, if the currently authenticated user can share it.
+func (a *API) ShareFolder(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+	var input ShareInput
+
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := json.Unmarshal(body, &input); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	share, err := ctx.ShareFolder(getIDFromRequest(r), input.Email, input.Access)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err = respondWithJSON(w, http.StatusCreated, shareResponse(share)); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// UpdateShare changes the access of the user specified in the HTTP request to the
+// folder, if the currently authenticated user can share it.
+func (a *API) UpdateShare(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+	var input ShareInput
+
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := json.Unmarshal(body, &input); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	share, err := ctx.UpdateShare(getIDFromRequest(r), getUIDFromRequest(r), input.Access)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err = respondWithJSON(w, http.StatusOK, shareResponse(share)); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// Unshare stops sharing the folder with the user specified in the HTTP request, if the
+// currently authenticated user can share the folder or is the user giving it up.
+func (a *API) Unshare(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+
+	if err := ctx.Unshare(getIDFromRequest(r), getUIDFromRequest(r)); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err := respondWithJSON(w, http.StatusNoContent, ""); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"leggett.dev/devmarks/api/model"
+)
+
+// GetTags returns the tags owned by the currently authenticated user in json form
+func (a *API) GetTags(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+	tags, err := ctx.GetUserTags()
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err = respondWithJSON(w, http.StatusOK, tags); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// GetTagByID writes the json representation of a tag to the HTTP Response, if the
+// currently authenticated user can see it.
+func (a *API) GetTagByID(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+	id := getIDFromRequest(r)
+	tag, err := ctx.GetTagByID(id)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err = respondWithJSON(w, http.StatusOK, tag); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// TagInput represents the input to the CreateTag and UpdateTagByID functions
+type TagInput struct {
+	Name string `json:"name"`
+}
+
+// CreateTag creates a new tag owned by the currently authenticated user based on
+// json from the HTTP Request
+func (a *API) CreateTag(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+	var input TagInput
+
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := json.Unmarshal(body, &input); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	tag := &model.Tag{Name: input.Name}
+	if err := ctx.CreateTag(tag); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err = respondWithJSON(w, http.StatusCreated, tag); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// UpdateTagByID renames the tag whose ID is specified in the HTTP request if the
+// currently authenticated user can change it.
+func (a *API) UpdateTagByID(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+	id := getIDFromRequest(r)
+	var input TagInput
+
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := json.Unmarshal(body, &input); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	tag, err := ctx.GetTagByID(id)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	tag.Name = input.Name
+	if err := ctx.UpdateTag(tag); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err = respondWithJSON(w, http.StatusOK, tag); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// DeleteTagByID deletes the tag whose ID is specified in the HTTP request if it is
+// owned by the currently authenticated user. Bookmarks carrying the tag are kept.
+func (a *API) DeleteTagByID(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+	id := getIDFromRequest(r)
+
+	if err := ctx.DeleteTagByID(id); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err := respondWithJSON(w, http.StatusNoContent, ""); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	myAuth "leggett.dev/devmarks/api/auth"
+	"leggett.dev/devmarks/api/model"
+)
+
+// GetTokens returns the API tokens of the currently authenticated user in json form.
+// The tokens themselves are never included, only their names and usage.
+func (a *API) GetTokens(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+
+	tokens, err := ctx.GetUserTokens()
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err = respondWithJSON(w, http.StatusOK, tokens); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// PersonalTokenInput represents the input to the CreatePersonalToken function
+type PersonalTokenInput struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// PersonalTokenResponse is written to the HTTP response when a personal access token
+// is created. It is the only response that ever contains the token itself.
+type PersonalTokenResponse struct {
+	*model.Token
+	Secret string `json:"token"`
+}
+
+// CreatePersonalToken creates a personal access token for the currently authenticated
+// user, limited to the scopes given in the HTTP request.
+func (a *API) CreatePersonalToken(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+	var input PersonalTokenInput
+
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := json.Unmarshal(body, &input); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	secret, token, err := ctx.CreatePersonalToken(input.Name, input.Scopes, input.ExpiresAt)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err = respondWithJSON(w, http.StatusCreated, &PersonalTokenResponse{Token: token, Secret: secret}); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// RevokeTokenByID revokes the API token whose ID is specified in the HTTP request if it
+// belongs to the currently authenticated user.
+func (a *API) RevokeTokenByID(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+	id := getIDFromRequest(r)
+
+	if err := ctx.RevokeTokenByID(id); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err := respondWithJSON(w, http.StatusNoContent, ""); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// Logout revokes the API token the current request was authenticated with, or ends the
+// login session of its JWT access token.
+func (a *API) Logout(w http.ResponseWriter, r *http.Request) {
+	ctx := a.newContext(r)
+	if ctx.User == nil {
+		respondWithError(w, http.StatusUnauthorized, "no user signed in")
+		return
+	}
+
+	var err error
+	if session := myAuth.GetSession(r.Context()); session != "" {
+		err = ctx.RevokeSession(session)
+	} else {
+		err = ctx.RevokeTokenByID(myAuth.GetTokenID(r.Context()))
+	}
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if err := respondWithJSON(w, http.StatusNoContent, ""); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
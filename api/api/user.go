@@ -4,11 +4,8 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
-	"strconv"
+	"time"
 
-	"github.com/google/uuid"
-	"github.com/pkg/errors"
-	"github.com/shaj13/go-guardian/auth"
 	"leggett.dev/devmarks/api/app"
 	myAuth "leggett.dev/devmarks/api/auth"
 	"leggett.dev/devmarks/api/model"
@@ -16,8 +13,9 @@ import (
 
 // UserInput represents the input to the CreateUser function
 type UserInput struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	InviteCode string `json:"invite_code"`
 }
 
 // UserResponse represents the response written to the HTTP response header upon
@@ -44,12 +42,8 @@ func (a *API) CreateUser(w http.ResponseWriter, r *http.Request) {
 
 	user := &model.User{Email: input.Email}
 
-	if err := a.App.CreateUser(user, input.Password); err != nil {
-		if err, ok := err.(*app.ValidationError); ok {
-			respondWithError(w, http.StatusUnprocessableEntity, err.Error())
-		} else {
-			respondWithError(w, http.StatusInternalServerError, err.Error())
-		}
+	if err := a.App.CreateUser(user, input.Password, input.InviteCode); err != nil {
+		respondWithAppError(w, err)
 		return
 	}
 
@@ -76,28 +70,100 @@ func (a *API) GetUser(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (a *API) validateLogin(r *http.Request, userName, password string) (auth.Info, error) {
-	user, err := a.App.GetUserByEmail(userName)
+// TokenInput represents the input to the createToken function
+type TokenInput struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Name     string `json:"name"`
+}
+
+// TokenResponse is written to the HTTP response on login. RefreshToken is only set
+// when logins hand out JWT access tokens.
+type TokenResponse struct {
+	Token        string     `json:"token"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	RefreshToken string     `json:"refresh_token,omitempty"`
+}
+
+func (a *API) createToken(w http.ResponseWriter, r *http.Request) {
+	var input TokenInput
+
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := json.Unmarshal(body, &input); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	user, err := a.App.Login(input.Email, input.Password, a.Logger.IPAddressForRequest(r))
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
 
-	if user == nil || err != nil {
+	a.respondWithLogin(w, user, input.Name)
+}
+
+// respondWithLogin writes the token of a user who just signed in to the HTTP response,
+// or an MFA challenge instead if the user has two-factor authentication enabled.
+func (a *API) respondWithLogin(w http.ResponseWriter, user *model.User, name string) {
+	if user.TOTPEnabled() {
+		challenge, err := a.App.CreateMFAChallenge(user)
+		if err != nil {
+			respondWithAppError(w, err)
+			return
+		}
+		err = respondWithJSON(w, http.StatusOK, &MFAChallengeResponse{MFARequired: true, MFAToken: challenge})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	response, err := a.issueLoginToken(user, name)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+	err = respondWithJSON(w, http.StatusOK, response)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// issueLoginToken hands out the kind of token the AuthStrategy setting selects to a
+// user who just signed in. name is only used for opaque tokens.
+func (a *API) issueLoginToken(user *model.User, name string) (*TokenResponse, error) {
+	if a.App.Config.AuthStrategy == app.AuthStrategyJWT {
+		session, err := a.App.CreateSession(user)
 		if err != nil {
 			return nil, err
 		}
-		return nil, err
+		return sessionResponse(session), nil
 	}
 
-	if ok := user.CheckPassword(password); !ok {
-		return nil, errors.Wrap(err, "invalid credentials")
+	secret, token, err := a.App.CreateToken(user, name)
+	if err != nil {
+		return nil, err
 	}
-	return auth.NewDefaultUser(user.Email, strconv.Itoa(int(user.ID)), nil, nil), nil
+	return &TokenResponse{Token: secret, ExpiresAt: token.ExpiresAt}, nil
 }
 
-type TokenResponse struct {
-	Token string `json:"token"`
+// RefreshInput represents the input to the refreshToken function
+type RefreshInput struct {
+	RefreshToken string `json:"refresh_token"`
 }
 
-func (a *API) createToken(w http.ResponseWriter, r *http.Request) {
-	var input UserInput
+// refreshToken exchanges the refresh token given in the HTTP request for a new access
+// token and refresh token.
+func (a *API) refreshToken(w http.ResponseWriter, r *http.Request) {
+	var input RefreshInput
 
 	defer r.Body.Close()
 	body, err := ioutil.ReadAll(r.Body)
@@ -111,17 +177,19 @@ func (a *API) createToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := a.validateLogin(r, input.Email, input.Password)
+	session, err := a.App.RefreshSession(input.RefreshToken)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, err.Error())
+		respondWithAppError(w, err)
 		return
 	}
 
-	bearerToken := uuid.New().String()
-	a.App.AuthCache.Store(bearerToken, user, r)
-	err = respondWithJSON(w, http.StatusOK, &TokenResponse{Token: bearerToken})
+	err = respondWithJSON(w, http.StatusOK, sessionResponse(session))
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 }
+
+func sessionResponse(session *app.Session) *TokenResponse {
+	return &TokenResponse{Token: session.AccessToken, ExpiresAt: &session.ExpiresAt, RefreshToken: session.RefreshToken}
+}
@@ -0,0 +1,200 @@
+package app
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"leggett.dev/devmarks/api/db"
+	"leggett.dev/devmarks/api/mailer"
+	"leggett.dev/devmarks/api/model"
+)
+
+const (
+	// passwordResetAudience marks the tokens emailed to reset a password.
+	passwordResetAudience = "password-reset"
+	// passwordResetLifetime is how long a password reset link works.
+	passwordResetLifetime = time.Hour
+	// emailVerificationAudience marks the tokens emailed to verify an email address.
+	emailVerificationAudience = "verify-email"
+	// emailVerificationLifetime is how long an email verification link works.
+	emailVerificationLifetime = 48 * time.Hour
+)
+
+// ErrInvalidAccountToken is returned when a password reset or email verification token
+// is invalid, expired or was already used.
+var ErrInvalidAccountToken = &UserError{Message: "invalid or expired token", StatusCode: http.StatusBadRequest}
+
+// accountClaims are the claims of the tokens emailed to users. They are not stored;
+// instead Fingerprint ties a token to the state of the account it was issued for, so
+// it stops working once it has been used.
+type accountClaims struct {
+	jwt.StandardClaims
+	Fingerprint string `json:"fp"`
+}
+
+// fingerprint hashes the parts of the account a token depends on.
+func fingerprint(value []byte) string {
+	sum := sha256.Sum256(value)
+	return hex.EncodeToString(sum[:16])
+}
+
+// passwordResetFingerprint changes whenever the user's password does.
+func passwordResetFingerprint(user *model.User) string {
+	return fingerprint(user.HashedPassword)
+}
+
+// emailVerificationFingerprint changes whenever the user's email address does.
+func emailVerificationFingerprint(user *model.User) string {
+	return fingerprint([]byte(user.Email))
+}
+
+// signAccountToken returns a token for the user, for the audience, valid for lifetime.
+func (a *App) signAccountToken(user *model.User, audience, fingerprint string, lifetime time.Duration) (string, error) {
+	now := time.Now()
+	claims := accountClaims{
+		StandardClaims: jwt.StandardClaims{
+			Subject:   strconv.Itoa(int(user.ID)),
+			Issuer:    accessTokenIssuer,
+			Audience:  audience,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(lifetime).Unix(),
+		},
+		Fingerprint: fingerprint,
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(a.Config.SecretKey)
+}
+
+// parseAccountToken checks a token signed by signAccountToken for the audience and
+// returns the user it was issued to, as long as fingerprint still gives the same result.
+func (a *App) parseAccountToken(token, audience string, fingerprint func(*model.User) string) (*model.User, error) {
+	claims := &accountClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return a.Config.SecretKey, nil
+	})
+	if err != nil || claims.Audience != audience || claims.Issuer != accessTokenIssuer {
+		return nil, ErrInvalidAccountToken
+	}
+	id, err := strconv.ParseUint(claims.Subject, 10, 0)
+	if err != nil {
+		return nil, ErrInvalidAccountToken
+	}
+	user, err := a.Database.GetUserById(uint(id))
+	if err != nil {
+		if db.IsNotFound(err) {
+			return nil, ErrInvalidAccountToken
+		}
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(fingerprint(user)), []byte(claims.Fingerprint)) != 1 {
+		return nil, ErrInvalidAccountToken
+	}
+	return user, nil
+}
+
+// clientLink returns the URL of a page of the web client that handles the token.
+func (a *App) clientLink(page, token string) string {
+	return fmt.Sprintf("%s/%s?token=%s", a.Config.ClientURL, page, url.QueryEscape(token))
+}
+
+// RequestPasswordReset emails a link to reset their password to the user with the email
+// address. Nothing tells the caller whether such a user exists: unknown addresses and
+// failures to send are only logged.
+func (a *App) RequestPasswordReset(email string) error {
+	user, err := a.Database.GetUserByEmail(email)
+	if err != nil {
+		if db.IsNotFound(err) {
+			logrus.WithField("email", email).Info("password reset requested for unknown email")
+			return nil
+		}
+		return err
+	}
+
+	token, err := a.signAccountToken(user, passwordResetAudience, passwordResetFingerprint(user), passwordResetLifetime)
+	if err != nil {
+		return errors.Wrap(err, "unable to sign token")
+	}
+	err = a.Mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your devmarks password",
+		Body: "Someone asked to reset the password of your devmarks account. If it was you, choose a new password within the hour at:\n\n" +
+			a.clientLink("reset-password", token) + "\n\nIf it was not, you can ignore this email.\n",
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("user_id", user.ID).Error("unable to send password reset email")
+	}
+	return nil
+}
+
+// ResetPassword sets the password of the user the password reset token was issued to.
+// Every login session and token of the user is revoked, and since the token was
+// emailed to them their email address counts as verified.
+func (a *App) ResetPassword(token, password string) error {
+	user, err := a.parseAccountToken(token, passwordResetAudience, passwordResetFingerprint)
+	if err != nil {
+		return err
+	}
+	if err := validatePassword(password); err != nil {
+		return err
+	}
+
+	if err := user.SetPassword(password); err != nil {
+		return errors.Wrap(err, "unable to set user password")
+	}
+	if !user.EmailVerified() {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+	return a.Database.WithTransaction(func(tx *db.Database) error {
+		if err := tx.UpdateUser(user); err != nil {
+			return err
+		}
+		return tx.RevokeUserLogins(user.ID, time.Now())
+	})
+}
+
+// SendEmailVerification emails a link to confirm they own their email address to the
+// user.
+func (a *App) SendEmailVerification(user *model.User) error {
+	token, err := a.signAccountToken(user, emailVerificationAudience, emailVerificationFingerprint(user), emailVerificationLifetime)
+	if err != nil {
+		return errors.Wrap(err, "unable to sign token")
+	}
+	return a.Mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your devmarks email address",
+		Body: "Confirm this is the email address of your devmarks account within 48 hours at:\n\n" +
+			a.clientLink("verify-email", token) + "\n",
+	})
+}
+
+// VerifyEmail marks the email address the verification token was issued for as
+// verified and returns its user.
+func (a *App) VerifyEmail(token string) (*model.User, error) {
+	user, err := a.parseAccountToken(token, emailVerificationAudience, emailVerificationFingerprint)
+	if err != nil {
+		return nil, err
+	}
+	if user.EmailVerified() {
+		return nil, ErrInvalidAccountToken
+	}
+
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	if err := a.Database.UpdateUser(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
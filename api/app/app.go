@@ -1,25 +1,45 @@
 package app
 
 import (
+	"context"
+	"net/http"
+
 	"github.com/shaj13/go-guardian/auth"
-	"github.com/shaj13/go-guardian/store"
 	"github.com/sirupsen/logrus"
 	"leggett.dev/devmarks/api/db"
+	"leggett.dev/devmarks/api/fetcher"
+	"leggett.dev/devmarks/api/mailer"
+	"leggett.dev/devmarks/api/oidc"
+	"leggett.dev/devmarks/api/outbound"
+	"leggett.dev/devmarks/api/policy"
 )
 
 // App is an object representing our App's configuration
 type App struct {
 	Config   *Config
 	Database *db.Database
-	AuthCache store.Cache
+	Policy   *policy.Policy
+	// OIDC is the single sign-on provider, or nil if none is configured.
+	OIDC          *oidc.Provider
+	Mailer        mailer.Mailer
 	Authenticator auth.Authenticator
+	// HTTPClient makes the requests to URLs chosen by users, such as the pages their
+	// bookmarks point to, and cannot reach private addresses that are not allowed.
+	HTTPClient *http.Client
+
+	// metadata fetches the metadata of new bookmarks, or is nil if that is disabled.
+	metadata *metadataQueue
 }
 
 // NewContext returns a new Context object
 func (a *App) NewContext() *Context {
 	return &Context{
+		Context:  context.Background(),
 		Logger:   logrus.New(),
 		Database: a.Database,
+		Policy:   a.Policy,
+		Config:   a.Config,
+		metadata: a.metadata,
 	}
 }
 
@@ -38,12 +58,35 @@ func New() (app *App, err error) {
 	if err != nil {
 		return nil, err
 	}
+	app.Policy = newPolicy(app.Database)
+	app.Mailer, err = mailer.New(app.Config.Mailer)
+	if err != nil {
+		return nil, err
+	}
+	app.HTTPClient, err = outbound.New(app.Config.Outbound)
+	if err != nil {
+		return nil, err
+	}
+	if app.Config.FetchMetadata {
+		f := fetcher.New(app.HTTPClient, app.Config.Metadata)
+		app.metadata = newMetadataQueue(f, app.Database, app.Config.MetadataWorkers)
+	}
+	if app.Config.OIDC != nil {
+		app.OIDC = oidc.NewProvider(*app.Config.OIDC, nil)
+	}
 	return app, err
 }
 
+// newPolicy returns the policy deciding what users can do, granting access through
+// organizations and shared folders as well as ownership.
+func newPolicy(database *db.Database) *policy.Policy {
+	return policy.New(&organizationGranter{database: database}, &shareGranter{database: database})
+}
+
 // Close performs any actions necessary to close our our running
-// app, like closing the database connection
+// app, like waiting for background work and closing the database connection
 func (a *App) Close() error {
+	a.metadata.close()
 	return a.Database.Close()
 }
 
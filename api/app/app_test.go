@@ -0,0 +1,70 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"leggett.dev/devmarks/api/db/dbtest"
+	"leggett.dev/devmarks/api/helpers"
+	"leggett.dev/devmarks/api/model"
+)
+
+// newTestApp returns an app backed by an empty in-memory database with a table for
+// every model, and the policy the app uses.
+func newTestApp(t *testing.T) *App {
+	database := dbtest.New(t,
+		&model.User{}, &model.Identity{}, &model.Token{}, &model.RefreshToken{}, &model.LoginAttempt{},
+		&model.Invite{}, &model.FeedToken{}, &model.Organization{}, &model.Membership{},
+		&model.Folder{}, &model.Share{}, &model.Bookmark{}, &model.Tag{},
+	)
+	return &App{Config: &Config{}, Database: database, Policy: newPolicy(database)}
+}
+
+// signedIn creates a user with the email and returns a context signed in as them.
+func signedIn(t *testing.T, a *App, email string) *Context {
+	t.Helper()
+	user := &model.User{Email: email}
+	if err := a.Database.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return anonymous(a).WithUser(user)
+}
+
+// anonymous returns a context without a signed in user, like that of a request
+// without credentials.
+func anonymous(a *App) *Context {
+	ctx := a.NewContext()
+	// requests carry the embeds they asked for, which the database expects to find.
+	return ctx.WithContext(context.WithValue(ctx.Context, helpers.EmbedsKey, []string{}))
+}
+
+// createFolder creates a folder as the user of the context.
+func createFolder(t *testing.T, ctx *Context, folder *model.Folder) *model.Folder {
+	t.Helper()
+	if err := ctx.CreateFolder(folder); err != nil {
+		t.Fatal(err)
+	}
+	return folder
+}
+
+// createBookmark creates a bookmark to the URL as the user of the context, in the
+// given folders.
+func createBookmark(t *testing.T, ctx *Context, url string, folders ...*model.Folder) *model.Bookmark {
+	t.Helper()
+	bookmark := &model.Bookmark{Name: url, URL: url}
+	if err := ctx.CreateBookmark(bookmark, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, folder := range folders {
+		if err := ctx.AddBookmarkToFolder(folder.ID, bookmark.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return bookmark
+}
+
+// isStatus returns true if err is a UserError with the status code.
+func isStatus(err error, code int) bool {
+	userErr, ok := err.(*UserError)
+	return ok && userErr.StatusCode == code
+}
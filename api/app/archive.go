@@ -0,0 +1,60 @@
+package app
+
+import (
+	"leggett.dev/devmarks/api/archive"
+	"leggett.dev/devmarks/api/db"
+)
+
+// ExportArchive builds the archive of everything owned by the currently authenticated
+// user
+func (ctx *Context) ExportArchive() (*archive.Archive, error) {
+	if ctx.User == nil {
+		return nil, ctx.AuthorizationError()
+	}
+
+	return ctx.Database.ExportArchive(ctx.User.ID)
+}
+
+// ImportArchive restores an archive for the currently authenticated user, handling the
+// folders and bookmarks they already have according to conflict, one of db.ConflictSkip,
+// db.ConflictOverwrite or db.ConflictDuplicate. Its entries are validated the way the
+// API validates them, and tags are normalized.
+func (ctx *Context) ImportArchive(a *archive.Archive, conflict string) (*db.ArchiveImportResult, error) {
+	if ctx.User == nil {
+		return nil, ctx.AuthorizationError()
+	}
+	if conflict != db.ConflictSkip && conflict != db.ConflictOverwrite && conflict != db.ConflictDuplicate {
+		return nil, &ValidationError{"conflict must be one of " + db.ConflictSkip + ", " + db.ConflictOverwrite + ", " + db.ConflictDuplicate}
+	}
+	if err := validateArchive(a); err != nil {
+		return nil, err
+	}
+
+	return ctx.Database.ImportArchive(ctx.User.ID, a, conflict)
+}
+
+// validateArchive checks the hierarchy of the folders of the archive, and that its
+// entries would be accepted by the API, normalizing the names of its tags.
+func validateArchive(a *archive.Archive) error {
+	if err := a.Validate(); err != nil {
+		return &ValidationError{err.Error()}
+	}
+	for _, f := range a.Folders {
+		if f.Name == "" {
+			return &ValidationError{"folders in the archive must have a name"}
+		}
+	}
+	for i := range a.Tags {
+		name, err := validateTagName(a.Tags[i].Name)
+		if err != nil {
+			return err
+		}
+		a.Tags[i].Name = name
+	}
+	for _, b := range a.Bookmarks {
+		if b.URL == "" {
+			return &ValidationError{"bookmarks in the archive must have a url"}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package app
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"leggett.dev/devmarks/api/archive"
+	"leggett.dev/devmarks/api/db"
+	"leggett.dev/devmarks/api/model"
+)
+
+func TestImportArchiveNormalizesTags(t *testing.T) {
+	ctx := newImportContext(t)
+	a := &archive.Archive{
+		Tags: []archive.Tag{{ID: 1, Name: " go "}, {ID: 2, Name: "go"}, {ID: 3, Name: "web\n"}},
+		Bookmarks: []archive.Bookmark{
+			{ID: 1, Name: "Go", URL: "https://golang.org", TagIDs: []uint{1, 2, 3}},
+		},
+	}
+
+	result, err := ctx.ImportArchive(a, db.ConflictSkip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Tags.Created != 2 || result.Tags.Skipped != 1 {
+		t.Errorf("ImportArchive() tags = %+v, want 2 created and 1 skipped", result.Tags)
+	}
+	if got, want := tagNames(t, ctx), []string{"go", "web"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tags = %q, want %q", got, want)
+	}
+}
+
+func TestImportArchiveRejectsInvalidEntries(t *testing.T) {
+	parent := func(id uint) *uint { return &id }
+	bookmarks := []archive.Bookmark{{ID: 1, Name: "Go", URL: "https://golang.org"}}
+	tests := []struct {
+		name    string
+		archive archive.Archive
+	}{
+		{"tag name too long", archive.Archive{Tags: []archive.Tag{{ID: 1, Name: strings.Repeat("a", maxTagNameLength+1)}}, Bookmarks: bookmarks}},
+		{"empty tag name", archive.Archive{Tags: []archive.Tag{{ID: 1, Name: "  "}}, Bookmarks: bookmarks}},
+		{"empty folder name", archive.Archive{Folders: []archive.Folder{{ID: 1, Name: "Docs"}, {ID: 2, ParentID: parent(1)}}, Bookmarks: bookmarks}},
+		{"folder cycle", archive.Archive{Folders: []archive.Folder{{ID: 1, Name: "A", ParentID: parent(2)}, {ID: 2, Name: "B", ParentID: parent(1)}}, Bookmarks: bookmarks}},
+		{"unknown parent", archive.Archive{Folders: []archive.Folder{{ID: 1, Name: "A", ParentID: parent(3)}}, Bookmarks: bookmarks}},
+		{"bookmark without url", archive.Archive{Bookmarks: []archive.Bookmark{{ID: 1, Name: "Go"}}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := newImportContext(t)
+			if _, err := ctx.ImportArchive(&test.archive, db.ConflictSkip); !isValidationError(err) {
+				t.Fatalf("ImportArchive() error = %v, want a validation error", err)
+			}
+			var folders, bookmarks int
+			ctx.Database.Model(&model.Folder{}).Count(&folders)
+			ctx.Database.Model(&model.Bookmark{}).Count(&bookmarks)
+			if folders != 0 || bookmarks != 0 || len(tagNames(t, ctx)) != 0 {
+				t.Errorf("ImportArchive() created %d folders, %d bookmarks and tags %q, want nothing", folders, bookmarks, tagNames(t, ctx))
+			}
+		})
+	}
+}
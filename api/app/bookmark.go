@@ -1,58 +1,95 @@
 package app
 
-import "leggett.dev/devmarks/api/model"
+import (
+	"leggett.dev/devmarks/api/db"
+	"leggett.dev/devmarks/api/model"
+	"leggett.dev/devmarks/api/search"
+)
+
+// GetBookmarkByID returns a Bookmark model from the bookmark's ID, if the currently
+// authenticated user can see it.
+func (ctx *Context) GetBookmarkByID(id uint) (*model.Bookmark, error) {
+	if ctx.User == nil {
+		return nil, ctx.AuthorizationError()
+	}
 
-// GetBookmarkByID returns a Bookmark model from the bookmark's ID
-// func (ctx *Context) GetBookmarkByID(id uint) (*model.Bookmark, error) {
-// 	if ctx.User == nil {
-// 		return nil, ctx.AuthorizationError()
-// 	}
+	bookmark, err := ctx.Database.GetBookmarkByID(ctx, id)
+	if err != nil {
+		if db.IsNotFound(err) {
+			return nil, ctx.NotFoundError("bookmark")
+		}
+		return nil, err
+	}
 
-// 	bookmark, err := ctx.Database.GetBookmarkByID(ctx, id)
-// 	if err != nil {
-// 		return nil, err
-// 	}
+	if err := ctx.canRead(bookmark); err != nil {
+		return nil, err
+	}
 
-// 	if bookmark.OwnerID != ctx.User.ID {
-// 		return nil, ctx.AuthorizationError()
-// 	}
+	return bookmark, nil
+}
 
-// 	return bookmark, nil
-// }
+// GetUserBookmarks returns the page of bookmarks owned by the currently authenticated
+// User described by opts
+func (ctx *Context) GetUserBookmarks(opts *db.ListOptions) ([]*model.Bookmark, *db.Page, error) {
+	if ctx.User == nil {
+		return nil, nil, ctx.AuthorizationError()
+	}
 
-// func (ctx *Context) getBookmarksByUserID(userID uint) ([]*model.Bookmark, error) {
-// 	return ctx.Database.GetBookmarksByUserID(userID)
-// }
+	return ctx.Database.GetBookmarksByUserID(ctx, ctx.User.ID, opts)
+}
 
-// GetUserBookmarks returns a slice of all bookmark models that are owned by
-// The currently authenticated User
-// func (ctx *Context) GetUserBookmarks() ([]*model.Bookmark, error) {
-// 	if ctx.User == nil {
-// 		return nil, ctx.AuthorizationError()
-// 	}
+// SearchBookmarks returns the bookmarks owned by the currently authenticated User that
+// match the search query
+func (ctx *Context) SearchBookmarks(query *search.Query) ([]*model.Bookmark, error) {
+	if ctx.User == nil {
+		return nil, ctx.AuthorizationError()
+	}
 
-// 	return ctx.getBookmarksByUserID(ctx.User.ID)
-// }
+	return ctx.Database.SearchBookmarks(ctx, ctx.User.ID, query.Scopes()...)
+}
 
 // CreateBookmark performs the business logic necessary to create and
-// validate a Bookmark given an initial instance of one
-func (ctx *Context) CreateBookmark(bookmark *model.Bookmark) error {
+// validate a Bookmark given an initial instance of one, tagging it with
+// the tags with the given names. Bookmarks without a color get the default
+// bookmark color of the user. The page the bookmark points to is fetched in
+// the background afterwards, to fill in its metadata and, if it has none, its
+// name.
+func (ctx *Context) CreateBookmark(bookmark *model.Bookmark, tagNames []string) error {
 	if ctx.User == nil {
 		return ctx.AuthorizationError()
 	}
 
 	bookmark.OwnerID = ctx.User.ID
+	if bookmark.Color == nil {
+		bookmark.Color = ctx.User.Preferences.DefaultBookmarkColor
+	}
 
 	if err := ctx.validateBookmark(bookmark); err != nil {
 		return err
 	}
+	tagNames, err := normalizeTagNames(tagNames)
+	if err != nil {
+		return err
+	}
+
+	if err := ctx.Database.CreateBookmark(bookmark); err != nil {
+		return err
+	}
+	ctx.metadata.enqueue(bookmark)
 
-	return ctx.Database.CreateBookmark(bookmark)
+	if len(tagNames) == 0 {
+		return nil
+	}
+	return ctx.setBookmarkTags(bookmark, tagNames)
 }
 
 const maxBookmarkNameLength = 100
 
 func (ctx *Context) validateBookmark(bookmark *model.Bookmark) *ValidationError {
+	if bookmark.URL == "" {
+		return &ValidationError{"url is required"}
+	}
+
 	if len(bookmark.Name) > maxBookmarkNameLength {
 		return &ValidationError{"name is too long"}
 	}
@@ -61,14 +98,15 @@ func (ctx *Context) validateBookmark(bookmark *model.Bookmark) *ValidationError
 }
 
 // UpdateBookmark performs the business logic necessary to validate and update
-// a given bookmark model
-func (ctx *Context) UpdateBookmark(bookmark *model.Bookmark) error {
+// a given bookmark model. If tagNames is not nil the bookmark's tags are replaced
+// with the tags with those names.
+func (ctx *Context) UpdateBookmark(bookmark *model.Bookmark, tagNames *[]string) error {
 	if ctx.User == nil {
 		return ctx.AuthorizationError()
 	}
 
-	if bookmark.OwnerID != ctx.User.ID {
-		return ctx.AuthorizationError()
+	if err := ctx.canWrite(bookmark); err != nil {
+		return err
 	}
 
 	if bookmark.ID == 0 {
@@ -76,27 +114,55 @@ func (ctx *Context) UpdateBookmark(bookmark *model.Bookmark) error {
 	}
 
 	if err := ctx.validateBookmark(bookmark); err != nil {
+		return err
+	}
+	var names []string
+	if tagNames != nil {
+		var err error
+		if names, err = normalizeTagNames(*tagNames); err != nil {
+			return err
+		}
+	}
+
+	if err := ctx.Database.UpdateBookmark(bookmark); err != nil {
+		return err
+	}
+
+	if tagNames == nil {
 		return nil
 	}
+	return ctx.setBookmarkTags(bookmark, names)
+}
 
-	return ctx.Database.UpdateBookmark(bookmark)
+// setBookmarkTags replaces the tags of the bookmark with the tags of its owner with
+// the given names, creating the ones that do not exist.
+func (ctx *Context) setBookmarkTags(bookmark *model.Bookmark, names []string) error {
+	tags, err := ctx.Database.FindOrCreateTags(bookmark.OwnerID, names)
+	if err != nil {
+		return err
+	}
+	if err := ctx.Database.ReplaceBookmarkTags(bookmark, tags); err != nil {
+		return err
+	}
+	bookmark.Tags = tags
+	return nil
 }
 
 // DeleteBookmarkByID performs the necessary business logic to delete
 // a bookmark owned by the currently authenticated user
-// func (ctx *Context) DeleteBookmarkByID(id uint) error {
-// 	if ctx.User == nil {
-// 		return ctx.AuthorizationError()
-// 	}
-
-// 	bookmark, err := ctx.GetBookmarkByID(id)
-// 	if err != nil {
-// 		return err
-// 	}
-
-// 	if bookmark.OwnerID != ctx.User.ID {
-// 		return ctx.AuthorizationError()
-// 	}
-
-// 	return ctx.Database.DeleteBookmarkByID(id)
-// }
+func (ctx *Context) DeleteBookmarkByID(id uint) error {
+	if ctx.User == nil {
+		return ctx.AuthorizationError()
+	}
+
+	bookmark, err := ctx.GetBookmarkByID(id)
+	if err != nil {
+		return err
+	}
+
+	if err := ctx.canManage(bookmark); err != nil {
+		return err
+	}
+
+	return ctx.Database.DeleteBookmarkByID(id)
+}
@@ -0,0 +1,235 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"leggett.dev/devmarks/api/db"
+	"leggett.dev/devmarks/api/helpers"
+	"leggett.dev/devmarks/api/model"
+)
+
+// The operations a bulk request can apply to bookmarks.
+const (
+	BulkMove       = "move"
+	BulkAddTags    = "add_tags"
+	BulkRemoveTags = "remove_tags"
+	BulkRecolor    = "recolor"
+	BulkDelete     = "delete"
+)
+
+// MaxBulkBookmarks is the largest number of bookmarks a single bulk request may change.
+const MaxBulkBookmarks = 500
+
+// BulkOperation is a single change applied to every bookmark of a bulk request. Which
+// fields are used depends on Op:
+//   - move: removes the bookmark from FromFolderID, or from every folder the user can
+//     change if it is not given, then adds it to FolderID if it is given, which like
+//     adding a single bookmark to a folder needs the user to own the bookmark
+//   - add_tags, remove_tags: attaches or detaches the tags named in Tags
+//   - recolor: sets the color to Color
+//   - delete: deletes the bookmark; any operations after it are skipped
+type BulkOperation struct {
+	Op           string   `json:"op"`
+	FolderID     *uint    `json:"folder_id"`
+	FromFolderID *uint    `json:"from_folder_id"`
+	Tags         []string `json:"tags"`
+	Color        *string  `json:"color"`
+}
+
+// BulkResult reports what happened to one bookmark of a bulk request.
+type BulkResult struct {
+	ID     uint   `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Statuses of a BulkResult.
+const (
+	BulkStatusOK        = "ok"
+	BulkStatusNotFound  = "not_found"
+	BulkStatusForbidden = "forbidden"
+)
+
+// bulkPlan holds the operations of a bulk request after they have been validated,
+// along with the normalized names of the tags they refer to. Tags are looked up for
+// each bookmark, among the tags of its owner.
+type bulkPlan struct {
+	operations []BulkOperation
+	tagNames   map[int][]string
+	// manages is true if the operations need the user to manage the bookmarks, as they
+	// delete them or add them to folders.
+	manages bool
+}
+
+// BulkUpdateBookmarks applies a list of operations to a list of bookmarks in a single
+// transaction, and returns the outcome for each bookmark. Bookmarks that do not exist
+// or that the currently authenticated user cannot change are skipped and reported;
+// any other failure rolls back the whole request.
+func (ctx *Context) BulkUpdateBookmarks(ids []uint, operations []BulkOperation) ([]BulkResult, error) {
+	if ctx.User == nil {
+		return nil, ctx.AuthorizationError()
+	}
+
+	if len(ids) == 0 {
+		return nil, &ValidationError{"ids are required"}
+	}
+	if len(ids) > MaxBulkBookmarks {
+		return nil, &ValidationError{fmt.Sprintf("at most %d bookmarks can be changed at once", MaxBulkBookmarks)}
+	}
+	if len(operations) == 0 {
+		return nil, &ValidationError{"operations are required"}
+	}
+
+	// bookmarks are loaded without embeds, so saving them cannot re-attach associations
+	// the operations have just changed.
+	ctx = ctx.WithContext(context.WithValue(ctx.Context, helpers.EmbedsKey, []string{}))
+	var results []BulkResult
+	err := ctx.Database.WithTransaction(func(tx *db.Database) error {
+		txCtx := ctx.WithDatabase(tx)
+		plan, err := txCtx.planBulkOperations(operations)
+		if err != nil {
+			return err
+		}
+		results = make([]BulkResult, 0, len(ids))
+		for _, id := range ids {
+			result, err := txCtx.applyBulkOperations(id, plan)
+			if err != nil {
+				return err
+			}
+			results = append(results, result)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// planBulkOperations validates the operations of a bulk request and looks up the
+// folders they refer to. Only invalid operations are reported as validation errors;
+// failures to look the folders up are returned as they are.
+func (ctx *Context) planBulkOperations(operations []BulkOperation) (*bulkPlan, error) {
+	plan := &bulkPlan{operations: operations, tagNames: map[int][]string{}}
+	for i, op := range operations {
+		switch op.Op {
+		case BulkMove:
+			for _, folderID := range []*uint{op.FolderID, op.FromFolderID} {
+				if folderID == nil {
+					continue
+				}
+				folder, err := ctx.GetFolderByID(*folderID)
+				if err == nil {
+					err = ctx.canWrite(folder)
+				}
+				if err != nil {
+					if _, ok := err.(*UserError); ok {
+						return nil, &ValidationError{fmt.Sprintf("operation %d: folder %d does not exist", i, *folderID)}
+					}
+					return nil, err
+				}
+			}
+			if op.FolderID != nil {
+				plan.manages = true
+			}
+		case BulkAddTags, BulkRemoveTags:
+			names, err := normalizeTagNames(op.Tags)
+			if err != nil {
+				return nil, &ValidationError{fmt.Sprintf("operation %d: %s", i, err)}
+			}
+			if len(names) == 0 {
+				return nil, &ValidationError{fmt.Sprintf("operation %d: tags are required", i)}
+			}
+			plan.tagNames[i] = names
+		case BulkRecolor:
+			if op.Color == nil {
+				return nil, &ValidationError{fmt.Sprintf("operation %d: color is required", i)}
+			}
+		case BulkDelete:
+			plan.manages = true
+		default:
+			return nil, &ValidationError{fmt.Sprintf("operation %d: unknown op %q", i, op.Op)}
+		}
+	}
+	return plan, nil
+}
+
+// applyBulkOperations applies every operation of the plan to the bookmark with the
+// specified ID. Errors are only returned for failures that should abort the request.
+func (ctx *Context) applyBulkOperations(id uint, plan *bulkPlan) (BulkResult, error) {
+	bookmark, err := ctx.GetBookmarkByID(id)
+	if err == nil {
+		if plan.manages {
+			err = ctx.canManage(bookmark)
+		} else {
+			err = ctx.canWrite(bookmark)
+		}
+	}
+	if err != nil {
+		if err, ok := err.(*UserError); ok {
+			status := BulkStatusForbidden
+			if err.StatusCode == http.StatusNotFound {
+				status = BulkStatusNotFound
+			}
+			return BulkResult{ID: id, Status: status, Error: err.Error()}, nil
+		}
+		return BulkResult{}, err
+	}
+
+	for i, op := range plan.operations {
+		switch op.Op {
+		case BulkMove:
+			if op.FromFolderID != nil {
+				err = ctx.Database.RemoveBookmarkFromFolder(id, *op.FromFolderID)
+			} else {
+				err = ctx.removeBookmarkFromWritableFolders(id)
+			}
+			if err == nil && op.FolderID != nil {
+				err = ctx.Database.AddBookmarkToFolder(ctx, id, *op.FolderID)
+			}
+		case BulkAddTags:
+			var tags []model.Tag
+			if tags, err = ctx.Database.FindOrCreateTags(bookmark.OwnerID, plan.tagNames[i]); err == nil {
+				err = ctx.Database.AddTagsToBookmark(bookmark, tags)
+			}
+		case BulkRemoveTags:
+			var tags []model.Tag
+			if tags, err = ctx.Database.GetTagsByName(bookmark.OwnerID, plan.tagNames[i]); err == nil {
+				err = ctx.Database.RemoveTagsFromBookmark(bookmark, tags)
+			}
+		case BulkRecolor:
+			bookmark.Color = op.Color
+			err = ctx.Database.UpdateBookmark(bookmark)
+		case BulkDelete:
+			return BulkResult{ID: id, Status: BulkStatusOK}, ctx.Database.DeleteBookmarkByID(id)
+		}
+		if err != nil {
+			return BulkResult{}, err
+		}
+	}
+	return BulkResult{ID: id, Status: BulkStatusOK}, nil
+}
+
+// removeBookmarkFromWritableFolders removes the bookmark with the specified ID from
+// every folder it is in that the currently authenticated user can change.
+func (ctx *Context) removeBookmarkFromWritableFolders(id uint) error {
+	folders, err := ctx.Database.GetBookmarkFolders(id)
+	if err != nil {
+		return err
+	}
+	for _, folder := range folders {
+		ok, err := ctx.Policy.CanWrite(ctx.User, folder)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if err := ctx.Database.RemoveBookmarkFromFolder(id, folder.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
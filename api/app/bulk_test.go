@@ -0,0 +1,162 @@
+package app
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"leggett.dev/devmarks/api/db/dbtest"
+	"leggett.dev/devmarks/api/helpers"
+	"leggett.dev/devmarks/api/model"
+	"leggett.dev/devmarks/api/policy"
+)
+
+func TestBulkUpdateBookmarksErrors(t *testing.T) {
+	uid := func(id uint) *uint { return &id }
+	tests := []struct {
+		name string
+		// models are the tables the database has besides bookmarks; the request fails on
+		// the others.
+		models     []interface{}
+		operation  BulkOperation
+		validation bool
+	}{
+		{"missing folder", []interface{}{&model.Folder{}, &model.Tag{}}, BulkOperation{Op: BulkMove, FolderID: uid(42)}, true},
+		{"foreign folder", []interface{}{&model.Folder{}, &model.Tag{}}, BulkOperation{Op: BulkMove, FolderID: uid(1)}, true},
+		{"invalid tags", []interface{}{&model.Folder{}, &model.Tag{}}, BulkOperation{Op: BulkAddTags, Tags: []string{" "}}, true},
+		{"folder lookup fails", []interface{}{&model.Tag{}}, BulkOperation{Op: BulkMove, FolderID: uid(1)}, false},
+		{"tag creation fails", []interface{}{&model.Folder{}}, BulkOperation{Op: BulkAddTags, Tags: []string{"go"}}, false},
+		{"tag lookup fails", []interface{}{&model.Folder{}}, BulkOperation{Op: BulkRemoveTags, Tags: []string{"go"}}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a := &App{
+				Config:   &Config{},
+				Database: dbtest.New(t, append(test.models, &model.Bookmark{})...),
+				Policy:   policy.New(),
+			}
+			if a.Database.HasTable(&model.Folder{}) {
+				if err := a.Database.CreateFolder(&model.Folder{Name: "Someone else's", OwnerID: 2}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := a.Database.CreateBookmark(&model.Bookmark{URL: "https://golang.org", OwnerID: 1}); err != nil {
+				t.Fatal(err)
+			}
+			ctx := a.NewContext().WithUser(&model.User{Model: model.Model{ID: 1}})
+			ctx = ctx.WithContext(context.WithValue(ctx.Context, helpers.EmbedsKey, []string{}))
+
+			_, err := ctx.BulkUpdateBookmarks([]uint{1}, []BulkOperation{test.operation})
+			if err == nil {
+				t.Fatal("BulkUpdateBookmarks() succeeded, want an error")
+			}
+			if _, ok := err.(*UserError); ok {
+				t.Errorf("BulkUpdateBookmarks() error = %v, want a validation or server error", err)
+			}
+			if isValidationError(err) != test.validation {
+				t.Errorf("BulkUpdateBookmarks() error = %#v, want a validation error: %v", err, test.validation)
+			}
+		})
+	}
+}
+
+func TestBulkUpdateBookmarksTagsBelongToOwner(t *testing.T) {
+	a := newTestApp(t)
+	alice := signedIn(t, a, "alice@example.com")
+	victor := signedIn(t, a, "victor@example.com")
+
+	shared := createFolder(t, alice, &model.Folder{Name: "Shared"})
+	bookmark := createBookmark(t, alice, "https://golang.org", shared)
+	if err := alice.UpdateBookmark(bookmark, &[]string{"old"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := alice.ShareFolder(shared.ID, "victor@example.com", model.AccessEdit); err != nil {
+		t.Fatal(err)
+	}
+
+	operations := []BulkOperation{{Op: BulkAddTags, Tags: []string{"go"}}, {Op: BulkRemoveTags, Tags: []string{"old"}}}
+	results, err := victor.BulkUpdateBookmarks([]uint{bookmark.ID}, operations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Status != BulkStatusOK {
+		t.Fatalf("BulkUpdateBookmarks() = %+v, want the bookmark changed", results)
+	}
+
+	if got, want := tagNames(t, alice), []string{"go", "old"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tags of the owner = %q, want %q", got, want)
+	}
+	if got := tagNames(t, victor); len(got) != 0 {
+		t.Errorf("tags of the collaborator = %q, want none", got)
+	}
+	var tags []model.Tag
+	if err := a.Database.Model(bookmark).Association("Tags").Find(&tags).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 1 || tags[0].Name != "go" || tags[0].OwnerID != alice.User.ID {
+		t.Errorf("tags of the bookmark = %+v, want the owner's go tag", tags)
+	}
+}
+
+func TestBulkUpdateBookmarksMove(t *testing.T) {
+	a := newTestApp(t)
+	alice := signedIn(t, a, "alice@example.com")
+	victor := signedIn(t, a, "victor@example.com")
+
+	shared := createFolder(t, alice, &model.Folder{Name: "Shared"})
+	private := createFolder(t, alice, &model.Folder{Name: "Private"})
+	bookmark := createBookmark(t, alice, "https://golang.org", shared, private)
+	if _, err := alice.ShareFolder(shared.ID, "victor@example.com", model.AccessEdit); err != nil {
+		t.Fatal(err)
+	}
+	own := createFolder(t, victor, &model.Folder{Name: "Victor's"})
+
+	folderNames := func() []string {
+		t.Helper()
+		folders, err := a.Database.GetBookmarkFolders(bookmark.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := []string{}
+		for _, folder := range folders {
+			names = append(names, folder.Name)
+		}
+		return names
+	}
+
+	// like adding a single bookmark to a folder, moving a bookmark into one needs the
+	// user to own it.
+	results, err := victor.BulkUpdateBookmarks([]uint{bookmark.ID}, []BulkOperation{{Op: BulkMove, FolderID: &own.ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Status != BulkStatusForbidden {
+		t.Errorf("moving into a folder: status = %q, want %q", results[0].Status, BulkStatusForbidden)
+	}
+	if got, want := folderNames(), []string{"Shared", "Private"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("folders = %q, want %q", got, want)
+	}
+
+	// moving out of every folder only affects the folders the user can change.
+	results, err = victor.BulkUpdateBookmarks([]uint{bookmark.ID}, []BulkOperation{{Op: BulkMove}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Status != BulkStatusOK {
+		t.Errorf("moving out of folders: status = %q, want %q", results[0].Status, BulkStatusOK)
+	}
+	if got, want := folderNames(), []string{"Private"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("folders = %q, want %q", got, want)
+	}
+
+	results, err = alice.BulkUpdateBookmarks([]uint{bookmark.ID}, []BulkOperation{{Op: BulkMove, FolderID: &shared.ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Status != BulkStatusOK {
+		t.Errorf("owner moving: status = %q, want %q", results[0].Status, BulkStatusOK)
+	}
+	if got, want := folderNames(), []string{"Shared"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("folders = %q, want %q", got, want)
+	}
+}
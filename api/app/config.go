@@ -2,14 +2,111 @@ package app
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
+
+	"leggett.dev/devmarks/api/fetcher"
+	"leggett.dev/devmarks/api/mailer"
+	"leggett.dev/devmarks/api/oidc"
+	"leggett.dev/devmarks/api/outbound"
+)
+
+// defaultTokenLifetime is how long login sessions last when TokenLifetime is not set.
+const defaultTokenLifetime = 30 * 24 * time.Hour
+
+// defaultAccessTokenLifetime is how long JWT access tokens stay valid when
+// AccessTokenLifetime is not set.
+const defaultAccessTokenLifetime = 15 * time.Minute
+
+// Defaults for throttling failed logins when the Login settings are not set.
+const (
+	defaultLoginWindow           = 15 * time.Minute
+	defaultLoginMaxAttempts      = 5
+	defaultLoginMaxAttemptsPerIP = 20
+	defaultLoginLockout          = time.Minute
+)
+
+// defaultClientURL is where the web client is served when ClientURL is not set.
+const defaultClientURL = "http://localhost:3000"
+
+// defaultMetadataWorkers is how many bookmarks have their metadata fetched at once
+// when Metadata.Workers is not set.
+const defaultMetadataWorkers = 4
+
+// The kinds of tokens the AuthStrategy setting can have logins hand out.
+const (
+	// AuthStrategyJWT hands out short-lived JWT access tokens along with refresh tokens.
+	AuthStrategyJWT = "jwt"
+	// AuthStrategyToken hands out opaque tokens stored in the database.
+	AuthStrategyToken = "token"
+)
+
+// The ways new users can register, set with the Registration setting.
+const (
+	// RegistrationOpen lets anyone who can reach the server register.
+	RegistrationOpen = "open"
+	// RegistrationInvite requires a valid invite code to register.
+	RegistrationInvite = "invite"
+	// RegistrationClosed refuses every registration.
+	RegistrationClosed = "closed"
 )
 
 // Config represents our App's configuration (secret-key, etc)
 type Config struct {
 	// A secret string used for session cookies, passwords, etc.
 	SecretKey []byte
+	// How long a login lasts: the lifetime of the opaque tokens, or of the refresh
+	// tokens, handed out on login.
+	TokenLifetime time.Duration
+	// The kind of tokens handed out on login, either AuthStrategyJWT or AuthStrategyToken.
+	AuthStrategy string
+	// How long JWT access tokens stay valid. They cannot be revoked, so this should
+	// be short.
+	AccessTokenLifetime time.Duration
+	// The OpenID Connect provider users can sign in with, or nil if single sign-on is
+	// not set up.
+	OIDC *oidc.Config
+	// How far back failed logins are counted, and the longest an account stays locked.
+	LoginWindow time.Duration
+	// The number of failed logins in a row after which an account is locked.
+	LoginMaxAttempts int
+	// The number of failed logins from one IP address within LoginWindow after which
+	// logins from it are refused.
+	LoginMaxAttemptsPerIP int
+	// How long an account is first locked. It doubles with every further failed login.
+	LoginLockout time.Duration
+	// How emails to users are sent.
+	Mailer mailer.Config
+	// The URL of the web client, which links in emails point to.
+	ClientURL string
+	// Who can register, one of RegistrationOpen, RegistrationInvite or RegistrationClosed.
+	Registration string
+	// The email addresses of the users who administer this server, once verified.
+	Admins []string
+	// The limits on every request made to URLs chosen by users, and the private
+	// hosts and networks they can reach.
+	Outbound outbound.Config
+	// Whether the pages bookmarks point to are fetched after they are created, to fill
+	// in their title, description and images.
+	FetchMetadata bool
+	// The limits on fetching the pages bookmarks point to.
+	Metadata fetcher.Config
+	// How many bookmarks have their metadata fetched at once.
+	MetadataWorkers int
+	// Whether the links of bookmarks are checked periodically while serving.
+	CheckLinks bool
+	// How often the links that are due are checked.
+	LinkCheckEvery time.Duration
+	// How long after a link worked it is checked again.
+	LinkCheckInterval time.Duration
+	// How long after a link first failed it is checked again, doubled after each
+	// further failure.
+	LinkCheckRetry time.Duration
+	// How many bookmarks are loaded, and how many are checked at once, at a time.
+	LinkCheckBatchSize int
+	LinkCheckWorkers   int
 }
 
 // InitConfig initializes our App's Config object based on viper or default values
@@ -17,10 +114,121 @@ type Config struct {
 // and there is no default.
 func InitConfig() (*Config, error) {
 	config := &Config{
-		SecretKey: []byte(viper.GetString("SecretKey")),
+		SecretKey:     []byte(viper.GetString("SecretKey")),
+		TokenLifetime: viper.GetDuration("TokenLifetime"),
+		AuthStrategy:  viper.GetString("AuthStrategy"),
+
+		AccessTokenLifetime: viper.GetDuration("AccessTokenLifetime"),
+
+		LoginWindow:           viper.GetDuration("LoginWindow"),
+		LoginMaxAttempts:      viper.GetInt("LoginMaxAttempts"),
+		LoginMaxAttemptsPerIP: viper.GetInt("LoginMaxAttemptsPerIP"),
+		LoginLockout:          viper.GetDuration("LoginLockout"),
+
+		Mailer: mailer.Config{
+			Driver:   viper.GetString("Mailer.Driver"),
+			From:     viper.GetString("Mailer.From"),
+			Host:     viper.GetString("Mailer.Host"),
+			Port:     viper.GetInt("Mailer.Port"),
+			Username: viper.GetString("Mailer.Username"),
+			Password: viper.GetString("Mailer.Password"),
+			Path:     viper.GetString("Mailer.Path"),
+		},
+		ClientURL: strings.TrimSuffix(viper.GetString("ClientURL"), "/"),
+
+		Registration: viper.GetString("Registration"),
+		Admins:       viper.GetStringSlice("Admins"),
+
+		Outbound: outbound.Config{
+			Timeout:      viper.GetDuration("Outbound.Timeout"),
+			MaxRedirects: viper.GetInt("Outbound.MaxRedirects"),
+			MaxBytes:     viper.GetInt64("Outbound.MaxBytes"),
+			Schemes:      viper.GetStringSlice("Outbound.Schemes"),
+			Allow:        viper.GetStringSlice("Outbound.Allow"),
+		},
+		FetchMetadata: !viper.GetBool("Metadata.Disabled"),
+		Metadata: fetcher.Config{
+			Timeout:   viper.GetDuration("Metadata.Timeout"),
+			MaxBytes:  viper.GetInt64("Metadata.MaxBytes"),
+			UserAgent: viper.GetString("Metadata.UserAgent"),
+		},
+		MetadataWorkers: viper.GetInt("Metadata.Workers"),
+
+		CheckLinks:         !viper.GetBool("LinkCheck.Disabled"),
+		LinkCheckEvery:     viper.GetDuration("LinkCheck.Every"),
+		LinkCheckInterval:  viper.GetDuration("LinkCheck.Interval"),
+		LinkCheckRetry:     viper.GetDuration("LinkCheck.Retry"),
+		LinkCheckBatchSize: viper.GetInt("LinkCheck.BatchSize"),
+		LinkCheckWorkers:   viper.GetInt("LinkCheck.Workers"),
 	}
 	if len(config.SecretKey) == 0 {
 		return nil, fmt.Errorf("SecretKey must be set")
 	}
+	if config.TokenLifetime == 0 {
+		config.TokenLifetime = defaultTokenLifetime
+	}
+	if config.AuthStrategy == "" {
+		config.AuthStrategy = AuthStrategyJWT
+	}
+	if config.AuthStrategy != AuthStrategyJWT && config.AuthStrategy != AuthStrategyToken {
+		return nil, fmt.Errorf("AuthStrategy must be one of %s, %s", AuthStrategyJWT, AuthStrategyToken)
+	}
+	if config.AccessTokenLifetime == 0 {
+		config.AccessTokenLifetime = defaultAccessTokenLifetime
+	}
+	if config.LoginWindow == 0 {
+		config.LoginWindow = defaultLoginWindow
+	}
+	if config.LoginMaxAttempts == 0 {
+		config.LoginMaxAttempts = defaultLoginMaxAttempts
+	}
+	if config.LoginMaxAttemptsPerIP == 0 {
+		config.LoginMaxAttemptsPerIP = defaultLoginMaxAttemptsPerIP
+	}
+	if config.LoginLockout == 0 {
+		config.LoginLockout = defaultLoginLockout
+	}
+	if config.Mailer.From == "" {
+		config.Mailer.From = "devmarks <no-reply@devmarks.local>"
+	}
+	if config.ClientURL == "" {
+		config.ClientURL = defaultClientURL
+	}
+	if config.Registration == "" {
+		config.Registration = RegistrationOpen
+	}
+	if config.Registration != RegistrationOpen && config.Registration != RegistrationInvite && config.Registration != RegistrationClosed {
+		return nil, fmt.Errorf("Registration must be one of %s, %s, %s", RegistrationOpen, RegistrationInvite, RegistrationClosed)
+	}
+	if config.MetadataWorkers <= 0 {
+		config.MetadataWorkers = defaultMetadataWorkers
+	}
+	if config.LinkCheckEvery <= 0 {
+		config.LinkCheckEvery = defaultLinkCheckEvery
+	}
+	if config.LinkCheckInterval <= 0 {
+		config.LinkCheckInterval = defaultLinkCheckInterval
+	}
+	if config.LinkCheckRetry <= 0 {
+		config.LinkCheckRetry = defaultLinkCheckRetry
+	}
+	if config.LinkCheckBatchSize <= 0 {
+		config.LinkCheckBatchSize = defaultLinkCheckBatchSize
+	}
+	if config.LinkCheckWorkers <= 0 {
+		config.LinkCheckWorkers = defaultLinkCheckWorkers
+	}
+	if issuer := viper.GetString("OIDC.Issuer"); issuer != "" {
+		config.OIDC = &oidc.Config{
+			Issuer:       issuer,
+			ClientID:     viper.GetString("OIDC.ClientID"),
+			ClientSecret: viper.GetString("OIDC.ClientSecret"),
+			RedirectURL:  viper.GetString("OIDC.RedirectURL"),
+			Scopes:       viper.GetStringSlice("OIDC.Scopes"),
+		}
+		if config.OIDC.ClientID == "" || config.OIDC.RedirectURL == "" {
+			return nil, fmt.Errorf("OIDC.ClientID and OIDC.RedirectURL must be set to use OIDC")
+		}
+	}
 	return config, nil
 }
@@ -1,21 +1,46 @@
 package app
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/sirupsen/logrus"
 
 	"leggett.dev/devmarks/api/db"
 	"leggett.dev/devmarks/api/model"
+	"leggett.dev/devmarks/api/policy"
 )
 
 // Context represents the current Context of our application (logger, remote address,
-// currently logged in user, etc.))
+// currently logged in user, etc.)) and provides the service methods handlers go through
+// to read and change resources, so that every access is checked against the Policy.
+// It wraps the context.Context of the request it was made for.
 type Context struct {
+	context.Context
 	Logger        logrus.FieldLogger
 	RemoteAddress string
 	Database      *db.Database
+	Policy        *policy.Policy
+	Config        *Config
 	User          *model.User
+
+	metadata *metadataQueue
+}
+
+// WithContext returns an instance of the context it was called on wrapping the specified
+// context.Context, usually the one of the current HTTP request.
+func (ctx *Context) WithContext(c context.Context) *Context {
+	ret := *ctx
+	ret.Context = c
+	return &ret
+}
+
+// WithDatabase returns an instance of the context it was called on with the specified
+// Database substituted in, for instance one bound to a transaction.
+func (ctx *Context) WithDatabase(database *db.Database) *Context {
+	ret := *ctx
+	ret.Database = database
+	return &ret
 }
 
 // WithLogger returns an instance of the context it was called on with the specified logger
@@ -46,3 +71,44 @@ func (ctx *Context) WithUser(user *model.User) *Context {
 func (ctx *Context) AuthorizationError() *UserError {
 	return &UserError{Message: "unauthorized", StatusCode: http.StatusForbidden}
 }
+
+// NotFoundError returns a UserError signifying the requested resource does not exist
+func (ctx *Context) NotFoundError(resource string) *UserError {
+	return &UserError{Message: resource + " does not exist", StatusCode: http.StatusNotFound}
+}
+
+// canRead returns an error unless the current user can see the resource.
+func (ctx *Context) canRead(resource interface{}) error {
+	ok, err := ctx.Policy.CanRead(ctx.User, resource)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ctx.AuthorizationError()
+	}
+	return nil
+}
+
+// canWrite returns an error unless the current user can change the resource.
+func (ctx *Context) canWrite(resource interface{}) error {
+	ok, err := ctx.Policy.CanWrite(ctx.User, resource)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ctx.AuthorizationError()
+	}
+	return nil
+}
+
+// canManage returns an error unless the current user can delete or share the resource.
+func (ctx *Context) canManage(resource interface{}) error {
+	ok, err := ctx.Policy.CanManage(ctx.User, resource)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ctx.AuthorizationError()
+	}
+	return nil
+}
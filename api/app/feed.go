@@ -0,0 +1,168 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"leggett.dev/devmarks/api/db"
+	"leggett.dev/devmarks/api/feed"
+	"leggett.dev/devmarks/api/model"
+)
+
+// feedItemLimit is the number of most recent bookmarks feeds hold.
+const feedItemLimit = 50
+
+// ErrInvalidFeedToken is returned when the token in a feed URL does not exist, was
+// revoked or belongs to another feed.
+var ErrInvalidFeedToken = &UserError{Message: "invalid feed token", StatusCode: http.StatusUnauthorized}
+
+// CreateFolderFeedToken issues the token for the feed of a folder the currently
+// authenticated user can see, replacing the one they had for it. The returned secret
+// is the only time the token itself is available.
+func (ctx *Context) CreateFolderFeedToken(folderID uint) (string, *model.FeedToken, error) {
+	if _, err := ctx.GetFolderByID(folderID); err != nil {
+		return "", nil, err
+	}
+
+	secret, token := model.NewFeedToken(ctx.User.ID, &folderID, nil)
+	if err := ctx.Database.ReplaceFeedToken(token); err != nil {
+		return "", nil, err
+	}
+	return secret, token, nil
+}
+
+// CreateTagFeedToken issues the token for the feed of a tag the currently authenticated
+// user can see, replacing the one they had for it. The returned secret is the only
+// time the token itself is available.
+func (ctx *Context) CreateTagFeedToken(tagID uint) (string, *model.FeedToken, error) {
+	if _, err := ctx.GetTagByID(tagID); err != nil {
+		return "", nil, err
+	}
+
+	secret, token := model.NewFeedToken(ctx.User.ID, nil, &tagID)
+	if err := ctx.Database.ReplaceFeedToken(token); err != nil {
+		return "", nil, err
+	}
+	return secret, token, nil
+}
+
+// RevokeFolderFeedToken revokes the currently authenticated user's token for the feed
+// of a folder, so its feed URL stops working.
+func (ctx *Context) RevokeFolderFeedToken(folderID uint) error {
+	if ctx.User == nil {
+		return ctx.AuthorizationError()
+	}
+
+	return ctx.Database.DeleteFeedToken(ctx.User.ID, &folderID, nil)
+}
+
+// RevokeTagFeedToken revokes the currently authenticated user's token for the feed of
+// a tag, so its feed URL stops working.
+func (ctx *Context) RevokeTagFeedToken(tagID uint) error {
+	if ctx.User == nil {
+		return ctx.AuthorizationError()
+	}
+
+	return ctx.Database.DeleteFeedToken(ctx.User.ID, nil, &tagID)
+}
+
+// GetFolderFeed returns the feed of the most recent bookmarks in a folder, for the
+// holder of the folder's feed token. The feed is read as the user the token belongs
+// to, who must still be able to see the folder.
+func (ctx *Context) GetFolderFeed(folderID uint, secret string) (*feed.Feed, error) {
+	token, err := ctx.authenticateFeedToken(secret)
+	if err != nil {
+		return nil, err
+	}
+	if token.FolderID == nil || *token.FolderID != folderID {
+		return nil, ErrInvalidFeedToken
+	}
+
+	ctx = ctx.WithUser(token.User)
+	folder, err := ctx.GetFolderByID(folderID)
+	if err != nil {
+		return nil, err
+	}
+	bookmarks, err := ctx.Database.GetFolderFeedBookmarks(folderID, feedItemLimit)
+	if err != nil {
+		return nil, err
+	}
+	return ctx.buildFeed(folder.Name, fmt.Sprintf("/folders/%d", folder.ID), folder.UpdatedAt, bookmarks), nil
+}
+
+// GetTagFeed returns the feed of the most recent bookmarks with a tag, for the holder
+// of the tag's feed token.
+func (ctx *Context) GetTagFeed(tagID uint, secret string) (*feed.Feed, error) {
+	token, err := ctx.authenticateFeedToken(secret)
+	if err != nil {
+		return nil, err
+	}
+	if token.TagID == nil || *token.TagID != tagID {
+		return nil, ErrInvalidFeedToken
+	}
+
+	ctx = ctx.WithUser(token.User)
+	tag, err := ctx.GetTagByID(tagID)
+	if err != nil {
+		return nil, err
+	}
+	bookmarks, err := ctx.Database.GetTagFeedBookmarks(tagID, feedItemLimit)
+	if err != nil {
+		return nil, err
+	}
+	return ctx.buildFeed("#"+tag.Name, fmt.Sprintf("/tags/%d", tag.ID), tag.UpdatedAt, bookmarks), nil
+}
+
+// authenticateFeedToken returns the feed token matching the given secret, with its
+// user preloaded, and records that it was used.
+func (ctx *Context) authenticateFeedToken(secret string) (*model.FeedToken, error) {
+	if secret == "" {
+		return nil, ErrInvalidFeedToken
+	}
+	token, err := ctx.Database.GetFeedTokenByHash(model.HashToken(secret))
+	if err != nil {
+		if db.IsNotFound(err) {
+			return nil, ErrInvalidFeedToken
+		}
+		return nil, err
+	}
+	if token.User == nil {
+		return nil, ErrInvalidFeedToken
+	}
+
+	now := time.Now()
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > tokenTouchInterval {
+		if err := ctx.Database.TouchFeedToken(token.ID, now); err != nil {
+			return nil, err
+		}
+		token.LastUsedAt = &now
+	}
+	return token, nil
+}
+
+// buildFeed returns the feed of the bookmarks, which link to the page at path in the
+// web client. The feed was last updated when the newest of them, or the folder or tag
+// it is about, was.
+func (ctx *Context) buildFeed(title, path string, updated time.Time, bookmarks []*model.Bookmark) *feed.Feed {
+	link := ctx.Config.ClientURL + path
+	f := &feed.Feed{ID: link, Title: title, Link: link, Updated: updated, Items: []*feed.Item{}}
+	for _, bookmark := range bookmarks {
+		item := &feed.Item{
+			ID:        fmt.Sprintf("%s/bookmarks/%d", ctx.Config.ClientURL, bookmark.ID),
+			Title:     bookmark.Name,
+			URL:       bookmark.URL,
+			Content:   bookmark.Notes,
+			Published: bookmark.CreatedAt,
+			Updated:   bookmark.UpdatedAt,
+		}
+		for _, tag := range bookmark.Tags {
+			item.Tags = append(item.Tags, tag.Name)
+		}
+		if bookmark.UpdatedAt.After(f.Updated) {
+			f.Updated = bookmark.UpdatedAt
+		}
+		f.Items = append(f.Items, item)
+	}
+	return f
+}
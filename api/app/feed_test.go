@@ -0,0 +1,165 @@
+package app
+
+import (
+	"strconv"
+	"testing"
+
+	"leggett.dev/devmarks/api/model"
+)
+
+func TestFolderFeed(t *testing.T) {
+	a := newTestApp(t)
+	a.Config.ClientURL = "https://devmarks.local"
+	alice := signedIn(t, a, "alice@example.com")
+	victor := signedIn(t, a, "victor@example.com")
+
+	folder := createFolder(t, alice, &model.Folder{Name: "Reading"})
+	other := createFolder(t, alice, &model.Folder{Name: "Other"})
+	createBookmark(t, alice, "https://golang.org", folder)
+	createBookmark(t, alice, "https://go.dev", folder)
+	createBookmark(t, alice, "https://example.com", other)
+
+	if _, _, err := victor.CreateFolderFeedToken(folder.ID); !isForbidden(err) {
+		t.Errorf("token for a folder the user cannot see: err = %v, want forbidden", err)
+	}
+
+	secret, _, err := alice.CreateFolderFeedToken(folder.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// feeds are read without signing in, with the token alone.
+	f, err := anonymous(a).GetFolderFeed(folder.ID, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Title != "Reading" || f.Link != "https://devmarks.local/folders/"+uintString(folder.ID) || len(f.Items) != 2 {
+		t.Errorf("feed = %+v, want Reading with its two bookmarks", f)
+	}
+
+	for name, test := range map[string]struct {
+		folderID uint
+		secret   string
+	}{
+		"no token":         {folder.ID, ""},
+		"wrong token":      {folder.ID, secret + "x"},
+		"another folder":   {other.ID, secret},
+		"a missing folder": {other.ID + 100, secret},
+	} {
+		if _, err := anonymous(a).GetFolderFeed(test.folderID, test.secret); err != ErrInvalidFeedToken {
+			t.Errorf("%s: err = %v, want %v", name, err, ErrInvalidFeedToken)
+		}
+	}
+
+	// issuing a token again replaces the old one, and revoking it ends the feed.
+	replaced, _, err := alice.CreateFolderFeedToken(folder.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := anonymous(a).GetFolderFeed(folder.ID, secret); err != ErrInvalidFeedToken {
+		t.Errorf("replaced token: err = %v, want %v", err, ErrInvalidFeedToken)
+	}
+	if err := alice.RevokeFolderFeedToken(folder.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := anonymous(a).GetFolderFeed(folder.ID, replaced); err != ErrInvalidFeedToken {
+		t.Errorf("revoked token: err = %v, want %v", err, ErrInvalidFeedToken)
+	}
+}
+
+func TestFolderFeedFollowsAccess(t *testing.T) {
+	a := newTestApp(t)
+	alice := signedIn(t, a, "alice@example.com")
+	victor := signedIn(t, a, "victor@example.com")
+
+	folder := createFolder(t, alice, &model.Folder{Name: "Shared"})
+	createBookmark(t, alice, "https://golang.org", folder)
+	if _, err := alice.ShareFolder(folder.ID, "victor@example.com", model.AccessRead); err != nil {
+		t.Fatal(err)
+	}
+	secret, _, err := victor.CreateFolderFeedToken(folder.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := anonymous(a).GetFolderFeed(folder.ID, secret); err != nil {
+		t.Fatalf("feed of a shared folder: %v", err)
+	}
+
+	// the feed is read as the holder of the token, so it stops once they lose access.
+	if err := alice.Unshare(folder.ID, victor.User.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := anonymous(a).GetFolderFeed(folder.ID, secret); !isForbidden(err) {
+		t.Errorf("feed of a folder no longer shared: err = %v, want forbidden", err)
+	}
+}
+
+func TestTagFeed(t *testing.T) {
+	a := newTestApp(t)
+	alice := signedIn(t, a, "alice@example.com")
+	victor := signedIn(t, a, "victor@example.com")
+
+	for url, tags := range map[string][]string{
+		"https://golang.org":  {"go"},
+		"https://go.dev":      {"go", "docs"},
+		"https://example.com": {"docs"},
+	} {
+		if err := alice.CreateBookmark(&model.Bookmark{Name: url, URL: url}, tags); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tags, err := alice.GetUserTags()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var goTag, docsTag *model.Tag
+	for _, tag := range tags {
+		switch tag.Name {
+		case "go":
+			goTag = tag
+		case "docs":
+			docsTag = tag
+		}
+	}
+
+	if _, _, err := victor.CreateTagFeedToken(goTag.ID); err == nil {
+		t.Error("CreateTagFeedToken() issued a token for the tag of another user")
+	}
+	secret, _, err := alice.CreateTagFeedToken(goTag.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := anonymous(a).GetTagFeed(goTag.ID, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Title != "#go" || len(f.Items) != 2 {
+		t.Errorf("feed = %+v, want #go with its two bookmarks", f)
+	}
+	for _, item := range f.Items {
+		if !contains(item.Tags, "go") {
+			t.Errorf("item %s tags = %v, want them to include go", item.URL, item.Tags)
+		}
+	}
+	if _, err := anonymous(a).GetTagFeed(docsTag.ID, secret); err != ErrInvalidFeedToken {
+		t.Errorf("token of another tag: err = %v, want %v", err, ErrInvalidFeedToken)
+	}
+
+	// folder and tag tokens are not interchangeable.
+	folder := createFolder(t, alice, &model.Folder{Name: "Reading"})
+	if _, err := anonymous(a).GetFolderFeed(folder.ID, secret); err != ErrInvalidFeedToken {
+		t.Errorf("tag token for a folder feed: err = %v, want %v", err, ErrInvalidFeedToken)
+	}
+}
+
+func uintString(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,236 @@
+package app
+
+import (
+	"leggett.dev/devmarks/api/db"
+	"leggett.dev/devmarks/api/model"
+)
+
+// GetFolderByID returns a Folder model from the folder's ID, if the currently
+// authenticated user can see it.
+func (ctx *Context) GetFolderByID(id uint) (*model.Folder, error) {
+	if ctx.User == nil {
+		return nil, ctx.AuthorizationError()
+	}
+
+	folder, err := ctx.Database.GetFolderByID(ctx, id)
+	if err != nil {
+		if db.IsNotFound(err) {
+			return nil, ctx.NotFoundError("folder")
+		}
+		return nil, err
+	}
+
+	if err := ctx.canRead(folder); err != nil {
+		return nil, err
+	}
+
+	return folder, nil
+}
+
+// GetUserFolders returns the page of folders owned by the currently authenticated
+// User described by opts
+func (ctx *Context) GetUserFolders(opts *db.ListOptions) ([]*model.Folder, *db.Page, error) {
+	if ctx.User == nil {
+		return nil, nil, ctx.AuthorizationError()
+	}
+
+	return ctx.Database.GetFoldersByUserID(ctx, ctx.User.ID, opts)
+}
+
+// GetFolderTree returns the folders owned by the currently authenticated User as a
+// nested hierarchy, limited to depth levels below the top level if it is not negative.
+func (ctx *Context) GetFolderTree(depth int) ([]*model.FolderNode, error) {
+	if ctx.User == nil {
+		return nil, ctx.AuthorizationError()
+	}
+
+	return ctx.Database.GetFolderTree(ctx.User.ID, depth)
+}
+
+// GetFolderSubtree returns the folder with the specified ID and the hierarchy of
+// folders nested inside it, if the currently authenticated user can see it.
+func (ctx *Context) GetFolderSubtree(id uint, depth int) (*model.FolderNode, error) {
+	if _, err := ctx.GetFolderByID(id); err != nil {
+		return nil, err
+	}
+
+	return ctx.Database.GetFolderSubtree(id, depth)
+}
+
+// CreateFolder performs the business logic necessary to create and validate
+// a Folder given an initial instance of one. Folders of an organization can be created
+// by its editors, and are owned by the owner of the organization.
+func (ctx *Context) CreateFolder(folder *model.Folder) error {
+	if ctx.User == nil {
+		return ctx.AuthorizationError()
+	}
+
+	folder.OwnerID = ctx.User.ID
+	if folder.OrganizationID != nil {
+		organization, err := ctx.Database.GetOrganizationByID(*folder.OrganizationID)
+		if err != nil {
+			if db.IsNotFound(err) {
+				return &ValidationError{"organization does not exist"}
+			}
+			return err
+		}
+		role, err := ctx.Database.GetMemberRole(organization.ID, ctx.User.ID)
+		if err != nil {
+			return err
+		}
+		if !model.RoleAtLeast(role, model.RoleEditor) {
+			return ctx.AuthorizationError()
+		}
+		folder.OwnerID = organization.OwnerID
+	}
+
+	if err := ctx.validateFolder(folder); err != nil {
+		return err
+	}
+
+	return ctx.Database.CreateFolder(folder)
+}
+
+// UpdateFolder performs the business logic necessary to validate and update
+// a given folder model, including moving it to a different parent.
+func (ctx *Context) UpdateFolder(folder *model.Folder) error {
+	if ctx.User == nil {
+		return ctx.AuthorizationError()
+	}
+
+	if err := ctx.canWrite(folder); err != nil {
+		return err
+	}
+
+	if folder.ID == 0 {
+		return &ValidationError{"cannot update"}
+	}
+
+	if err := ctx.validateFolder(folder); err != nil {
+		return err
+	}
+
+	return ctx.Database.UpdateFolder(folder)
+}
+
+func (ctx *Context) validateFolder(folder *model.Folder) error {
+	if folder.Name == "" {
+		return &ValidationError{"name is required"}
+	}
+
+	if folder.ParentID == nil {
+		return nil
+	}
+	return ctx.validateFolderParent(folder, *folder.ParentID)
+}
+
+// validateFolderParent makes sure the folder with the ID parentID can become the
+// parent of the folder. The parent must exist, be changeable by the currently
+// authenticated user, be owned by the same user or the same organization, and not be
+// the folder itself or any folder below it. Folders the user cannot see are reported
+// as not existing, like missing ones.
+func (ctx *Context) validateFolderParent(folder *model.Folder, parentID uint) error {
+	parent, err := ctx.GetFolderByID(parentID)
+	if err != nil {
+		if _, ok := err.(*UserError); ok {
+			return &ValidationError{"parent folder does not exist"}
+		}
+		return err
+	}
+	if err := ctx.canWrite(parent); err != nil {
+		return err
+	}
+	if derefOrganizationID(parent.OrganizationID) != derefOrganizationID(folder.OrganizationID) {
+		return &ValidationError{"parent folder must belong to the same organization"}
+	}
+	if parent.OwnerID != folder.OwnerID {
+		return &ValidationError{"parent folder must be owned by the same user"}
+	}
+	if folder.ID == 0 {
+		return nil
+	}
+	if parentID == folder.ID {
+		return &ValidationError{"a folder cannot be its own parent"}
+	}
+	descendants, err := ctx.Database.GetFolderDescendantIDs(folder.ID)
+	if err != nil {
+		return err
+	}
+	for _, descendant := range descendants {
+		if descendant == parentID {
+			return &ValidationError{"a folder cannot be moved into one of its own subfolders"}
+		}
+	}
+	return nil
+}
+
+func derefOrganizationID(id *uint) uint {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
+
+// DeleteFolderByID performs the necessary business logic to delete a folder owned
+// by the currently authenticated user. If cascade is true every folder below it is
+// deleted too, otherwise its child folders are moved up to its parent.
+func (ctx *Context) DeleteFolderByID(id uint, cascade bool) error {
+	if ctx.User == nil {
+		return ctx.AuthorizationError()
+	}
+
+	folder, err := ctx.GetFolderByID(id)
+	if err != nil {
+		return err
+	}
+
+	if err := ctx.canManage(folder); err != nil {
+		return err
+	}
+
+	return ctx.Database.DeleteFolder(folder, cascade)
+}
+
+// AddBookmarkToFolder adds the bookmark with the ID bookmarkID to the folder with the
+// ID folderID, if the currently authenticated user can change the folder and manage
+// the bookmark. Folders give the members of their organization and the users they are
+// shared with access to their bookmarks, so only the owner of a bookmark can put it
+// in one.
+func (ctx *Context) AddBookmarkToFolder(folderID uint, bookmarkID uint) error {
+	_, bookmark, err := ctx.getFolderAndBookmark(folderID, bookmarkID)
+	if err != nil {
+		return err
+	}
+	if err := ctx.canManage(bookmark); err != nil {
+		return err
+	}
+
+	return ctx.Database.AddBookmarkToFolder(ctx, bookmarkID, folderID)
+}
+
+// RemoveBookmarkFromFolder removes the bookmark with the ID bookmarkID from the folder
+// with the ID folderID, if the currently authenticated user can change the folder and
+// see the bookmark. The bookmark itself is kept.
+func (ctx *Context) RemoveBookmarkFromFolder(folderID uint, bookmarkID uint) error {
+	if _, _, err := ctx.getFolderAndBookmark(folderID, bookmarkID); err != nil {
+		return err
+	}
+
+	return ctx.Database.RemoveBookmarkFromFolder(bookmarkID, folderID)
+}
+
+func (ctx *Context) getFolderAndBookmark(folderID uint, bookmarkID uint) (*model.Folder, *model.Bookmark, error) {
+	folder, err := ctx.GetFolderByID(folderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := ctx.canWrite(folder); err != nil {
+		return nil, nil, err
+	}
+
+	bookmark, err := ctx.GetBookmarkByID(bookmarkID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return folder, bookmark, nil
+}
@@ -0,0 +1,151 @@
+package app
+
+import (
+	"net/http"
+	"testing"
+
+	"leggett.dev/devmarks/api/model"
+)
+
+func TestAddBookmarkToFolder(t *testing.T) {
+	a := newTestApp(t)
+	alice := signedIn(t, a, "alice@example.com")
+	victor := signedIn(t, a, "victor@example.com")
+
+	shared := createFolder(t, alice, &model.Folder{Name: "Shared"})
+	bookmark := createBookmark(t, alice, "https://golang.org", shared)
+	if _, err := alice.ShareFolder(shared.ID, "victor@example.com", model.AccessRead); err != nil {
+		t.Fatal(err)
+	}
+
+	// the owner of a bookmark can put it in any folder they can change.
+	other := createFolder(t, alice, &model.Folder{Name: "Other"})
+	if err := alice.AddBookmarkToFolder(other.ID, bookmark.ID); err != nil {
+		t.Errorf("owner AddBookmarkToFolder() error = %v", err)
+	}
+
+	// someone who can only see the bookmark cannot gain write access to it by putting
+	// it in a folder of an organization of their own.
+	organization := &model.Organization{Name: "Victor's"}
+	if err := victor.CreateOrganization(organization); err != nil {
+		t.Fatal(err)
+	}
+	folder := createFolder(t, victor, &model.Folder{Name: "Team", OrganizationID: &organization.ID})
+	if err := victor.AddBookmarkToFolder(folder.ID, bookmark.ID); !isStatus(err, http.StatusForbidden) {
+		t.Errorf("viewer AddBookmarkToFolder() error = %v, want a forbidden error", err)
+	}
+	if ok, err := a.Policy.CanWrite(victor.User, bookmark); err != nil || ok {
+		t.Errorf("CanWrite() = %v, %v, want the viewer to still be unable to change the bookmark", ok, err)
+	}
+}
+
+func TestUpdateFolderParentAccess(t *testing.T) {
+	a := newTestApp(t)
+	alice := signedIn(t, a, "alice@example.com")
+	victor := signedIn(t, a, "victor@example.com")
+
+	shared := createFolder(t, alice, &model.Folder{Name: "Shared"})
+	private := createFolder(t, alice, &model.Folder{Name: "Private"})
+	readOnly := createFolder(t, alice, &model.Folder{Name: "Read only"})
+	for folder, access := range map[*model.Folder]string{shared: model.AccessEdit, readOnly: model.AccessRead} {
+		if _, err := alice.ShareFolder(folder.ID, "victor@example.com", access); err != nil {
+			t.Fatal(err)
+		}
+	}
+	own := createFolder(t, victor, &model.Folder{Name: "Victor's"})
+
+	tests := []struct {
+		name     string
+		parentID uint
+		want     func(error) bool
+	}{
+		// folders victor cannot see look the same as folders that do not exist.
+		{"private folder of the owner", private.ID, isParentMissing},
+		{"missing folder", private.ID + 100, isParentMissing},
+		{"folder shared read only", readOnly.ID, func(err error) bool { return isStatus(err, http.StatusForbidden) }},
+		{"folder of someone else", own.ID, isValidationError},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			folder, err := victor.GetFolderByID(shared.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			folder.ParentID = &test.parentID
+			if err := victor.UpdateFolder(folder); !test.want(err) {
+				t.Errorf("UpdateFolder() error = %v", err)
+			}
+			if stored, _ := alice.GetFolderByID(shared.ID); stored.ParentID != nil {
+				t.Errorf("UpdateFolder() moved the folder under %d", *stored.ParentID)
+			}
+		})
+	}
+
+	if err := victor.CreateFolder(&model.Folder{Name: "Sneaky", ParentID: &private.ID}); !isParentMissing(err) {
+		t.Errorf("CreateFolder() error = %v, want the parent to be missing", err)
+	}
+}
+
+func isParentMissing(err error) bool {
+	validationErr, ok := err.(*ValidationError)
+	return ok && validationErr.Message == "parent folder does not exist"
+}
+
+func TestUpdateFolderParentCycles(t *testing.T) {
+	a := newTestApp(t)
+	ctx := signedIn(t, a, "alice@example.com")
+
+	root := createFolder(t, ctx, &model.Folder{Name: "Root"})
+	child := createFolder(t, ctx, &model.Folder{Name: "Child", ParentID: &root.ID})
+	grandchild := createFolder(t, ctx, &model.Folder{Name: "Grandchild", ParentID: &child.ID})
+	other := createFolder(t, ctx, &model.Folder{Name: "Other"})
+
+	tests := []struct {
+		name     string
+		folder   *model.Folder
+		parentID uint
+		message  string
+	}{
+		{"itself", root, root.ID, "a folder cannot be its own parent"},
+		{"its child", root, child.ID, "a folder cannot be moved into one of its own subfolders"},
+		{"its grandchild", root, grandchild.ID, "a folder cannot be moved into one of its own subfolders"},
+		{"a child below its grandchild", child, grandchild.ID, "a folder cannot be moved into one of its own subfolders"},
+		{"another folder", root, other.ID, ""},
+		{"its grandparent", grandchild, other.ID, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			folder, err := ctx.GetFolderByID(test.folder.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			previous := folder.ParentID
+			folder.ParentID = &test.parentID
+			err = ctx.UpdateFolder(folder)
+
+			stored, _ := ctx.GetFolderByID(test.folder.ID)
+			if test.message == "" {
+				if err != nil {
+					t.Fatalf("UpdateFolder() error = %v", err)
+				}
+				if stored.ParentID == nil || *stored.ParentID != test.parentID {
+					t.Errorf("parent = %v, want %d", stored.ParentID, test.parentID)
+				}
+				return
+			}
+			if validationErr, ok := err.(*ValidationError); !ok || validationErr.Message != test.message {
+				t.Errorf("UpdateFolder() error = %v, want %q", err, test.message)
+			}
+			if derefFolderID(stored.ParentID) != derefFolderID(previous) {
+				t.Errorf("UpdateFolder() moved the folder under %d", *stored.ParentID)
+			}
+		})
+	}
+}
+
+func derefFolderID(id *uint) uint {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
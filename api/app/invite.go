@@ -0,0 +1,126 @@
+package app
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"leggett.dev/devmarks/api/db"
+	"leggett.dev/devmarks/api/model"
+)
+
+// maxInviteNoteLength is the longest note an invite can have.
+const maxInviteNoteLength = 200
+
+var (
+	// ErrRegistrationClosed is returned when someone registers while registration is closed.
+	ErrRegistrationClosed = &UserError{Message: "registration is closed", StatusCode: http.StatusForbidden}
+	// ErrInviteRequired is returned when someone registers without an invite code while
+	// registration is invite-only.
+	ErrInviteRequired = &UserError{Message: "an invite code is required to register", StatusCode: http.StatusForbidden}
+	// ErrInvalidInvite is returned when an invite code does not exist, expired or was
+	// used up.
+	ErrInvalidInvite = &UserError{Message: "invalid or expired invite code", StatusCode: http.StatusForbidden}
+)
+
+// IsAdmin returns true if the user administers this server: their email address is
+// one of the Admins setting and they verified it.
+func (a *App) IsAdmin(user *model.User) bool {
+	return isAdmin(a.Config, user)
+}
+
+func isAdmin(config *Config, user *model.User) bool {
+	if user == nil || !user.EmailVerified() {
+		return false
+	}
+	for _, email := range config.Admins {
+		if strings.EqualFold(email, user.Email) {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateInvite creates an invite on behalf of the server itself, for instance from the
+// command line to invite the first users. The returned code is the only time the code
+// itself is available.
+func (a *App) CreateInvite(note string, maxUses int, expiresAt *time.Time) (string, *model.Invite, error) {
+	return createInvite(a.Database, nil, note, maxUses, expiresAt)
+}
+
+// GetInvites returns every invite if the currently authenticated user is an admin.
+func (ctx *Context) GetInvites() ([]*model.Invite, error) {
+	if !isAdmin(ctx.Config, ctx.User) {
+		return nil, ctx.AuthorizationError()
+	}
+
+	return ctx.Database.GetInvites()
+}
+
+// CreateInvite creates an invite letting up to maxUses people register before
+// expiresAt, if the currently authenticated user is an admin. The returned code is
+// the only time the code itself is available.
+func (ctx *Context) CreateInvite(note string, maxUses int, expiresAt *time.Time) (string, *model.Invite, error) {
+	if !isAdmin(ctx.Config, ctx.User) {
+		return "", nil, ctx.AuthorizationError()
+	}
+
+	return createInvite(ctx.Database, &ctx.User.ID, note, maxUses, expiresAt)
+}
+
+func createInvite(database *db.Database, createdByID *uint, note string, maxUses int, expiresAt *time.Time) (string, *model.Invite, error) {
+	note = strings.TrimSpace(note)
+	if len(note) > maxInviteNoteLength {
+		return "", nil, &ValidationError{"note is too long"}
+	}
+	if maxUses == 0 {
+		maxUses = 1
+	}
+	if maxUses < 0 {
+		return "", nil, &ValidationError{"max_uses must be positive"}
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return "", nil, &ValidationError{"expires_at must be in the future"}
+	}
+
+	code, invite := model.NewInvite(createdByID, maxUses, expiresAt)
+	invite.Note = note
+	if err := database.CreateInvite(invite); err != nil {
+		return "", nil, err
+	}
+	return code, invite, nil
+}
+
+// checkRegistration returns an error if a new user cannot register with the given
+// invite code under the Registration setting. The code itself is checked once the user
+// is created, by redeemInvite.
+func (a *App) checkRegistration(inviteCode string) error {
+	switch a.Config.Registration {
+	case RegistrationClosed:
+		return ErrRegistrationClosed
+	case RegistrationInvite:
+		if inviteCode == "" {
+			return ErrInviteRequired
+		}
+	}
+	return nil
+}
+
+// redeemInvite uses the invite with the given code once, returning it.
+func redeemInvite(tx *db.Database, code string) (*model.Invite, error) {
+	invite, err := tx.GetInviteByHash(model.HashToken(code))
+	if err != nil {
+		if db.IsNotFound(err) {
+			return nil, ErrInvalidInvite
+		}
+		return nil, err
+	}
+	ok, err := tx.UseInvite(invite.ID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidInvite
+	}
+	return invite, nil
+}
@@ -0,0 +1,148 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"leggett.dev/devmarks/api/db"
+	"leggett.dev/devmarks/api/mailer"
+	"leggett.dev/devmarks/api/model"
+)
+
+// newInviteApp returns an app where registration is invite-only, with the signed in
+// admin admin@example.com.
+func newInviteApp(t *testing.T) (*App, *Context) {
+	a := newTestApp(t)
+	a.Config = &Config{
+		SecretKey:    []byte("secret"),
+		Registration: RegistrationInvite,
+		Admins:       []string{"Admin@example.com"},
+	}
+	a.Mailer = mailer.NewFile("devmarks@example.com", filepath.Join(t.TempDir(), "mail"))
+	admin := signedIn(t, a, "admin@example.com")
+	now := time.Now()
+	admin.User.EmailVerifiedAt = &now
+	if err := a.Database.UpdateUser(admin.User); err != nil {
+		t.Fatal(err)
+	}
+	return a, admin
+}
+
+// register registers a user with the email address and invite code.
+func register(a *App, email, code string) (*model.User, error) {
+	user := &model.User{Email: email}
+	return user, a.CreateUser(user, "correct horse battery", code)
+}
+
+func TestCreateInvite(t *testing.T) {
+	a, admin := newInviteApp(t)
+	user := signedIn(t, a, "jane@example.com")
+	unverified := signedIn(t, a, "unverified@example.com")
+	a.Config.Admins = append(a.Config.Admins, "unverified@example.com")
+
+	for name, ctx := range map[string]*Context{"user": user, "unverified admin": unverified} {
+		if _, _, err := ctx.CreateInvite("", 1, nil); !isForbidden(err) {
+			t.Errorf("%s creating an invite: err = %v, want forbidden", name, err)
+		}
+		if _, err := ctx.GetInvites(); !isForbidden(err) {
+			t.Errorf("%s listing invites: err = %v, want forbidden", name, err)
+		}
+	}
+
+	past := time.Now().Add(-time.Minute)
+	invalid := []struct {
+		note      string
+		maxUses   int
+		expiresAt *time.Time
+	}{
+		{strings.Repeat("a", maxInviteNoteLength+1), 1, nil},
+		{"", -1, nil},
+		{"", 1, &past},
+	}
+	for _, test := range invalid {
+		if _, _, err := admin.CreateInvite(test.note, test.maxUses, test.expiresAt); !isValidationError(err) {
+			t.Errorf("CreateInvite(%d chars, %d, %v): err = %v, want a validation error", len(test.note), test.maxUses, test.expiresAt, err)
+		}
+	}
+
+	code, invite, err := admin.CreateInvite(" for jane ", 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if invite.Note != "for jane" || invite.MaxUses != 1 || invite.CreatedByID == nil || *invite.CreatedByID != admin.User.ID {
+		t.Errorf("invite = %+v, want a single use invite by the admin", invite)
+	}
+	if invite.Hash == code {
+		t.Error("the invite code is stored in the clear")
+	}
+	invites, err := admin.GetInvites()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(invites) != 1 || invites[0].ID != invite.ID {
+		t.Errorf("invites = %+v, want the invite", invites)
+	}
+}
+
+func TestRegisterWithInvite(t *testing.T) {
+	a, admin := newInviteApp(t)
+
+	code, invite, err := admin.CreateInvite("", 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := register(a, "nocode@example.com", ""); err != ErrInviteRequired {
+		t.Errorf("registering without a code: err = %v, want %v", err, ErrInviteRequired)
+	}
+	if _, err := register(a, "badcode@example.com", code+"x"); err != ErrInvalidInvite {
+		t.Errorf("registering with a wrong code: err = %v, want %v", err, ErrInvalidInvite)
+	}
+
+	for i := 0; i < 2; i++ {
+		user, err := register(a, fmt.Sprintf("user%d@example.com", i), code)
+		if err != nil {
+			t.Fatalf("registration %d: %v", i+1, err)
+		}
+		if user.InviteID == nil || *user.InviteID != invite.ID {
+			t.Errorf("registration %d: invite = %v, want %d", i+1, user.InviteID, invite.ID)
+		}
+	}
+	if _, err := register(a, "third@example.com", code); err != ErrInvalidInvite {
+		t.Errorf("registering with a used up code: err = %v, want %v", err, ErrInvalidInvite)
+	}
+
+	// refused registrations create no user.
+	for _, email := range []string{"nocode@example.com", "badcode@example.com", "third@example.com"} {
+		if _, err := a.Database.GetUserByEmail(email); !db.IsNotFound(err) {
+			t.Errorf("user %s: err = %v, want it not to exist", email, err)
+		}
+	}
+}
+
+func TestRegisterWithExpiredInvite(t *testing.T) {
+	a, admin := newInviteApp(t)
+
+	expiresAt := time.Now().Add(time.Hour)
+	code, invite, err := admin.CreateInvite("", 5, &expiresAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Database.Model(invite).UpdateColumn("expires_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := register(a, "late@example.com", code); err != ErrInvalidInvite {
+		t.Errorf("registering with an expired code: err = %v, want %v", err, ErrInvalidInvite)
+	}
+
+	a.Config.Registration = RegistrationClosed
+	if _, err := register(a, "closed@example.com", code); err != ErrRegistrationClosed {
+		t.Errorf("registering while closed: err = %v, want %v", err, ErrRegistrationClosed)
+	}
+	a.Config.Registration = RegistrationOpen
+	if _, err := register(a, "open@example.com", ""); err != nil {
+		t.Errorf("registering while open: %v", err)
+	}
+}
@@ -0,0 +1,201 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"leggett.dev/devmarks/api/fetcher"
+	"leggett.dev/devmarks/api/model"
+)
+
+// Defaults for checking links when the LinkCheck settings are not set.
+const (
+	defaultLinkCheckEvery     = 10 * time.Minute
+	defaultLinkCheckInterval  = 7 * 24 * time.Hour
+	defaultLinkCheckRetry     = time.Hour
+	defaultLinkCheckBatchSize = 100
+	defaultLinkCheckWorkers   = 4
+)
+
+// maxLinkErrorLength limits the length of the error stored when a check fails.
+const maxLinkErrorLength = 300
+
+// ErrNoRedirect is returned when following the redirect of a bookmark whose link
+// does not redirect anywhere.
+var ErrNoRedirect = &UserError{Message: "bookmark link does not redirect", StatusCode: http.StatusConflict}
+
+// CheckLinks checks the links of the bookmarks that are due, then again every
+// LinkCheckEvery, until ctx is done. Links that work are checked again after
+// LinkCheckInterval; links that fail are retried after LinkCheckRetry, waiting twice
+// as long after each failure in a row up to LinkCheckInterval.
+func (a *App) CheckLinks(ctx context.Context) {
+	if !a.Config.CheckLinks {
+		return
+	}
+	logger := logrus.StandardLogger().WithField("job", "links")
+	ticker := time.NewTicker(a.Config.LinkCheckEvery)
+	defer ticker.Stop()
+	for {
+		if err := a.checkDueLinks(ctx, logger); err != nil {
+			logger.WithError(err).Error("unable to check links")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkDueLinks checks the bookmarks that are due a batch at a time, until there are
+// none left or ctx is done.
+func (a *App) checkDueLinks(ctx context.Context, logger logrus.FieldLogger) error {
+	for ctx.Err() == nil {
+		bookmarks, err := a.Database.GetBookmarksToCheck(time.Now(), a.Config.LinkCheckBatchSize)
+		if err != nil {
+			return err
+		}
+
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		var storeErr error
+		work := make(chan *model.Bookmark)
+		for i := 0; i < a.Config.LinkCheckWorkers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for bookmark := range work {
+					link := a.checkLink(ctx, bookmark)
+					if ctx.Err() != nil {
+						// the check was cut short, so its outcome says nothing about the link.
+						continue
+					}
+					if err := a.Database.SetBookmarkLink(bookmark.ID, bookmark.URL, link); err != nil {
+						mu.Lock()
+						storeErr = err
+						mu.Unlock()
+					}
+					if link.Failures > 0 {
+						logger.WithFields(logrus.Fields{"bookmark": bookmark.ID, "failures": link.Failures, "error": link.Error}).Info("link check failed")
+					}
+				}
+			}()
+		}
+		for _, bookmark := range bookmarks {
+			work <- bookmark
+		}
+		close(work)
+		wg.Wait()
+
+		// bookmarks whose outcome could not be stored would be picked up again at once.
+		if storeErr != nil || len(bookmarks) < a.Config.LinkCheckBatchSize {
+			return storeErr
+		}
+	}
+	return nil
+}
+
+// checkLink checks the link of the bookmark with a HEAD request, falling back to a
+// GET request for the servers that refuse HEAD, and returns its new state. Links
+// that are not web pages, like mailto: links, are left unchecked.
+func (a *App) checkLink(ctx context.Context, bookmark *model.Bookmark) model.BookmarkLink {
+	now := time.Now()
+	next := now.Add(a.Config.LinkCheckInterval)
+	if u, err := url.Parse(bookmark.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return model.BookmarkLink{NextCheckAt: &next}
+	}
+	link := model.BookmarkLink{CheckedAt: &now}
+
+	statusCode, redirectURL, err := a.requestLink(ctx, http.MethodHead, bookmark.URL)
+	if err == nil && statusCode >= 400 {
+		statusCode, redirectURL, err = a.requestLink(ctx, http.MethodGet, bookmark.URL)
+	}
+	link.StatusCode = statusCode
+	switch {
+	case err != nil:
+		link.Error = truncate(err.Error(), maxLinkErrorLength)
+	case statusCode >= 400:
+		link.Error = http.StatusText(statusCode)
+	}
+
+	if link.Error == "" {
+		link.RedirectURL = redirectURL
+	} else {
+		link.Failures = bookmark.Link.Failures + 1
+		retry := a.Config.LinkCheckRetry
+		for i := 1; i < link.Failures && retry < a.Config.LinkCheckInterval; i++ {
+			retry *= 2
+		}
+		if retry < a.Config.LinkCheckInterval {
+			next = now.Add(retry)
+		}
+	}
+	link.NextCheckAt = &next
+	return link
+}
+
+// requestLink requests rawURL with method, following redirects, and returns the status
+// of the final response and, if every redirect on the way was permanent, the URL the
+// redirects ended at.
+func (a *App) requestLink(ctx context.Context, method, rawURL string) (int, string, error) {
+	req, err := http.NewRequest(method, rawURL, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("User-Agent", fetcher.DefaultUserAgent)
+
+	client := *a.HTTPClient
+	permanent := true
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if a.HTTPClient.CheckRedirect != nil {
+			if err := a.HTTPClient.CheckRedirect(req, via); err != nil {
+				return err
+			}
+		}
+		if code := req.Response.StatusCode; code != http.StatusMovedPermanently && code != http.StatusPermanentRedirect {
+			permanent = false
+		}
+		return nil
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	resp.Body.Close()
+
+	var redirectURL string
+	if final := resp.Request.URL.String(); permanent && final != rawURL {
+		redirectURL = final
+	}
+	return resp.StatusCode, redirectURL, nil
+}
+
+// FollowBookmarkRedirect changes the URL of the bookmark with the specified ID to the
+// URL its link permanently redirects to, if the currently authenticated user can edit
+// it. The new URL is checked again, and its metadata fetched, in the background.
+func (ctx *Context) FollowBookmarkRedirect(id uint) (*model.Bookmark, error) {
+	bookmark, err := ctx.GetBookmarkByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.canWrite(bookmark); err != nil {
+		return nil, err
+	}
+	if bookmark.Link.RedirectURL == "" {
+		return nil, ErrNoRedirect
+	}
+
+	bookmark.URL = bookmark.Link.RedirectURL
+	bookmark.Link = model.BookmarkLink{}
+	if err := ctx.Database.UpdateBookmark(bookmark); err != nil {
+		return nil, err
+	}
+	ctx.metadata.enqueue(bookmark)
+	return bookmark, nil
+}
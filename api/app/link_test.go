@@ -0,0 +1,163 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"leggett.dev/devmarks/api/model"
+)
+
+// newLinkApp returns an app checking links weekly, retrying failed ones after an hour,
+// and a server with pages at /ok, /missing and /head-refused, and redirects at
+// /moved (permanent), /found (temporary) and /moved-then-found (both).
+func newLinkApp(t *testing.T) (*App, *httptest.Server) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/missing", http.NotFound)
+	mux.HandleFunc("/head-refused", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.Handle("/moved", http.RedirectHandler("/ok", http.StatusMovedPermanently))
+	mux.Handle("/found", http.RedirectHandler("/ok", http.StatusFound))
+	mux.Handle("/moved-then-found", http.RedirectHandler("/found", http.StatusPermanentRedirect))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	a := newTestApp(t)
+	a.Config = &Config{
+		LinkCheckInterval:  7 * 24 * time.Hour,
+		LinkCheckRetry:     time.Hour,
+		LinkCheckBatchSize: 2,
+		LinkCheckWorkers:   2,
+	}
+	a.HTTPClient = server.Client()
+	return a, server
+}
+
+func TestCheckLink(t *testing.T) {
+	a, server := newLinkApp(t)
+
+	tests := []struct {
+		path        string
+		statusCode  int
+		error       string
+		redirectURL string
+	}{
+		{"/ok", http.StatusOK, "", ""},
+		{"/head-refused", http.StatusOK, "", ""},
+		{"/missing", http.StatusNotFound, "Not Found", ""},
+		{"/moved", http.StatusOK, "", server.URL + "/ok"},
+		{"/found", http.StatusOK, "", ""},
+		{"/moved-then-found", http.StatusOK, "", ""},
+	}
+	for _, test := range tests {
+		before := time.Now()
+		link := a.checkLink(context.Background(), &model.Bookmark{URL: server.URL + test.path})
+		if link.StatusCode != test.statusCode || link.Error != test.error || link.RedirectURL != test.redirectURL {
+			t.Errorf("%s: link = %+v, want status %d, error %q and redirect %q", test.path, link, test.statusCode, test.error, test.redirectURL)
+		}
+		if link.CheckedAt == nil || link.CheckedAt.Before(before) {
+			t.Errorf("%s: checked at %v, want now", test.path, link.CheckedAt)
+		}
+	}
+
+	// links that are not web pages are not checked, only scheduled.
+	link := a.checkLink(context.Background(), &model.Bookmark{URL: "mailto:jane@example.com"})
+	if link.CheckedAt != nil || link.NextCheckAt == nil {
+		t.Errorf("mailto link = %+v, want it unchecked", link)
+	}
+
+	// an unreachable server is a failure without a status.
+	link = a.checkLink(context.Background(), &model.Bookmark{URL: "http://127.0.0.1:1/"})
+	if link.StatusCode != 0 || link.Error == "" || link.Failures != 1 {
+		t.Errorf("unreachable link = %+v, want a failure", link)
+	}
+}
+
+func TestCheckLinkBackoff(t *testing.T) {
+	a, server := newLinkApp(t)
+
+	tests := []struct {
+		previousFailures int
+		retry            time.Duration
+	}{
+		{0, time.Hour},
+		{1, 2 * time.Hour},
+		{3, 8 * time.Hour},
+		// retries never wait longer than working links do.
+		{10, 7 * 24 * time.Hour},
+	}
+	for _, test := range tests {
+		bookmark := &model.Bookmark{URL: server.URL + "/missing", Link: model.BookmarkLink{Failures: test.previousFailures}}
+		link := a.checkLink(context.Background(), bookmark)
+		if link.Failures != test.previousFailures+1 {
+			t.Errorf("after %d failures: failures = %d, want %d", test.previousFailures, link.Failures, test.previousFailures+1)
+		}
+		if retry := link.NextCheckAt.Sub(*link.CheckedAt); retry != test.retry {
+			t.Errorf("after %d failures: retried after %v, want %v", test.previousFailures, retry, test.retry)
+		}
+	}
+
+	// a link that works again is back to the normal interval.
+	link := a.checkLink(context.Background(), &model.Bookmark{URL: server.URL + "/ok", Link: model.BookmarkLink{Failures: 3}})
+	if link.Failures != 0 || link.NextCheckAt.Sub(*link.CheckedAt) != 7*24*time.Hour {
+		t.Errorf("working link after failures = %+v, want no failures and a check in a week", link)
+	}
+}
+
+func TestCheckDueLinks(t *testing.T) {
+	a, server := newLinkApp(t)
+	ctx := signedIn(t, a, "alice@example.com")
+
+	ok := createBookmark(t, ctx, server.URL+"/ok")
+	missing := createBookmark(t, ctx, server.URL+"/missing")
+	moved := createBookmark(t, ctx, server.URL+"/moved")
+
+	// every due bookmark is checked, a batch at a time.
+	if err := a.checkDueLinks(context.Background(), logrus.StandardLogger()); err != nil {
+		t.Fatal(err)
+	}
+	statuses := map[uint]string{ok.ID: model.LinkStatusOK, missing.ID: model.LinkStatusBroken, moved.ID: model.LinkStatusRedirected}
+	for id, status := range statuses {
+		bookmark, err := ctx.GetBookmarkByID(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := bookmark.Link.Status(); got != status {
+			t.Errorf("bookmark %s: link status = %q, want %q", bookmark.URL, got, status)
+		}
+	}
+	due, err := a.Database.GetBookmarksToCheck(time.Now(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(due) != 0 {
+		t.Errorf("%d bookmarks still due after checking them", len(due))
+	}
+
+	// the redirect can be followed, which changes the URL and checks it again.
+	if _, err := ctx.FollowBookmarkRedirect(ok.ID); err != ErrNoRedirect {
+		t.Errorf("following a link without a redirect: err = %v, want %v", err, ErrNoRedirect)
+	}
+	followed, err := ctx.FollowBookmarkRedirect(moved.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if followed.URL != server.URL+"/ok" || followed.Link.Status() != model.LinkStatusUnchecked {
+		t.Errorf("followed bookmark = %s with link %+v, want the redirect's URL, unchecked", followed.URL, followed.Link)
+	}
+	due, err = a.Database.GetBookmarksToCheck(time.Now(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(due) != 1 || due[0].ID != moved.ID {
+		t.Errorf("due bookmarks = %d, want only the followed one", len(due))
+	}
+}
@@ -0,0 +1,132 @@
+package app
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"leggett.dev/devmarks/api/db"
+	"leggett.dev/devmarks/api/model"
+)
+
+// ErrInvalidCredentials is returned for every failed login, whether or not the account
+// exists, so that logins cannot be used to find out which email addresses have one.
+var ErrInvalidCredentials = &UserError{Message: "invalid credentials", StatusCode: http.StatusUnauthorized}
+
+// RateLimitError is returned when too many logins failed, from an IP address or for an
+// account, and the client has to wait RetryAfter before trying again.
+type RateLimitError struct {
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return e.Message
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// compareDummyPassword spends as long as checking a password, so that logins for email
+// addresses without an account take as long as those with one.
+func compareDummyPassword(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = model.GeneratePasswordHash([]byte("devmarks"))
+	})
+	model.ComparePasswordHash(dummyHash, []byte(password))
+}
+
+// Login checks the email address and password of a user signing in from the IP address
+// and returns the user. Every attempt is recorded; once too many fail, further attempts
+// from the IP address or for the account are refused with a *RateLimitError.
+func (a *App) Login(email, password, ip string) (*model.User, error) {
+	if err := a.checkLoginRate(email, ip); err != nil {
+		return nil, err
+	}
+
+	user, err := a.Database.GetUserByEmail(email)
+	if err != nil {
+		if !db.IsNotFound(err) {
+			return nil, err
+		}
+		compareDummyPassword(password)
+		return nil, a.failLogin(email, nil, ip, model.LoginFailedUnknownUser)
+	}
+	if !user.CheckPassword(password) {
+		return nil, a.failLogin(email, user, ip, model.LoginFailedPassword)
+	}
+
+	// with two-factor authentication the login is only complete once the code is given.
+	if !user.TOTPEnabled() {
+		if err := a.recordLogin(email, user, ip, true, ""); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// checkLoginRate returns a *RateLimitError if logins from the IP address or for the
+// email address have to wait.
+func (a *App) checkLoginRate(email, ip string) error {
+	now := time.Now()
+	since := now.Add(-a.Config.LoginWindow)
+
+	failures, err := a.Database.CountFailedLoginsByIP(ip, since)
+	if err != nil {
+		return err
+	}
+	if failures >= a.Config.LoginMaxAttemptsPerIP {
+		return &RateLimitError{Message: "too many failed logins, try again later", RetryAfter: a.Config.LoginWindow}
+	}
+
+	failures, latest, err := a.Database.GetFailedLoginsByEmail(email, since)
+	if err != nil {
+		return err
+	}
+	if failures < a.Config.LoginMaxAttempts {
+		return nil
+	}
+	if lockedUntil := latest.Add(a.lockoutFor(failures)); now.Before(lockedUntil) {
+		return &RateLimitError{Message: "account temporarily locked, try again later", RetryAfter: lockedUntil.Sub(now)}
+	}
+	return nil
+}
+
+// lockoutFor returns how long an account is locked after the number of failed logins
+// in a row: LoginLockout, doubled for each failure past LoginMaxAttempts, up to
+// LoginWindow.
+func (a *App) lockoutFor(failures int) time.Duration {
+	lockout := a.Config.LoginLockout
+	for i := a.Config.LoginMaxAttempts; i < failures && lockout < a.Config.LoginWindow; i++ {
+		lockout *= 2
+	}
+	if lockout > a.Config.LoginWindow {
+		lockout = a.Config.LoginWindow
+	}
+	return lockout
+}
+
+// failLogin records a failed login and returns ErrInvalidCredentials, or the error
+// recording it.
+func (a *App) failLogin(email string, user *model.User, ip, reason string) error {
+	if err := a.recordLogin(email, user, ip, false, reason); err != nil {
+		return err
+	}
+	return ErrInvalidCredentials
+}
+
+// recordLogin stores a login attempt, and logs it if it failed.
+func (a *App) recordLogin(email string, user *model.User, ip string, success bool, reason string) error {
+	attempt := &model.LoginAttempt{Email: email, IPAddress: ip, Success: success, Reason: reason}
+	if user != nil {
+		attempt.UserID = &user.ID
+	}
+	if !success {
+		logrus.WithFields(logrus.Fields{"email": email, "ip": ip, "reason": reason}).Warn("failed login")
+	}
+	return a.Database.CreateLoginAttempt(attempt)
+}
@@ -0,0 +1,140 @@
+package app
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"leggett.dev/devmarks/api/model"
+)
+
+// newLoginApp returns an app locking accounts after three failed logins and IP
+// addresses after ten, with the user jane@example.com whose password is
+// "correct horse battery".
+func newLoginApp(t *testing.T) *App {
+	a := newTestApp(t)
+	a.Config = &Config{
+		LoginWindow:           15 * time.Minute,
+		LoginMaxAttempts:      3,
+		LoginMaxAttemptsPerIP: 10,
+		LoginLockout:          time.Minute,
+	}
+	user := &model.User{Email: "jane@example.com"}
+	if err := user.SetPassword("correct horse battery"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Database.CreateUser(user); err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+// ageLoginAttempts moves every recorded login attempt back by d.
+func ageLoginAttempts(t *testing.T, a *App, d time.Duration) {
+	t.Helper()
+	var attempts []*model.LoginAttempt
+	if err := a.Database.Find(&attempts).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, attempt := range attempts {
+		if err := a.Database.Model(attempt).UpdateColumn("created_at", attempt.CreatedAt.Add(-d)).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// retryAfter returns how long the error says to wait, or 0 if it is not a
+// *RateLimitError.
+func retryAfter(err error) time.Duration {
+	if rateErr, ok := err.(*RateLimitError); ok {
+		return rateErr.RetryAfter
+	}
+	return 0
+}
+
+func TestLoginLocksOutAccount(t *testing.T) {
+	a := newLoginApp(t)
+
+	for i := 0; i < 3; i++ {
+		if _, err := a.Login("jane@example.com", "wrong", "192.0.2.1"); err != ErrInvalidCredentials {
+			t.Fatalf("failed login %d: err = %v, want %v", i+1, err, ErrInvalidCredentials)
+		}
+	}
+	// once locked, even the right password is refused, from any IP address.
+	_, err := a.Login("jane@example.com", "correct horse battery", "192.0.2.2")
+	if wait := retryAfter(err); wait <= 0 || wait > time.Minute {
+		t.Fatalf("login while locked: err = %v, want to wait up to a minute", err)
+	}
+
+	// each further failure doubles the lockout.
+	ageLoginAttempts(t, a, time.Minute)
+	if _, err := a.Login("jane@example.com", "wrong", "192.0.2.1"); err != ErrInvalidCredentials {
+		t.Fatalf("failed login after the lockout: err = %v, want %v", err, ErrInvalidCredentials)
+	}
+	_, err = a.Login("jane@example.com", "correct horse battery", "192.0.2.1")
+	if wait := retryAfter(err); wait <= time.Minute || wait > 2*time.Minute {
+		t.Fatalf("login after four failures: err = %v, want to wait up to two minutes", err)
+	}
+
+	// a successful login resets the count.
+	ageLoginAttempts(t, a, 2*time.Minute)
+	if _, err := a.Login("jane@example.com", "correct horse battery", "192.0.2.1"); err != nil {
+		t.Fatalf("login after the lockout: %v", err)
+	}
+	if _, err := a.Login("jane@example.com", "wrong", "192.0.2.1"); err != ErrInvalidCredentials {
+		t.Errorf("failed login after a successful one: err = %v, want %v", err, ErrInvalidCredentials)
+	}
+	if _, err := a.Login("jane@example.com", "correct horse battery", "192.0.2.1"); err != nil {
+		t.Errorf("login after a single failure: %v", err)
+	}
+}
+
+func TestLoginLimitsIPAddress(t *testing.T) {
+	a := newLoginApp(t)
+
+	// failures for every email address, known or not, count against the IP address.
+	for i := 0; i < 10; i++ {
+		if _, err := a.Login(fmt.Sprintf("nobody%d@example.com", i), "wrong", "192.0.2.1"); err != ErrInvalidCredentials {
+			t.Fatalf("failed login %d: err = %v, want %v", i+1, err, ErrInvalidCredentials)
+		}
+	}
+	if _, err := a.Login("jane@example.com", "correct horse battery", "192.0.2.1"); retryAfter(err) != 15*time.Minute {
+		t.Errorf("login from a limited IP address: err = %v, want to wait the login window", err)
+	}
+	if _, err := a.Login("jane@example.com", "correct horse battery", "192.0.2.2"); err != nil {
+		t.Errorf("login from another IP address: %v", err)
+	}
+
+	// failures older than the window no longer count.
+	ageLoginAttempts(t, a, 15*time.Minute)
+	if _, err := a.Login("jane@example.com", "correct horse battery", "192.0.2.1"); err != nil {
+		t.Errorf("login once the window passed: %v", err)
+	}
+
+	var attempts []*model.LoginAttempt
+	if err := a.Database.Order("id").Find(&attempts).Error; err != nil {
+		t.Fatal(err)
+	}
+	if first := attempts[0]; first.UserID != nil || first.Reason != model.LoginFailedUnknownUser || first.Success {
+		t.Errorf("attempt for an unknown user = %+v, want a failure without a user", first)
+	}
+}
+
+func TestLoginReturnsDatabaseErrors(t *testing.T) {
+	a := newLoginApp(t)
+	if err := a.Database.DropTable(&model.User{}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := a.Login("jane@example.com", "correct horse battery", "192.0.2.1")
+	if err == nil || err == ErrInvalidCredentials {
+		t.Errorf("err = %v, want the database error", err)
+	}
+	var failures int
+	if err := a.Database.Model(&model.LoginAttempt{}).Count(&failures).Error; err != nil {
+		t.Fatal(err)
+	}
+	if failures != 0 {
+		t.Errorf("recorded %d login attempts, want none", failures)
+	}
+}
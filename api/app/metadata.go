@@ -0,0 +1,129 @@
+package app
+
+import (
+	"context"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+
+	"leggett.dev/devmarks/api/db"
+	"leggett.dev/devmarks/api/fetcher"
+	"leggett.dev/devmarks/api/model"
+)
+
+// metadataQueueSize is how many bookmarks can wait for their metadata to be fetched.
+// Bookmarks created while the queue is full are left without metadata.
+const metadataQueueSize = 256
+
+// maxMetadataErrorLength limits the length of the error stored when fetching fails.
+const maxMetadataErrorLength = 300
+
+type metadataJob struct {
+	bookmarkID uint
+	url        string
+}
+
+// metadataQueue fetches the metadata of newly created bookmarks in the background,
+// with a fixed number of workers, and stores it on the bookmarks.
+type metadataQueue struct {
+	fetcher  *fetcher.Fetcher
+	database *db.Database
+	logger   logrus.FieldLogger
+
+	mu     sync.RWMutex
+	closed bool
+	jobs   chan metadataJob
+	wg     sync.WaitGroup
+}
+
+func newMetadataQueue(f *fetcher.Fetcher, database *db.Database, workers int) *metadataQueue {
+	q := &metadataQueue{
+		fetcher:  f,
+		database: database,
+		logger:   logrus.StandardLogger().WithField("job", "metadata"),
+		jobs:     make(chan metadataJob, metadataQueueSize),
+	}
+	q.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go q.work()
+	}
+	return q
+}
+
+// enqueue schedules fetching the metadata of the bookmark. It never blocks, and does
+// nothing on a nil queue, which is what the app has when fetching is disabled.
+func (q *metadataQueue) enqueue(bookmark *model.Bookmark) {
+	if q == nil {
+		return
+	}
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return
+	}
+	select {
+	case q.jobs <- metadataJob{bookmarkID: bookmark.ID, url: bookmark.URL}:
+	default:
+		q.logger.WithField("bookmark", bookmark.ID).Warn("metadata queue full, skipping bookmark")
+	}
+}
+
+// close stops accepting bookmarks and waits for the ones queued to be fetched.
+func (q *metadataQueue) close() {
+	if q == nil {
+		return
+	}
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.jobs)
+	}
+	q.mu.Unlock()
+	q.wg.Wait()
+}
+
+func (q *metadataQueue) work() {
+	defer q.wg.Done()
+	for job := range q.jobs {
+		q.fetch(job)
+	}
+}
+
+// fetch fetches the metadata of the bookmark of the job and stores it, or why it could
+// not be fetched. Bookmarks created without a name are named after the page title.
+func (q *metadataQueue) fetch(job metadataJob) {
+	logger := q.logger.WithField("bookmark", job.bookmarkID)
+	now := time.Now()
+	metadata := model.BookmarkMetadata{FetchedAt: &now}
+	var name string
+
+	fetched, err := q.fetcher.Fetch(context.Background(), job.url)
+	if err != nil {
+		logger.WithError(err).Info("unable to fetch bookmark metadata")
+		metadata.Error = truncate(err.Error(), maxMetadataErrorLength)
+	} else {
+		metadata.Title = fetched.Title
+		metadata.Description = fetched.Description
+		metadata.FaviconURL = fetched.FaviconURL
+		metadata.CanonicalURL = fetched.CanonicalURL
+		metadata.ImageURL = fetched.ImageURL
+		name = truncate(fetched.Title, maxBookmarkNameLength)
+	}
+
+	if err := q.database.SetBookmarkMetadata(job.bookmarkID, job.url, metadata, name); err != nil {
+		logger.WithError(err).Error("unable to store bookmark metadata")
+	}
+}
+
+// truncate shortens s to at most max bytes, without splitting a character.
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max]
+}
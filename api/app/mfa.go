@@ -0,0 +1,215 @@
+package app
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/pkg/errors"
+
+	"leggett.dev/devmarks/api/model"
+	"leggett.dev/devmarks/api/totp"
+)
+
+const (
+	// totpIssuer is the name authenticator apps show next to the code.
+	totpIssuer = "devmarks"
+	// recoveryCodeCount is the number of recovery codes handed out when two-factor
+	// authentication is enabled.
+	recoveryCodeCount = 10
+	// mfaChallengeAudience marks the tokens of an MFA challenge, so they cannot be
+	// mistaken for anything else signed with the secret key.
+	mfaChallengeAudience = "mfa"
+	// mfaChallengeLifetime is how long a user has to give their code after their password.
+	mfaChallengeLifetime = 5 * time.Minute
+)
+
+var (
+	// ErrInvalidMFACode is returned when a one-time or recovery code is wrong.
+	ErrInvalidMFACode = &UserError{Message: "invalid code", StatusCode: http.StatusUnauthorized}
+	// ErrInvalidMFAChallenge is returned when an MFA challenge is invalid or expired.
+	ErrInvalidMFAChallenge = &UserError{Message: "invalid or expired mfa_token, sign in again", StatusCode: http.StatusUnauthorized}
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TOTPEnrollment holds what an authenticator app needs to be set up for a user.
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// EnrollTOTP generates a new authenticator app secret for the user. Two-factor
+// authentication is only enabled once a code from the app is confirmed with ConfirmTOTP.
+func (a *App) EnrollTOTP(user *model.User) (*TOTPEnrollment, error) {
+	if user.TOTPEnabled() {
+		return nil, &UserError{Message: "two-factor authentication is already enabled", StatusCode: http.StatusConflict}
+	}
+
+	secret := totp.GenerateSecret()
+	encrypted, err := a.encrypt(secret)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to encrypt secret")
+	}
+	user.TOTPSecret = encrypted
+	user.TOTPLastStep = 0
+	if err := a.Database.UpdateUser(user); err != nil {
+		return nil, err
+	}
+	return &TOTPEnrollment{Secret: secret, URI: totp.URI(totpIssuer, user.Email, secret)}, nil
+}
+
+// ConfirmTOTP enables two-factor authentication for the user once they have given a
+// code from their newly set up authenticator app, and returns their recovery codes.
+// The recovery codes are only ever available here.
+func (a *App) ConfirmTOTP(user *model.User, code string) ([]string, error) {
+	if user.TOTPEnabled() {
+		return nil, &UserError{Message: "two-factor authentication is already enabled", StatusCode: http.StatusConflict}
+	}
+	if user.TOTPSecret == "" {
+		return nil, &UserError{Message: "two-factor authentication enrollment has not been started", StatusCode: http.StatusConflict}
+	}
+
+	secret, err := a.decrypt(user.TOTPSecret)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to decrypt secret")
+	}
+	step, ok := totp.Validate(secret, code, time.Now(), user.TOTPLastStep)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		codes[i] = newRecoveryCode()
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+
+	now := time.Now()
+	user.TOTPEnabledAt = &now
+	user.TOTPLastStep = step
+	user.RecoveryCodes = hashes
+	if err := a.Database.UpdateUser(user); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTOTP turns two-factor authentication off for the user, who has to give a
+// current one-time or recovery code.
+func (a *App) DisableTOTP(user *model.User, code string) error {
+	if !user.TOTPEnabled() {
+		return &UserError{Message: "two-factor authentication is not enabled", StatusCode: http.StatusConflict}
+	}
+	if err := a.verifyMFACode(user, code); err != nil {
+		return err
+	}
+
+	user.TOTPSecret = ""
+	user.TOTPEnabledAt = nil
+	user.TOTPLastStep = 0
+	user.RecoveryCodes = nil
+	return a.Database.UpdateUser(user)
+}
+
+// CreateMFAChallenge returns the token a user who gave the right password, but has
+// two-factor authentication enabled, exchanges along with their code to sign in.
+func (a *App) CreateMFAChallenge(user *model.User) (string, error) {
+	now := time.Now()
+	claims := jwt.StandardClaims{
+		Subject:   strconv.Itoa(int(user.ID)),
+		Issuer:    accessTokenIssuer,
+		Audience:  mfaChallengeAudience,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(mfaChallengeLifetime).Unix(),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(a.Config.SecretKey)
+}
+
+// CompleteMFAChallenge checks the code given for an MFA challenge from the IP address
+// and returns the user signing in. Wrong codes count as failed logins.
+func (a *App) CompleteMFAChallenge(challenge, code, ip string) (*model.User, error) {
+	claims := &jwt.StandardClaims{}
+	_, err := jwt.ParseWithClaims(challenge, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return a.Config.SecretKey, nil
+	})
+	if err != nil || claims.Audience != mfaChallengeAudience || claims.Issuer != accessTokenIssuer {
+		return nil, ErrInvalidMFAChallenge
+	}
+	id, err := strconv.ParseUint(claims.Subject, 10, 0)
+	if err != nil {
+		return nil, ErrInvalidMFAChallenge
+	}
+	user, err := a.Database.GetUserById(uint(id))
+	if err != nil {
+		return nil, ErrInvalidMFAChallenge
+	}
+
+	if err := a.checkLoginRate(user.Email, ip); err != nil {
+		return nil, err
+	}
+	if err := a.verifyMFACode(user, code); err != nil {
+		if err == ErrInvalidMFACode {
+			if err := a.recordLogin(user.Email, user, ip, false, model.LoginFailedMFA); err != nil {
+				return nil, err
+			}
+		}
+		return nil, err
+	}
+	if err := a.recordLogin(user.Email, user, ip, true, ""); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// verifyMFACode checks a one-time code, or failing that a recovery code, for the user.
+// Recovery codes can only be used once.
+func (a *App) verifyMFACode(user *model.User, code string) error {
+	if !user.TOTPEnabled() {
+		return nil
+	}
+
+	secret, err := a.decrypt(user.TOTPSecret)
+	if err != nil {
+		return errors.Wrap(err, "unable to decrypt secret")
+	}
+	if step, ok := totp.Validate(secret, code, time.Now(), user.TOTPLastStep); ok {
+		user.TOTPLastStep = step
+		return a.Database.UpdateUser(user)
+	}
+
+	hash := hashRecoveryCode(code)
+	for i, candidate := range user.RecoveryCodes {
+		if candidate == hash {
+			user.RecoveryCodes = append(user.RecoveryCodes[:i:i], user.RecoveryCodes[i+1:]...)
+			return a.Database.UpdateUser(user)
+		}
+	}
+	return ErrInvalidMFACode
+}
+
+// newRecoveryCode returns a random recovery code, formatted as two groups of five
+// characters to be easy to copy down.
+func newRecoveryCode() string {
+	raw := make([]byte, 7)
+	if _, err := rand.Read(raw); err != nil {
+		panic(err)
+	}
+	code := strings.ToLower(recoveryCodeEncoding.EncodeToString(raw))[:10]
+	return code[:5] + "-" + code[5:]
+}
+
+// hashRecoveryCode returns the hash a recovery code is stored under, ignoring case,
+// spaces and dashes.
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return model.HashToken(normalized)
+}
@@ -0,0 +1,48 @@
+package app
+
+import (
+	"leggett.dev/devmarks/api/db"
+	"leggett.dev/devmarks/api/netscape"
+)
+
+// ImportNetscape creates the folders and bookmarks of a parsed Netscape bookmark
+// file for the currently authenticated user. Tags are normalized the way tags given
+// to the API are.
+func (ctx *Context) ImportNetscape(root *netscape.Folder) (*db.ImportResult, error) {
+	if ctx.User == nil {
+		return nil, ctx.AuthorizationError()
+	}
+	if err := normalizeNetscapeTags(root); err != nil {
+		return nil, err
+	}
+
+	return ctx.Database.ImportNetscape(ctx.User.ID, root)
+}
+
+// normalizeNetscapeTags normalizes the tags of the bookmarks in the folder and all of
+// its subfolders.
+func normalizeNetscapeTags(folder *netscape.Folder) error {
+	for _, bookmark := range folder.Bookmarks {
+		tags, err := normalizeTagNames(bookmark.Tags)
+		if err != nil {
+			return err
+		}
+		bookmark.Tags = tags
+	}
+	for _, child := range folder.Folders {
+		if err := normalizeNetscapeTags(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportNetscape builds the Netscape bookmark file tree of everything owned by the
+// currently authenticated user
+func (ctx *Context) ExportNetscape() (*netscape.Folder, error) {
+	if ctx.User == nil {
+		return nil, ctx.AuthorizationError()
+	}
+
+	return ctx.Database.ExportNetscape(ctx.User.ID)
+}
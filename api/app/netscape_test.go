@@ -0,0 +1,81 @@
+package app
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"leggett.dev/devmarks/api/model"
+	"leggett.dev/devmarks/api/netscape"
+)
+
+// newImportContext returns a context signed in as a user with nothing yet, for
+// importing into.
+func newImportContext(t *testing.T) *Context {
+	return signedIn(t, newTestApp(t), "jane@example.com")
+}
+
+// tagNames returns the sorted names of the tags of the user of the context.
+func tagNames(t *testing.T, ctx *Context) []string {
+	t.Helper()
+	var tags []model.Tag
+	if err := ctx.Database.Find(&tags, model.Tag{OwnerID: ctx.User.ID}).Error; err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestImportNetscapeNormalizesTags(t *testing.T) {
+	ctx := newImportContext(t)
+	root := &netscape.Folder{
+		Bookmarks: []*netscape.Bookmark{{Name: "Go", URL: "https://golang.org", Tags: []string{" go ", "go", "web"}}},
+		Folders: []*netscape.Folder{{
+			Name:      "Docs",
+			Bookmarks: []*netscape.Bookmark{{Name: "MDN", URL: "https://developer.mozilla.org", Tags: []string{"web\t", "docs"}}},
+		}},
+	}
+
+	result, err := ctx.ImportNetscape(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Bookmarks != 2 || result.Folders != 1 {
+		t.Errorf("ImportNetscape() = %+v, want 2 bookmarks and 1 folder", result)
+	}
+	if got, want := tagNames(t, ctx), []string{"docs", "go", "web"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tags = %q, want %q", got, want)
+	}
+}
+
+func TestImportNetscapeRejectsInvalidTags(t *testing.T) {
+	ctx := newImportContext(t)
+	root := &netscape.Folder{
+		Bookmarks: []*netscape.Bookmark{{Name: "Go", URL: "https://golang.org", Tags: []string{"go"}}},
+		Folders: []*netscape.Folder{{
+			Name:      "Docs",
+			Bookmarks: []*netscape.Bookmark{{Name: "MDN", URL: "https://developer.mozilla.org", Tags: []string{strings.Repeat("a", maxTagNameLength+1)}}},
+		}},
+	}
+
+	if _, err := ctx.ImportNetscape(root); !isValidationError(err) {
+		t.Fatalf("ImportNetscape() error = %v, want a validation error", err)
+	}
+	var count int
+	if err := ctx.Database.Model(&model.Bookmark{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 || len(tagNames(t, ctx)) != 0 {
+		t.Errorf("ImportNetscape() created %d bookmarks and tags %q, want nothing", count, tagNames(t, ctx))
+	}
+}
+
+func isValidationError(err error) bool {
+	_, ok := err.(*ValidationError)
+	return ok
+}
@@ -0,0 +1,70 @@
+package app
+
+import (
+	"net/http"
+	"time"
+
+	"leggett.dev/devmarks/api/db"
+	"leggett.dev/devmarks/api/model"
+	"leggett.dev/devmarks/api/oidc"
+)
+
+// ErrEmailNotVerified is returned when a user signs in with a provider account whose
+// email address the provider has not verified, so it cannot be trusted to pick a user.
+var ErrEmailNotVerified = &UserError{Message: "the identity provider has not verified your email address", StatusCode: http.StatusForbidden}
+
+// ErrAccountNotVerified is returned when a user signs in with a provider account for
+// the first time and the user with the same email address has not verified it. Anyone
+// can register with, or change to, an email address they do not own, so the provider
+// account is only linked to users who proved they own it.
+var ErrAccountNotVerified = &UserError{Message: "an account with your email address exists but has not verified it; sign in with its password and verify your email address first", StatusCode: http.StatusConflict}
+
+// SignInWithOIDC returns the user signing in with the verified claims of the single
+// sign-on provider. The provider account is linked to the user with the same email
+// address the first time, if that user verified it, and a user is created for it if
+// there is none and registration is open.
+func (a *App) SignInWithOIDC(claims *oidc.Claims) (*model.User, error) {
+	var user *model.User
+	err := a.Database.WithTransaction(func(tx *db.Database) error {
+		identity, err := tx.GetIdentity(a.OIDC.Issuer(), claims.Subject)
+		if err == nil && identity.User != nil {
+			user = identity.User
+			return nil
+		}
+		if err != nil && !db.IsNotFound(err) {
+			return err
+		}
+
+		if !claims.EmailVerified || claims.Email == "" {
+			return ErrEmailNotVerified
+		}
+		user, err = tx.GetUserByEmail(claims.Email)
+		if err != nil {
+			if !db.IsNotFound(err) {
+				return err
+			}
+			if err := a.checkRegistration(""); err != nil {
+				return err
+			}
+			// users created here have no password, so they can only sign in through the
+			// provider until they reset it; the hash is empty rather than null to satisfy
+			// the users table. The provider already verified their email address.
+			now := time.Now()
+			user = &model.User{Email: claims.Email, HashedPassword: []byte{}, EmailVerifiedAt: &now}
+			if err := validateEmail(user.Email); err != nil {
+				return err
+			}
+			if err := tx.CreateUser(user); err != nil {
+				return err
+			}
+		} else if !user.EmailVerified() {
+			return ErrAccountNotVerified
+		}
+
+		return tx.CreateIdentity(&model.Identity{UserID: user.ID, Issuer: a.OIDC.Issuer(), Subject: claims.Subject})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
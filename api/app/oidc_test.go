@@ -0,0 +1,138 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+
+	"leggett.dev/devmarks/api/model"
+	"leggett.dev/devmarks/api/oidc"
+	"leggett.dev/devmarks/api/oidc/oidctest"
+)
+
+// newOIDCApp returns an app signing users in through a mock provider.
+func newOIDCApp(t *testing.T) (*App, *oidctest.Provider) {
+	mock := oidctest.NewProvider("devmarks", "secret")
+	t.Cleanup(mock.Close)
+	config := &Config{
+		SecretKey:     []byte("secret"),
+		TokenLifetime: time.Hour,
+		AuthStrategy:  AuthStrategyToken,
+		Registration:  RegistrationOpen,
+		OIDC: &oidc.Config{
+			Issuer:       mock.Issuer(),
+			ClientID:     mock.ClientID,
+			ClientSecret: mock.ClientSecret,
+			RedirectURL:  "https://api.local/auth/oidc/callback",
+		},
+	}
+	a := newTestApp(t)
+	a.Config = config
+	a.OIDC = oidc.NewProvider(*config.OIDC, mock.Client())
+	return a, mock
+}
+
+// signIn signs in through the mock provider as the account with the given claims.
+func signIn(t *testing.T, a *App, mock *oidctest.Provider, claims jwt.MapClaims) (*model.User, error) {
+	t.Helper()
+	claims["nonce"] = "nonce"
+	verified, err := a.OIDC.Exchange(context.Background(), mock.Code(claims), "nonce")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a.SignInWithOIDC(verified)
+}
+
+func TestSignInWithOIDCProvisionsUser(t *testing.T) {
+	a, mock := newOIDCApp(t)
+
+	user, err := signIn(t, a, mock, jwt.MapClaims{"sub": "1", "email": "jane@example.com", "email_verified": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID == 0 || user.Email != "jane@example.com" || !user.EmailVerified() {
+		t.Errorf("provisioned user = %+v, want a verified user for jane@example.com", user)
+	}
+
+	// the provider account is linked, so it keeps signing in as the same user even
+	// once the provider reports another email address.
+	again, err := signIn(t, a, mock, jwt.MapClaims{"sub": "1", "email": "jane@corp.example.com", "email_verified": false})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != user.ID {
+		t.Errorf("second sign in as user %d, want %d", again.ID, user.ID)
+	}
+}
+
+func TestSignInWithOIDCLinksVerifiedUser(t *testing.T) {
+	a, mock := newOIDCApp(t)
+	now := time.Now()
+	existing := &model.User{Email: "jane@example.com", HashedPassword: []byte("hash"), EmailVerifiedAt: &now}
+	if err := a.Database.CreateUser(existing); err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := signIn(t, a, mock, jwt.MapClaims{"sub": "1", "email": "jane@example.com", "email_verified": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != existing.ID {
+		t.Errorf("signed in as user %d, want the existing user %d", user.ID, existing.ID)
+	}
+	identity, err := a.Database.GetIdentity(mock.Issuer(), "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.UserID != existing.ID {
+		t.Errorf("identity linked to user %d, want %d", identity.UserID, existing.ID)
+	}
+}
+
+func TestSignInWithOIDCRejectsUnverifiedUser(t *testing.T) {
+	a, mock := newOIDCApp(t)
+	// someone registered with the address without owning it.
+	existing := &model.User{Email: "jane@example.com", HashedPassword: []byte("hash")}
+	if err := a.Database.CreateUser(existing); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := signIn(t, a, mock, jwt.MapClaims{"sub": "1", "email": "jane@example.com", "email_verified": true})
+	if err != ErrAccountNotVerified {
+		t.Fatalf("error = %v, want ErrAccountNotVerified", err)
+	}
+	if _, err := a.Database.GetIdentity(mock.Issuer(), "1"); err == nil {
+		t.Error("the provider account was linked to the unverified user")
+	}
+}
+
+func TestSignInWithOIDCRejectsUnverifiedEmail(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+	}{
+		{name: "unverified", claims: jwt.MapClaims{"sub": "1", "email": "jane@example.com", "email_verified": false}},
+		{name: "verification unknown", claims: jwt.MapClaims{"sub": "1", "email": "jane@example.com"}},
+		{name: "no email", claims: jwt.MapClaims{"sub": "1", "email_verified": true}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a, mock := newOIDCApp(t)
+			if _, err := signIn(t, a, mock, test.claims); err != ErrEmailNotVerified {
+				t.Errorf("error = %v, want ErrEmailNotVerified", err)
+			}
+		})
+	}
+}
+
+func TestSignInWithOIDCRespectsRegistration(t *testing.T) {
+	a, mock := newOIDCApp(t)
+	a.Config.Registration = RegistrationClosed
+
+	_, err := signIn(t, a, mock, jwt.MapClaims{"sub": "1", "email": "jane@example.com", "email_verified": true})
+	if err != ErrRegistrationClosed {
+		t.Errorf("error = %v, want ErrRegistrationClosed", err)
+	}
+}
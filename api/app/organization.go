@@ -0,0 +1,293 @@
+package app
+
+import (
+	"net/http"
+	"strings"
+
+	"leggett.dev/devmarks/api/db"
+	"leggett.dev/devmarks/api/model"
+	"leggett.dev/devmarks/api/policy"
+)
+
+const maxOrganizationNameLength = 100
+
+// organizationGranter grants the members of organizations access to the organization,
+// its folders and the bookmarks in them according to their role.
+type organizationGranter struct {
+	database *db.Database
+}
+
+// Grant implements policy.Granter. Viewers can see everything; editors can also change
+// the folders and bookmarks; admins can also change the organization and its members,
+// and delete its folders.
+func (g *organizationGranter) Grant(user *model.User, resource interface{}) (policy.Level, error) {
+	switch r := resource.(type) {
+	case *model.Organization:
+		role, err := g.database.GetMemberRole(r.ID, user.ID)
+		if err != nil {
+			return policy.None, err
+		}
+		switch {
+		case model.RoleAtLeast(role, model.RoleAdmin):
+			return policy.Write, nil
+		case model.RoleAtLeast(role, model.RoleViewer):
+			return policy.Read, nil
+		}
+	case *model.Folder:
+		if r.OrganizationID == nil {
+			return policy.None, nil
+		}
+		role, err := g.database.GetMemberRole(*r.OrganizationID, user.ID)
+		if err != nil {
+			return policy.None, err
+		}
+		switch {
+		case model.RoleAtLeast(role, model.RoleAdmin):
+			return policy.Owner, nil
+		case model.RoleAtLeast(role, model.RoleEditor):
+			return policy.Write, nil
+		case model.RoleAtLeast(role, model.RoleViewer):
+			return policy.Read, nil
+		}
+	case *model.Bookmark:
+		roles, err := g.database.GetBookmarkMemberRoles(r.ID, user.ID)
+		if err != nil {
+			return policy.None, err
+		}
+		level := policy.None
+		for _, role := range roles {
+			if model.RoleAtLeast(role, model.RoleEditor) {
+				return policy.Write, nil
+			}
+			if model.RoleAtLeast(role, model.RoleViewer) {
+				level = policy.Read
+			}
+		}
+		return level, nil
+	}
+	return policy.None, nil
+}
+
+// GetUserOrganizations returns the organizations the currently authenticated user is
+// a member of
+func (ctx *Context) GetUserOrganizations() ([]*model.Organization, error) {
+	if ctx.User == nil {
+		return nil, ctx.AuthorizationError()
+	}
+
+	return ctx.Database.GetOrganizationsByUserID(ctx.User.ID)
+}
+
+// GetOrganizationByID returns the organization with the specified ID, if the currently
+// authenticated user is a member of it.
+func (ctx *Context) GetOrganizationByID(id uint) (*model.Organization, error) {
+	if ctx.User == nil {
+		return nil, ctx.AuthorizationError()
+	}
+
+	organization, err := ctx.Database.GetOrganizationByID(id)
+	if err != nil {
+		if db.IsNotFound(err) {
+			return nil, ctx.NotFoundError("organization")
+		}
+		return nil, err
+	}
+
+	if err := ctx.canRead(organization); err != nil {
+		return nil, err
+	}
+
+	return organization, nil
+}
+
+// CreateOrganization creates an organization owned by the currently authenticated user,
+// who becomes its first member.
+func (ctx *Context) CreateOrganization(organization *model.Organization) error {
+	if ctx.User == nil {
+		return ctx.AuthorizationError()
+	}
+
+	organization.OwnerID = ctx.User.ID
+
+	if err := validateOrganization(organization); err != nil {
+		return err
+	}
+
+	return ctx.Database.CreateOrganization(organization)
+}
+
+// UpdateOrganization renames or redescribes an organization, if the currently
+// authenticated user is one of its admins.
+func (ctx *Context) UpdateOrganization(organization *model.Organization) error {
+	if ctx.User == nil {
+		return ctx.AuthorizationError()
+	}
+
+	if err := ctx.canWrite(organization); err != nil {
+		return err
+	}
+
+	if err := validateOrganization(organization); err != nil {
+		return err
+	}
+
+	return ctx.Database.UpdateOrganization(organization)
+}
+
+func validateOrganization(organization *model.Organization) *ValidationError {
+	organization.Name = strings.TrimSpace(organization.Name)
+	if organization.Name == "" {
+		return &ValidationError{"name is required"}
+	}
+	if len(organization.Name) > maxOrganizationNameLength {
+		return &ValidationError{"name is too long"}
+	}
+	return nil
+}
+
+// DeleteOrganizationByID deletes an organization and its folders, if the currently
+// authenticated user owns it. The bookmarks in its folders are kept.
+func (ctx *Context) DeleteOrganizationByID(id uint) error {
+	organization, err := ctx.GetOrganizationByID(id)
+	if err != nil {
+		return err
+	}
+
+	if err := ctx.canManage(organization); err != nil {
+		return err
+	}
+
+	return ctx.Database.DeleteOrganization(organization)
+}
+
+// GetOrganizationFolders returns the folders of an organization, if the currently
+// authenticated user is a member of it.
+func (ctx *Context) GetOrganizationFolders(id uint) ([]*model.Folder, error) {
+	if _, err := ctx.GetOrganizationByID(id); err != nil {
+		return nil, err
+	}
+
+	return ctx.Database.GetOrganizationFolders(id)
+}
+
+// GetMembers returns the members of an organization, if the currently authenticated
+// user is a member of it.
+func (ctx *Context) GetMembers(organizationID uint) ([]*model.Membership, error) {
+	if _, err := ctx.GetOrganizationByID(organizationID); err != nil {
+		return nil, err
+	}
+
+	return ctx.Database.GetMembers(organizationID)
+}
+
+// AddMember adds the user with the email address to an organization with the role, if
+// the currently authenticated user is one of its admins. Only the owner of an
+// organization can have the owner role.
+func (ctx *Context) AddMember(organizationID uint, email, role string) (*model.Membership, error) {
+	organization, err := ctx.GetOrganizationByID(organizationID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.canWrite(organization); err != nil {
+		return nil, err
+	}
+	if err := validateMemberRole(role); err != nil {
+		return nil, err
+	}
+
+	user, err := ctx.Database.GetUserByEmail(email)
+	if err != nil {
+		if db.IsNotFound(err) {
+			return nil, ctx.NotFoundError("user")
+		}
+		return nil, err
+	}
+	existing, err := ctx.Database.GetMemberRole(organizationID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != "" {
+		return nil, &UserError{Message: "user is already a member", StatusCode: http.StatusConflict}
+	}
+
+	member := &model.Membership{OrganizationID: organizationID, UserID: user.ID, User: user, Role: role}
+	if err := ctx.Database.CreateMembership(member); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// UpdateMemberRole changes the role of a member of an organization, if the currently
+// authenticated user is one of its admins. The role of the owner cannot be changed.
+func (ctx *Context) UpdateMemberRole(organizationID, userID uint, role string) (*model.Membership, error) {
+	member, err := ctx.getManagedMember(organizationID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateMemberRole(role); err != nil {
+		return nil, err
+	}
+
+	member.Role = role
+	if err := ctx.Database.UpdateMembership(member); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// RemoveMember removes a member from an organization, if the currently authenticated
+// user is one of its admins or the member leaving. The owner cannot be removed.
+func (ctx *Context) RemoveMember(organizationID, userID uint) error {
+	if ctx.User != nil && ctx.User.ID == userID {
+		if _, err := ctx.GetOrganizationByID(organizationID); err != nil {
+			return err
+		}
+		member, err := ctx.Database.GetMembership(organizationID, userID)
+		if err != nil {
+			return err
+		}
+		if member.Role == model.RoleOwner {
+			return &UserError{Message: "the owner cannot leave the organization", StatusCode: http.StatusConflict}
+		}
+		return ctx.Database.DeleteMembership(organizationID, userID)
+	}
+
+	if _, err := ctx.getManagedMember(organizationID, userID); err != nil {
+		return err
+	}
+	return ctx.Database.DeleteMembership(organizationID, userID)
+}
+
+// getManagedMember returns a member of an organization the currently authenticated user
+// can change, as one of its admins. The owner cannot be changed.
+func (ctx *Context) getManagedMember(organizationID, userID uint) (*model.Membership, error) {
+	organization, err := ctx.GetOrganizationByID(organizationID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.canWrite(organization); err != nil {
+		return nil, err
+	}
+
+	member, err := ctx.Database.GetMembership(organizationID, userID)
+	if err != nil {
+		if db.IsNotFound(err) {
+			return nil, ctx.NotFoundError("member")
+		}
+		return nil, err
+	}
+	if member.Role == model.RoleOwner {
+		return nil, &UserError{Message: "the owner of the organization cannot be changed", StatusCode: http.StatusConflict}
+	}
+	return member, nil
+}
+
+func validateMemberRole(role string) *ValidationError {
+	if role == model.RoleOwner {
+		return &ValidationError{"only the owner of the organization can have the owner role"}
+	}
+	if !model.ValidRole(role) {
+		return &ValidationError{"role must be one of " + strings.Join([]string{model.RoleAdmin, model.RoleEditor, model.RoleViewer}, ", ")}
+	}
+	return nil
+}
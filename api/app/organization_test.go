@@ -0,0 +1,196 @@
+package app
+
+import (
+	"net/http"
+	"testing"
+
+	"leggett.dev/devmarks/api/model"
+)
+
+// organizationMembers is an organization with a member of each role, and a user who
+// is not a member.
+type organizationMembers struct {
+	organization                           *model.Organization
+	owner, admin, editor, viewer, outsider *Context
+}
+
+func newOrganization(t *testing.T, a *App) *organizationMembers {
+	t.Helper()
+	m := &organizationMembers{
+		owner:    signedIn(t, a, "owner@example.com"),
+		admin:    signedIn(t, a, "admin@example.com"),
+		editor:   signedIn(t, a, "editor@example.com"),
+		viewer:   signedIn(t, a, "viewer@example.com"),
+		outsider: signedIn(t, a, "outsider@example.com"),
+	}
+	m.organization = &model.Organization{Name: "Acme"}
+	if err := m.owner.CreateOrganization(m.organization); err != nil {
+		t.Fatal(err)
+	}
+	for email, role := range map[string]string{"admin@example.com": model.RoleAdmin, "editor@example.com": model.RoleEditor, "viewer@example.com": model.RoleViewer} {
+		if _, err := m.owner.AddMember(m.organization.ID, email, role); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return m
+}
+
+func isForbidden(err error) bool {
+	return isStatus(err, http.StatusForbidden)
+}
+
+func TestOrganizationFolderRoles(t *testing.T) {
+	a := newTestApp(t)
+	m := newOrganization(t, a)
+	id := m.organization.ID
+
+	if err := m.viewer.CreateFolder(&model.Folder{Name: "Viewer's", OrganizationID: &id}); !isForbidden(err) {
+		t.Errorf("viewer creating a folder: err = %v, want forbidden", err)
+	}
+	if err := m.outsider.CreateFolder(&model.Folder{Name: "Outsider's", OrganizationID: &id}); !isForbidden(err) {
+		t.Errorf("outsider creating a folder: err = %v, want forbidden", err)
+	}
+	folder := createFolder(t, m.editor, &model.Folder{Name: "Shared", OrganizationID: &id})
+	if folder.OwnerID != m.owner.User.ID {
+		t.Errorf("folder owner = %d, want the owner of the organization %d", folder.OwnerID, m.owner.User.ID)
+	}
+	bookmark := createBookmark(t, m.owner, "https://golang.org", folder)
+
+	// every member sees the folders and their bookmarks; others do not.
+	for name, ctx := range map[string]*Context{"admin": m.admin, "editor": m.editor, "viewer": m.viewer} {
+		if _, err := ctx.GetFolderByID(folder.ID); err != nil {
+			t.Errorf("%s getting the folder: %v", name, err)
+		}
+		if _, err := ctx.GetBookmarkByID(bookmark.ID); err != nil {
+			t.Errorf("%s getting the bookmark: %v", name, err)
+		}
+	}
+	if _, err := m.outsider.GetFolderByID(folder.ID); !isForbidden(err) {
+		t.Errorf("outsider getting the folder: err = %v, want forbidden", err)
+	}
+	if _, err := m.outsider.GetBookmarkByID(bookmark.ID); !isForbidden(err) {
+		t.Errorf("outsider getting the bookmark: err = %v, want forbidden", err)
+	}
+
+	// editors change folders and bookmarks, viewers do not.
+	rename := func(ctx *Context, name string) error {
+		folder, err := ctx.GetFolderByID(folder.ID)
+		if err != nil {
+			return err
+		}
+		folder.Name = name
+		return ctx.UpdateFolder(folder)
+	}
+	if err := rename(m.viewer, "Viewed"); !isForbidden(err) {
+		t.Errorf("viewer renaming the folder: err = %v, want forbidden", err)
+	}
+	if err := rename(m.editor, "Edited"); err != nil {
+		t.Errorf("editor renaming the folder: %v", err)
+	}
+	edit := func(ctx *Context) error {
+		bookmark, err := ctx.GetBookmarkByID(bookmark.ID)
+		if err != nil {
+			return err
+		}
+		bookmark.Name = "Go"
+		return ctx.UpdateBookmark(bookmark, nil)
+	}
+	if err := edit(m.viewer); !isForbidden(err) {
+		t.Errorf("viewer editing the bookmark: err = %v, want forbidden", err)
+	}
+	if err := edit(m.editor); err != nil {
+		t.Errorf("editor editing the bookmark: %v", err)
+	}
+
+	// only admins delete folders, and only the owner deletes the organization.
+	if err := m.editor.DeleteFolderByID(folder.ID, false); !isForbidden(err) {
+		t.Errorf("editor deleting the folder: err = %v, want forbidden", err)
+	}
+	if err := m.admin.DeleteFolderByID(folder.ID, false); err != nil {
+		t.Errorf("admin deleting the folder: %v", err)
+	}
+	if err := m.admin.DeleteOrganizationByID(id); !isForbidden(err) {
+		t.Errorf("admin deleting the organization: err = %v, want forbidden", err)
+	}
+	if err := m.owner.DeleteOrganizationByID(id); err != nil {
+		t.Errorf("owner deleting the organization: %v", err)
+	}
+	if _, err := m.viewer.GetOrganizationByID(id); !isStatus(err, http.StatusNotFound) {
+		t.Errorf("getting the deleted organization: err = %v, want not found", err)
+	}
+}
+
+func TestOrganizationMemberRoles(t *testing.T) {
+	a := newTestApp(t)
+	m := newOrganization(t, a)
+	id := m.organization.ID
+	signedIn(t, a, "new@example.com")
+
+	for name, ctx := range map[string]*Context{"editor": m.editor, "viewer": m.viewer} {
+		if _, err := ctx.AddMember(id, "new@example.com", model.RoleViewer); !isForbidden(err) {
+			t.Errorf("%s adding a member: err = %v, want forbidden", name, err)
+		}
+		if _, err := ctx.UpdateMemberRole(id, m.viewer.User.ID, model.RoleAdmin); !isForbidden(err) {
+			t.Errorf("%s changing a role: err = %v, want forbidden", name, err)
+		}
+	}
+	if _, err := m.outsider.GetMembers(id); !isForbidden(err) {
+		t.Errorf("outsider listing the members: err = %v, want forbidden", err)
+	}
+
+	if _, err := m.admin.AddMember(id, "new@example.com", model.RoleOwner); !isValidationError(err) {
+		t.Errorf("admin adding an owner: err = %v, want a validation error", err)
+	}
+	if _, err := m.admin.AddMember(id, "new@example.com", "superuser"); !isValidationError(err) {
+		t.Errorf("admin adding an unknown role: err = %v, want a validation error", err)
+	}
+	if _, err := m.admin.AddMember(id, "missing@example.com", model.RoleViewer); !isStatus(err, http.StatusNotFound) {
+		t.Errorf("admin adding a missing user: err = %v, want not found", err)
+	}
+	if _, err := m.admin.AddMember(id, "viewer@example.com", model.RoleViewer); !isStatus(err, http.StatusConflict) {
+		t.Errorf("admin adding a member again: err = %v, want a conflict", err)
+	}
+	if _, err := m.admin.AddMember(id, "new@example.com", model.RoleViewer); err != nil {
+		t.Errorf("admin adding a member: %v", err)
+	}
+
+	member, err := m.admin.UpdateMemberRole(id, m.viewer.User.ID, model.RoleEditor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if member.Role != model.RoleEditor {
+		t.Errorf("role = %q, want %q", member.Role, model.RoleEditor)
+	}
+	if _, err := m.admin.UpdateMemberRole(id, m.owner.User.ID, model.RoleViewer); !isStatus(err, http.StatusConflict) {
+		t.Errorf("admin changing the owner: err = %v, want a conflict", err)
+	}
+	if err := m.admin.RemoveMember(id, m.owner.User.ID); !isStatus(err, http.StatusConflict) {
+		t.Errorf("admin removing the owner: err = %v, want a conflict", err)
+	}
+	if err := m.owner.RemoveMember(id, m.owner.User.ID); !isStatus(err, http.StatusConflict) {
+		t.Errorf("owner leaving: err = %v, want a conflict", err)
+	}
+
+	// members can leave, and admins can remove others.
+	if err := m.editor.RemoveMember(id, m.editor.User.ID); err != nil {
+		t.Errorf("editor leaving: %v", err)
+	}
+	if err := m.admin.RemoveMember(id, m.viewer.User.ID); err != nil {
+		t.Errorf("admin removing a member: %v", err)
+	}
+	if _, err := m.viewer.GetOrganizationByID(id); !isForbidden(err) {
+		t.Errorf("removed member getting the organization: err = %v, want forbidden", err)
+	}
+
+	members, err := m.owner.GetMembers(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roles := map[uint]string{}
+	for _, member := range members {
+		roles[member.UserID] = member.Role
+	}
+	if len(roles) != 3 || roles[m.owner.User.ID] != model.RoleOwner || roles[m.admin.User.ID] != model.RoleAdmin {
+		t.Errorf("members = %v, want the owner, the admin and the new member", roles)
+	}
+}
@@ -0,0 +1,138 @@
+package app
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"leggett.dev/devmarks/api/db"
+	"leggett.dev/devmarks/api/model"
+)
+
+// maxUserNameLength is the longest name a user can have.
+const maxUserNameLength = 100
+
+var (
+	// ErrWrongPassword is returned when the current password given to change it is wrong.
+	ErrWrongPassword = &UserError{Message: "current password is incorrect", StatusCode: http.StatusForbidden}
+	// ErrEmailTaken is returned when a user changes their email address to one another
+	// user has.
+	ErrEmailTaken = &UserError{Message: "email is already in use", StatusCode: http.StatusConflict}
+	// ErrOwnsOrganizations is returned when a user who owns organizations deletes their
+	// account. Only the owner can delete an organization, so they have to do it first
+	// rather than leave it without one.
+	ErrOwnsOrganizations = &UserError{Message: "delete the organizations you own before deleting your account", StatusCode: http.StatusConflict}
+)
+
+// ProfileUpdate holds the changes to a user's profile. Fields left nil are not changed.
+type ProfileUpdate struct {
+	Name                 *string
+	Email                *string
+	DefaultBookmarkColor *string
+	// ClearDefaultBookmarkColor removes the default bookmark color.
+	ClearDefaultBookmarkColor bool
+	Theme                     *string
+}
+
+// UpdateProfile applies the changes to the user's profile. A new email address has to
+// be verified again, so a verification email is sent to it.
+func (a *App) UpdateProfile(user *model.User, update *ProfileUpdate) error {
+	if update.Name != nil {
+		name := strings.TrimSpace(*update.Name)
+		if len(name) > maxUserNameLength {
+			return &ValidationError{"name is too long"}
+		}
+		user.Name = &name
+		if name == "" {
+			user.Name = nil
+		}
+	}
+	if update.DefaultBookmarkColor != nil {
+		user.Preferences.DefaultBookmarkColor = update.DefaultBookmarkColor
+	}
+	if update.ClearDefaultBookmarkColor {
+		user.Preferences.DefaultBookmarkColor = nil
+	}
+	if update.Theme != nil {
+		if !isValidTheme(*update.Theme) {
+			return &ValidationError{"theme must be one of " + strings.Join([]string{model.ThemeSystem, model.ThemeLight, model.ThemeDark}, ", ")}
+		}
+		user.Preferences.Theme = *update.Theme
+	}
+
+	emailChanged := false
+	if update.Email != nil && *update.Email != user.Email {
+		if err := validateEmail(*update.Email); err != nil {
+			return err
+		}
+		existing, err := a.Database.GetUserByEmail(*update.Email)
+		if err == nil && existing.ID != user.ID {
+			return ErrEmailTaken
+		}
+		if err != nil && !db.IsNotFound(err) {
+			return err
+		}
+		user.Email = *update.Email
+		user.EmailVerifiedAt = nil
+		emailChanged = true
+	}
+
+	if err := a.Database.UpdateUser(user); err != nil {
+		return err
+	}
+
+	if emailChanged {
+		if err := a.SendEmailVerification(user); err != nil {
+			logrus.WithError(err).WithField("user_id", user.ID).Error("unable to send email verification")
+		}
+	}
+	return nil
+}
+
+func isValidTheme(theme string) bool {
+	return theme == model.ThemeSystem || theme == model.ThemeLight || theme == model.ThemeDark
+}
+
+// ChangePassword sets a new password for the user, who has to give their current one.
+// The user is signed out everywhere except with the token with the specified ID or the
+// login session with the specified refresh token family, whichever the request was
+// made with.
+func (a *App) ChangePassword(user *model.User, current, password string, tokenID uint, session string) error {
+	if !user.CheckPassword(current) {
+		return ErrWrongPassword
+	}
+	if err := validatePassword(password); err != nil {
+		return err
+	}
+
+	if err := user.SetPassword(password); err != nil {
+		return errors.Wrap(err, "unable to set user password")
+	}
+	return a.Database.WithTransaction(func(tx *db.Database) error {
+		if err := tx.UpdateUser(user); err != nil {
+			return err
+		}
+		return tx.RevokeOtherUserLogins(user.ID, tokenID, session, time.Now())
+	})
+}
+
+// DeleteAccount soft-deletes the user and signs them out everywhere. Users who own
+// organizations have to delete them first.
+func (a *App) DeleteAccount(user *model.User) error {
+	return a.Database.WithTransaction(func(tx *db.Database) error {
+		owned, err := tx.CountOwnedOrganizations(user.ID)
+		if err != nil {
+			return err
+		}
+		if owned > 0 {
+			return ErrOwnsOrganizations
+		}
+		if err := tx.RevokeUserLogins(user.ID, time.Now()); err != nil {
+			return err
+		}
+		return tx.DeleteUser(user)
+	})
+}
@@ -0,0 +1,88 @@
+package app
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+
+	"leggett.dev/devmarks/api/mailer"
+	"leggett.dev/devmarks/api/model"
+)
+
+func TestDeleteAccountFreesEmail(t *testing.T) {
+	a, mock := newOIDCApp(t)
+	a.Mailer = mailer.NewFile("devmarks@example.com", filepath.Join(t.TempDir(), "mail"))
+	// the unique indexes of the migrations, which the test database does not have.
+	for _, index := range []string{
+		"CREATE UNIQUE INDEX users_email_key ON users(email) WHERE deleted_at IS NULL",
+		"CREATE UNIQUE INDEX identities_issuer_subject_key ON identities(issuer, subject)",
+	} {
+		if err := a.Database.Exec(index).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	claims := jwt.MapClaims{"sub": "1", "email": "jane@example.com", "email_verified": true}
+	user, err := signIn(t, a, mock, claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.DeleteAccount(user); err != nil {
+		t.Fatal(err)
+	}
+
+	again, err := signIn(t, a, mock, claims)
+	if err != nil {
+		t.Fatalf("signing in with the provider account of a deleted user: %v", err)
+	}
+	if again.ID == user.ID {
+		t.Errorf("signed in as the deleted user %d", user.ID)
+	}
+	if err := a.DeleteAccount(again); err != nil {
+		t.Fatal(err)
+	}
+
+	a.Config.Registration = RegistrationOpen
+	if err := a.CreateUser(&model.User{Email: "jane@example.com"}, "correct horse battery", ""); err != nil {
+		t.Errorf("registering the email address of a deleted user: %v", err)
+	}
+}
+
+func TestDeleteAccountOwningOrganizations(t *testing.T) {
+	a := newTestApp(t)
+	owner := signedIn(t, a, "owner@example.com")
+	member := signedIn(t, a, "member@example.com")
+
+	organization := &model.Organization{Name: "Acme"}
+	if err := owner.CreateOrganization(organization); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := owner.AddMember(organization.ID, "member@example.com", model.RoleEditor); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.DeleteAccount(owner.User); !isStatus(err, http.StatusConflict) {
+		t.Errorf("deleting the owner of an organization: err = %v, want a conflict", err)
+	}
+
+	// members leave the organizations they are in.
+	if err := a.DeleteAccount(member.User); err != nil {
+		t.Fatal(err)
+	}
+	members, err := owner.GetMembers(organization.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 1 || members[0].UserID != owner.User.ID {
+		t.Errorf("members = %+v, want only the owner", members)
+	}
+
+	if err := owner.DeleteOrganizationByID(organization.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.DeleteAccount(owner.User); err != nil {
+		t.Errorf("deleting the owner once the organization is deleted: %v", err)
+	}
+}
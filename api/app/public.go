@@ -0,0 +1,149 @@
+package app
+
+import (
+	"net/http"
+	"time"
+
+	"leggett.dev/devmarks/api/db"
+	"leggett.dev/devmarks/api/model"
+)
+
+var (
+	// ErrPublicFolderNotFound is returned when no folder is published under a slug, or
+	// its link expired.
+	ErrPublicFolderNotFound = &UserError{Message: "folder does not exist", StatusCode: http.StatusNotFound}
+	// ErrPublicPasswordRequired is returned when a password protected folder is
+	// requested without a password.
+	ErrPublicPasswordRequired = &UserError{Message: "a password is required to see this folder", StatusCode: http.StatusUnauthorized}
+	// ErrPublicPasswordWrong is returned when the password given for a folder is wrong.
+	ErrPublicPasswordWrong = &UserError{Message: "incorrect password", StatusCode: http.StatusUnauthorized}
+)
+
+// PublishFolder publishes a folder, and the folders below it, at a new unguessable
+// link anyone can see without signing in, if the currently authenticated user can
+// share it. Publishing a folder again replaces its link, so the old one stops working.
+// Visitors need the password, if one is given, and the link stops working at expiresAt.
+func (ctx *Context) PublishFolder(id uint, password string, expiresAt *time.Time) (*model.Folder, error) {
+	folder, err := ctx.getManagedFolder(id)
+	if err != nil {
+		return nil, err
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, &ValidationError{"expires_at must be in the future"}
+	}
+
+	slug := model.NewPublicSlug()
+	folder.PublicSlug = &slug
+	folder.PublicExpiresAt = expiresAt
+	folder.PublicPasswordHash = nil
+	if password != "" {
+		folder.PublicPasswordHash, err = model.GeneratePasswordHash([]byte(password))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := ctx.Database.UpdateFolder(folder); err != nil {
+		return nil, err
+	}
+	return folder, nil
+}
+
+// UnpublishFolder stops publishing a folder, if the currently authenticated user can
+// share it.
+func (ctx *Context) UnpublishFolder(id uint) error {
+	folder, err := ctx.getManagedFolder(id)
+	if err != nil {
+		return err
+	}
+
+	folder.PublicSlug = nil
+	folder.PublicExpiresAt = nil
+	folder.PublicPasswordHash = nil
+	return ctx.Database.UpdateFolder(folder)
+}
+
+// GetPublicFolder returns the folder published under the slug, with its bookmarks and
+// the folders below it, to anyone visiting from the IP address. The password is only
+// checked if the folder is password protected.
+func (a *App) GetPublicFolder(slug, password, ip string) (*model.PublicFolder, error) {
+	folder, err := a.Database.GetFolderByPublicSlug(slug)
+	if err != nil {
+		if db.IsNotFound(err) {
+			return nil, ErrPublicFolderNotFound
+		}
+		return nil, err
+	}
+	if !folder.Public() {
+		return nil, ErrPublicFolderNotFound
+	}
+	if folder.PublicPasswordProtected() {
+		if password == "" {
+			return nil, ErrPublicPasswordRequired
+		}
+		if err := a.checkPublicPassword(folder, slug, password, ip); err != nil {
+			return nil, err
+		}
+	}
+
+	descendants, err := a.Database.GetFolderDescendantIDs(folder.ID)
+	if err != nil {
+		return nil, err
+	}
+	folders, err := a.Database.GetFoldersWithBookmarks(append([]uint{folder.ID}, descendants...))
+	if err != nil {
+		return nil, err
+	}
+	return buildPublicFolder(folder.ID, folders), nil
+}
+
+// checkPublicPassword checks the password given for the password protected folder
+// published under the slug. Attempts are recorded and throttled like logins, for the
+// IP address and for the slug in place of an email address; the slug is hashed so the
+// login attempts and logs do not reveal it.
+func (a *App) checkPublicPassword(folder *model.Folder, slug, password, ip string) error {
+	key := "public:" + fingerprint([]byte(slug))
+	if err := a.checkLoginRate(key, ip); err != nil {
+		return err
+	}
+	if !model.ComparePasswordHash(folder.PublicPasswordHash, []byte(password)) {
+		if err := a.recordLogin(key, nil, ip, false, model.LoginFailedPublicPassword); err != nil {
+			return err
+		}
+		return ErrPublicPasswordWrong
+	}
+	return a.recordLogin(key, nil, ip, true, "")
+}
+
+// buildPublicFolder nests the folders below the folder with the ID rootID. The folders
+// are ordered by name, and so are the folders below each of them.
+func buildPublicFolder(rootID uint, folders []*model.Folder) *model.PublicFolder {
+	nodes := make(map[uint]*model.PublicFolder, len(folders))
+	for _, folder := range folders {
+		node := &model.PublicFolder{
+			Name:      folder.Name,
+			Color:     folder.Color,
+			UpdatedAt: folder.UpdatedAt,
+			Bookmarks: make([]*model.PublicBookmark, 0, len(folder.Bookmarks)),
+			Folders:   []*model.PublicFolder{},
+		}
+		for _, bookmark := range folder.Bookmarks {
+			node.Bookmarks = append(node.Bookmarks, &model.PublicBookmark{
+				Name:      bookmark.Name,
+				URL:       bookmark.URL,
+				Notes:     bookmark.Notes,
+				CreatedAt: bookmark.CreatedAt,
+			})
+		}
+		nodes[folder.ID] = node
+	}
+	for _, folder := range folders {
+		if folder.ID == rootID || folder.ParentID == nil {
+			continue
+		}
+		if parent, ok := nodes[*folder.ParentID]; ok {
+			parent.Folders = append(parent.Folders, nodes[folder.ID])
+		}
+	}
+	return nodes[rootID]
+}
@@ -0,0 +1,147 @@
+package app
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"leggett.dev/devmarks/api/model"
+)
+
+func TestPublicFolder(t *testing.T) {
+	a := newTestApp(t)
+	ctx := signedIn(t, a, "alice@example.com")
+	victor := signedIn(t, a, "victor@example.com")
+
+	folder := createFolder(t, ctx, &model.Folder{Name: "Reading"})
+	child := createFolder(t, ctx, &model.Folder{Name: "Later", ParentID: &folder.ID})
+	createBookmark(t, ctx, "https://golang.org", folder)
+	createBookmark(t, ctx, "https://go.dev", child)
+	createFolder(t, ctx, &model.Folder{Name: "Private"})
+
+	if _, err := victor.PublishFolder(folder.ID, "", nil); err == nil {
+		t.Error("PublishFolder() published the folder of another user")
+	}
+	past := time.Now().Add(-time.Minute)
+	if _, err := ctx.PublishFolder(folder.ID, "", &past); !isValidationError(err) {
+		t.Errorf("PublishFolder() with a past expiry: err = %v, want a validation error", err)
+	}
+
+	published, err := ctx.PublishFolder(folder.ID, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slug := *published.PublicSlug
+	public, err := a.GetPublicFolder(slug, "", "192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if public.Name != "Reading" || len(public.Bookmarks) != 1 || public.Bookmarks[0].URL != "https://golang.org" {
+		t.Errorf("public folder = %+v, want Reading with its bookmark", public)
+	}
+	if len(public.Folders) != 1 || public.Folders[0].Name != "Later" || len(public.Folders[0].Bookmarks) != 1 {
+		t.Errorf("public folders = %+v, want only Later with its bookmark", public.Folders)
+	}
+
+	// publishing again replaces the link.
+	republished, err := ctx.PublishFolder(folder.ID, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.GetPublicFolder(slug, "", "192.0.2.1"); err != ErrPublicFolderNotFound {
+		t.Errorf("old link: err = %v, want %v", err, ErrPublicFolderNotFound)
+	}
+	if err := ctx.UnpublishFolder(folder.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.GetPublicFolder(*republished.PublicSlug, "", "192.0.2.1"); err != ErrPublicFolderNotFound {
+		t.Errorf("unpublished link: err = %v, want %v", err, ErrPublicFolderNotFound)
+	}
+}
+
+func TestPublicFolderExpires(t *testing.T) {
+	a := newTestApp(t)
+	ctx := signedIn(t, a, "alice@example.com")
+	folder := createFolder(t, ctx, &model.Folder{Name: "Reading"})
+
+	expiresAt := time.Now().Add(time.Hour)
+	published, err := ctx.PublishFolder(folder.ID, "", &expiresAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.GetPublicFolder(*published.PublicSlug, "", "192.0.2.1"); err != nil {
+		t.Fatalf("link before it expires: %v", err)
+	}
+
+	if err := a.Database.Model(published).UpdateColumn("public_expires_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.GetPublicFolder(*published.PublicSlug, "", "192.0.2.1"); err != ErrPublicFolderNotFound {
+		t.Errorf("expired link: err = %v, want %v", err, ErrPublicFolderNotFound)
+	}
+}
+
+func TestPublicFolderPassword(t *testing.T) {
+	a := newLoginApp(t)
+	ctx := signedIn(t, a, "alice@example.com")
+	folder := createFolder(t, ctx, &model.Folder{Name: "Reading"})
+	published, err := ctx.PublishFolder(folder.ID, "open sesame", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slug := *published.PublicSlug
+
+	if _, err := a.GetPublicFolder(slug, "", "192.0.2.1"); err != ErrPublicPasswordRequired {
+		t.Errorf("without a password: err = %v, want %v", err, ErrPublicPasswordRequired)
+	}
+	if _, err := a.GetPublicFolder(slug, "open sesame", "192.0.2.1"); err != nil {
+		t.Errorf("with the password: %v", err)
+	}
+
+	// wrong passwords are throttled for the link, from every IP address.
+	for i := 0; i < 3; i++ {
+		if _, err := a.GetPublicFolder(slug, "wrong", "192.0.2.1"); err != ErrPublicPasswordWrong {
+			t.Fatalf("wrong password %d: err = %v, want %v", i+1, err, ErrPublicPasswordWrong)
+		}
+	}
+	if _, err := a.GetPublicFolder(slug, "open sesame", "192.0.2.2"); retryAfter(err) <= 0 {
+		t.Errorf("password after too many wrong ones: err = %v, want to wait", err)
+	}
+	// the password is only checked once it is given.
+	if _, err := a.GetPublicFolder(slug, "", "192.0.2.2"); err != ErrPublicPasswordRequired {
+		t.Errorf("without a password while throttled: err = %v, want %v", err, ErrPublicPasswordRequired)
+	}
+
+	var attempts []*model.LoginAttempt
+	if err := a.Database.Where("reason = ?", model.LoginFailedPublicPassword).Find(&attempts).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(attempts) != 3 || attempts[0].Email == slug {
+		t.Errorf("recorded wrong passwords = %+v, want three without the slug", attempts)
+	}
+
+	// and for the IP address, across links.
+	publish := func(name string) string {
+		folder := createFolder(t, ctx, &model.Folder{Name: name})
+		published, err := ctx.PublishFolder(folder.ID, "open sesame", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return *published.PublicSlug
+	}
+	for i := 0; i < 5; i++ {
+		slug := publish(fmt.Sprintf("Folder %d", i))
+		for j := 0; j < 2; j++ {
+			if _, err := a.GetPublicFolder(slug, "wrong", "192.0.2.3"); err != ErrPublicPasswordWrong {
+				t.Fatalf("wrong password for folder %d: err = %v, want %v", i, err, ErrPublicPasswordWrong)
+			}
+		}
+	}
+	slug = publish("Last")
+	if _, err := a.GetPublicFolder(slug, "open sesame", "192.0.2.3"); retryAfter(err) <= 0 {
+		t.Errorf("password from a throttled IP address: err = %v, want to wait", err)
+	}
+	if _, err := a.GetPublicFolder(slug, "open sesame", "192.0.2.4"); err != nil {
+		t.Errorf("password from another IP address: %v", err)
+	}
+}
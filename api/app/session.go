@@ -0,0 +1,158 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/sirupsen/logrus"
+
+	"leggett.dev/devmarks/api/db"
+	"leggett.dev/devmarks/api/model"
+)
+
+// accessTokenIssuer is the issuer of the JWT access tokens handed out on login.
+const accessTokenIssuer = "devmarks"
+
+var (
+	// ErrInvalidRefreshToken is returned when a refresh token does not exist, was
+	// revoked or has expired.
+	ErrInvalidRefreshToken = &UserError{Message: "invalid refresh token", StatusCode: http.StatusUnauthorized}
+	// ErrRefreshTokenReused is returned when a refresh token that was already exchanged
+	// is used again. The whole session is revoked, since the token was likely stolen.
+	ErrRefreshTokenReused = &UserError{Message: "refresh token was already used, the session has been revoked", StatusCode: http.StatusUnauthorized}
+	// ErrRefreshDisabled is returned when refreshing is requested while logins hand out
+	// opaque tokens.
+	ErrRefreshDisabled = &UserError{Message: "refresh tokens are not in use", StatusCode: http.StatusNotFound}
+)
+
+// AccessClaims are the claims of a JWT access token. The subject is the ID of the user
+// and Session is the family of the refresh tokens of the login.
+type AccessClaims struct {
+	jwt.StandardClaims
+	Session string `json:"sid"`
+}
+
+// Session is a login session as handed out to the client: a short-lived access token
+// and the refresh token to get the next one with.
+type Session struct {
+	AccessToken  string
+	ExpiresAt    time.Time
+	RefreshToken string
+}
+
+// CreateSession starts a new login session for the user.
+func (a *App) CreateSession(user *model.User) (*Session, error) {
+	return a.issueSession(a.Database, user, model.NewTokenFamily())
+}
+
+// RefreshSession exchanges a refresh token for a new access token and refresh token of
+// the same session. A refresh token can only be exchanged once: using it again revokes
+// the session and returns ErrRefreshTokenReused.
+func (a *App) RefreshSession(secret string) (*Session, error) {
+	if a.Config.AuthStrategy != AuthStrategyJWT {
+		return nil, ErrRefreshDisabled
+	}
+
+	var session *Session
+	var reused *model.RefreshToken
+	err := a.Database.WithTransaction(func(tx *db.Database) error {
+		token, err := tx.GetRefreshTokenByHash(model.HashToken(secret))
+		if err != nil {
+			if db.IsNotFound(err) {
+				return ErrInvalidRefreshToken
+			}
+			return err
+		}
+		if token.User == nil || !token.Active() {
+			return ErrInvalidRefreshToken
+		}
+
+		fresh, err := tx.MarkRefreshTokenUsed(token.ID, time.Now())
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			reused = token
+			return ErrRefreshTokenReused
+		}
+
+		session, err = a.issueSession(tx, token.User, token.Family)
+		return err
+	})
+	if reused != nil {
+		// the session is revoked outside of the transaction, which was rolled back.
+		logrus.WithFields(logrus.Fields{"user_id": reused.UserID, "session": reused.Family}).Warn("refresh token reused, revoking session")
+		if err := a.Database.RevokeRefreshTokenFamily(reused.UserID, reused.Family, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (a *App) issueSession(database *db.Database, user *model.User, family string) (*Session, error) {
+	secret, refreshToken := model.NewRefreshToken(user.ID, family, time.Now().Add(a.Config.TokenLifetime))
+	if err := database.CreateRefreshToken(refreshToken); err != nil {
+		return nil, err
+	}
+
+	accessToken, expiresAt, err := a.issueAccessToken(user, family)
+	if err != nil {
+		return nil, err
+	}
+	return &Session{AccessToken: accessToken, ExpiresAt: expiresAt, RefreshToken: secret}, nil
+}
+
+func (a *App) issueAccessToken(user *model.User, family string) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(a.Config.AccessTokenLifetime)
+	claims := AccessClaims{
+		StandardClaims: jwt.StandardClaims{
+			Subject:   strconv.Itoa(int(user.ID)),
+			Issuer:    accessTokenIssuer,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: expiresAt.Unix(),
+		},
+		Session: family,
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(a.Config.SecretKey)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
+}
+
+// ParseAccessToken verifies the signature and expiry of a JWT access token and returns
+// its claims.
+func (a *App) ParseAccessToken(signed string) (*AccessClaims, error) {
+	claims := &AccessClaims{}
+	_, err := jwt.ParseWithClaims(signed, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return a.Config.SecretKey, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if claims.Issuer != accessTokenIssuer || claims.Session == "" {
+		return nil, fmt.Errorf("not an access token")
+	}
+	return claims, nil
+}
+
+// RevokeSession ends the login session of the currently authenticated user with the
+// specified refresh token family. Access tokens already handed out stay valid until
+// they expire.
+func (ctx *Context) RevokeSession(family string) error {
+	if ctx.User == nil {
+		return ctx.AuthorizationError()
+	}
+
+	return ctx.Database.RevokeRefreshTokenFamily(ctx.User.ID, family, time.Now())
+}
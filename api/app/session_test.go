@@ -0,0 +1,145 @@
+package app
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"leggett.dev/devmarks/api/model"
+)
+
+// newSessionApp returns an app handing out JWT access tokens and refresh tokens.
+func newSessionApp(t *testing.T) *App {
+	a := newTestApp(t)
+	a.Config = &Config{
+		SecretKey:           []byte("secret"),
+		AuthStrategy:        AuthStrategyJWT,
+		TokenLifetime:       time.Hour,
+		AccessTokenLifetime: 15 * time.Minute,
+	}
+	return a
+}
+
+func TestRefreshSessionRotates(t *testing.T) {
+	a := newSessionApp(t)
+	ctx := signedIn(t, a, "alice@example.com")
+
+	first, err := a.CreateSession(ctx.User)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := a.ParseAccessToken(first.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != strconv.Itoa(int(ctx.User.ID)) || claims.Session == "" {
+		t.Errorf("access token claims = %+v, want user %d and a session", claims, ctx.User.ID)
+	}
+
+	second, err := a.RefreshSession(first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Error("RefreshSession() handed out the same refresh token")
+	}
+	refreshed, err := a.ParseAccessToken(second.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshed.Session != claims.Session {
+		t.Errorf("refreshed session = %q, want %q", refreshed.Session, claims.Session)
+	}
+
+	third, err := a.RefreshSession(second.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.RefreshSession("dmr_unknown"); err != ErrInvalidRefreshToken {
+		t.Errorf("unknown refresh token: err = %v, want %v", err, ErrInvalidRefreshToken)
+	}
+
+	// ending the session stops its latest refresh token too.
+	if err := ctx.RevokeSession(claims.Session); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.RefreshSession(third.RefreshToken); err != ErrInvalidRefreshToken {
+		t.Errorf("refresh token of an ended session: err = %v, want %v", err, ErrInvalidRefreshToken)
+	}
+}
+
+func TestRefreshSessionReuse(t *testing.T) {
+	a := newSessionApp(t)
+	ctx := signedIn(t, a, "alice@example.com")
+
+	stolen, err := a.CreateSession(ctx.User)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := a.CreateSession(ctx.User)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := a.RefreshSession(stolen.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// using a refresh token twice revokes its whole session, but no other.
+	if _, err := a.RefreshSession(stolen.RefreshToken); err != ErrRefreshTokenReused {
+		t.Errorf("reused refresh token: err = %v, want %v", err, ErrRefreshTokenReused)
+	}
+	if _, err := a.RefreshSession(rotated.RefreshToken); err != ErrInvalidRefreshToken {
+		t.Errorf("refresh token of the revoked session: err = %v, want %v", err, ErrInvalidRefreshToken)
+	}
+	if _, err := a.RefreshSession(other.RefreshToken); err != nil {
+		t.Errorf("refresh token of another session: %v", err)
+	}
+}
+
+func TestRefreshSessionExpired(t *testing.T) {
+	a := newSessionApp(t)
+	ctx := signedIn(t, a, "alice@example.com")
+
+	session, err := a.CreateSession(ctx.User)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = a.Database.Model(&model.RefreshToken{}).Where("hash = ?", model.HashToken(session.RefreshToken)).
+		UpdateColumn("expires_at", time.Now().Add(-time.Second)).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.RefreshSession(session.RefreshToken); err != ErrInvalidRefreshToken {
+		t.Errorf("expired refresh token: err = %v, want %v", err, ErrInvalidRefreshToken)
+	}
+
+	a.Config.AuthStrategy = AuthStrategyToken
+	if _, err := a.RefreshSession(session.RefreshToken); err != ErrRefreshDisabled {
+		t.Errorf("refreshing with opaque tokens: err = %v, want %v", err, ErrRefreshDisabled)
+	}
+}
+
+func TestParseAccessToken(t *testing.T) {
+	a := newSessionApp(t)
+	ctx := signedIn(t, a, "alice@example.com")
+	session, err := a.CreateSession(ctx.User)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other := newSessionApp(t)
+	other.Config.SecretKey = []byte("another secret")
+	if _, err := other.ParseAccessToken(session.AccessToken); err == nil {
+		t.Error("ParseAccessToken() accepted a token signed with another key")
+	}
+
+	a.Config.AccessTokenLifetime = -time.Minute
+	expired, err := a.CreateSession(ctx.User)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.ParseAccessToken(expired.AccessToken); err == nil {
+		t.Error("ParseAccessToken() accepted an expired token")
+	}
+}
@@ -0,0 +1,169 @@
+package app
+
+import (
+	"net/http"
+
+	"leggett.dev/devmarks/api/db"
+	"leggett.dev/devmarks/api/model"
+	"leggett.dev/devmarks/api/policy"
+)
+
+// shareGranter grants the users a folder is shared with access to it, the folders
+// below it and the bookmarks in them.
+type shareGranter struct {
+	database *db.Database
+}
+
+// Grant implements policy.Granter. Sharing with edit access lets the user change the
+// folders and bookmarks; read access lets them see them.
+func (g *shareGranter) Grant(user *model.User, resource interface{}) (policy.Level, error) {
+	var access []string
+	var err error
+	switch r := resource.(type) {
+	case *model.Folder:
+		access, err = g.database.GetFolderShareAccess(r.ID, user.ID)
+	case *model.Bookmark:
+		access, err = g.database.GetBookmarkShareAccess(r.ID, user.ID)
+	default:
+		return policy.None, nil
+	}
+	if err != nil {
+		return policy.None, err
+	}
+
+	level := policy.None
+	for _, a := range access {
+		if a == model.AccessEdit {
+			return policy.Write, nil
+		}
+		if a == model.AccessRead {
+			level = policy.Read
+		}
+	}
+	return level, nil
+}
+
+// GetSharedFolders returns the folders shared with the currently authenticated user,
+// along with the access they were given. Folders below them are shared too, but are
+// not listed.
+func (ctx *Context) GetSharedFolders() ([]*model.Share, error) {
+	if ctx.User == nil {
+		return nil, ctx.AuthorizationError()
+	}
+
+	return ctx.Database.GetSharesByUserID(ctx.User.ID)
+}
+
+// GetShares returns the users a folder is shared with, if the currently authenticated
+// user can share it.
+func (ctx *Context) GetShares(folderID uint) ([]*model.Share, error) {
+	if _, err := ctx.getManagedFolder(folderID); err != nil {
+		return nil, err
+	}
+
+	return ctx.Database.GetShares(folderID)
+}
+
+// ShareFolder shares a folder with the user with the email address, if the currently
+// authenticated user can share it.
+func (ctx *Context) ShareFolder(folderID uint, email, access string) (*model.Share, error) {
+	folder, err := ctx.getManagedFolder(folderID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateShareAccess(access); err != nil {
+		return nil, err
+	}
+
+	user, err := ctx.Database.GetUserByEmail(email)
+	if err != nil {
+		if db.IsNotFound(err) {
+			return nil, ctx.NotFoundError("user")
+		}
+		return nil, err
+	}
+	if user.ID == folder.OwnerID {
+		return nil, &ValidationError{"a folder cannot be shared with its owner"}
+	}
+	if _, err := ctx.Database.GetShare(folderID, user.ID); err == nil {
+		return nil, &UserError{Message: "folder is already shared with this user", StatusCode: http.StatusConflict}
+	} else if !db.IsNotFound(err) {
+		return nil, err
+	}
+
+	share := &model.Share{FolderID: folderID, UserID: user.ID, User: user, Access: access}
+	if err := ctx.Database.CreateShare(share); err != nil {
+		return nil, err
+	}
+	return share, nil
+}
+
+// UpdateShare changes the access a user was given to a folder, if the currently
+// authenticated user can share it.
+func (ctx *Context) UpdateShare(folderID, userID uint, access string) (*model.Share, error) {
+	if _, err := ctx.getManagedFolder(folderID); err != nil {
+		return nil, err
+	}
+	if err := validateShareAccess(access); err != nil {
+		return nil, err
+	}
+
+	share, err := ctx.getShare(folderID, userID)
+	if err != nil {
+		return nil, err
+	}
+	share.Access = access
+	if err := ctx.Database.UpdateShare(share); err != nil {
+		return nil, err
+	}
+	return share, nil
+}
+
+// Unshare stops sharing a folder with a user, if the currently authenticated user can
+// share the folder or is the user giving the share up.
+func (ctx *Context) Unshare(folderID, userID uint) error {
+	if ctx.User == nil {
+		return ctx.AuthorizationError()
+	}
+	if ctx.User.ID != userID {
+		if _, err := ctx.getManagedFolder(folderID); err != nil {
+			return err
+		}
+	}
+
+	if _, err := ctx.getShare(folderID, userID); err != nil {
+		return err
+	}
+	return ctx.Database.DeleteShare(folderID, userID)
+}
+
+// getManagedFolder returns the folder with the specified ID if the currently
+// authenticated user can share it.
+func (ctx *Context) getManagedFolder(id uint) (*model.Folder, error) {
+	folder, err := ctx.GetFolderByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.canManage(folder); err != nil {
+		return nil, err
+	}
+	return folder, nil
+}
+
+func (ctx *Context) getShare(folderID, userID uint) (*model.Share, error) {
+	share, err := ctx.Database.GetShare(folderID, userID)
+	if err != nil {
+		if db.IsNotFound(err) {
+			return nil, ctx.NotFoundError("share")
+		}
+		return nil, err
+	}
+	return share, nil
+}
+
+func validateShareAccess(access string) *ValidationError {
+	if !model.ValidAccess(access) {
+		return &ValidationError{"access must be one of " + model.AccessRead + ", " + model.AccessEdit}
+	}
+	return nil
+}
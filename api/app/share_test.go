@@ -0,0 +1,161 @@
+package app
+
+import (
+	"net/http"
+	"testing"
+
+	"leggett.dev/devmarks/api/model"
+)
+
+func TestShareDoesNotGrantWriteToAddedBookmarks(t *testing.T) {
+	for _, access := range []string{model.AccessRead, model.AccessEdit} {
+		t.Run(access, func(t *testing.T) {
+			a := newTestApp(t)
+			alice := signedIn(t, a, "alice@example.com")
+			victor := signedIn(t, a, "victor@example.com")
+			mallory := signedIn(t, a, "mallory@example.com")
+
+			shared := createFolder(t, alice, &model.Folder{Name: "Shared"})
+			bookmark := createBookmark(t, alice, "https://golang.org", shared)
+			if _, err := alice.ShareFolder(shared.ID, "victor@example.com", access); err != nil {
+				t.Fatal(err)
+			}
+
+			// victor cannot put alice's bookmark in a folder of his that he shares at
+			// edit, which would let everyone he shares it with change the bookmark, nor
+			// publish it.
+			own := createFolder(t, victor, &model.Folder{Name: "Mine"})
+			if _, err := victor.ShareFolder(own.ID, "mallory@example.com", model.AccessEdit); err != nil {
+				t.Fatal(err)
+			}
+			if err := victor.AddBookmarkToFolder(own.ID, bookmark.ID); !isStatus(err, http.StatusForbidden) {
+				t.Errorf("AddBookmarkToFolder() error = %v, want a forbidden error", err)
+			}
+			if ok, err := a.Policy.CanWrite(mallory.User, bookmark); err != nil || ok {
+				t.Errorf("CanWrite() = %v, %v, want the bookmark to stay out of reach of mallory", ok, err)
+			}
+		})
+	}
+}
+
+func TestShareGrantsAccessToBookmarks(t *testing.T) {
+	a := newTestApp(t)
+	alice := signedIn(t, a, "alice@example.com")
+	reader := signedIn(t, a, "reader@example.com")
+	editor := signedIn(t, a, "editor@example.com")
+	stranger := signedIn(t, a, "stranger@example.com")
+
+	shared := createFolder(t, alice, &model.Folder{Name: "Shared"})
+	nested := createFolder(t, alice, &model.Folder{Name: "Nested", ParentID: &shared.ID})
+	bookmark := createBookmark(t, alice, "https://golang.org", nested)
+	for email, access := range map[string]string{"reader@example.com": model.AccessRead, "editor@example.com": model.AccessEdit} {
+		if _, err := alice.ShareFolder(shared.ID, email, access); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		ctx       *Context
+		read      bool
+		write     bool
+		canManage bool
+	}{
+		{alice, true, true, true},
+		{reader, true, false, false},
+		{editor, true, true, false},
+		{stranger, false, false, false},
+	}
+	for _, test := range tests {
+		for _, resource := range []interface{}{nested, bookmark} {
+			read, _ := a.Policy.CanRead(test.ctx.User, resource)
+			write, _ := a.Policy.CanWrite(test.ctx.User, resource)
+			manage, _ := a.Policy.CanManage(test.ctx.User, resource)
+			if read != test.read || write != test.write || manage != test.canManage {
+				t.Errorf("%s on %T: read, write, manage = %v, %v, %v, want %v, %v, %v",
+					test.ctx.User.Email, resource, read, write, manage, test.read, test.write, test.canManage)
+			}
+		}
+	}
+
+	// only the owner can share the folder further.
+	if _, err := editor.ShareFolder(shared.ID, "stranger@example.com", model.AccessRead); !isStatus(err, http.StatusForbidden) {
+		t.Errorf("editor ShareFolder() error = %v, want a forbidden error", err)
+	}
+	if err := alice.Unshare(shared.ID, reader.User.ID); err != nil {
+		t.Fatal(err)
+	}
+	if read, _ := a.Policy.CanRead(reader.User, bookmark); read {
+		t.Error("CanRead() = true after unsharing, want false")
+	}
+}
+
+func TestShareFolderManagement(t *testing.T) {
+	a := newTestApp(t)
+	alice := signedIn(t, a, "alice@example.com")
+	victor := signedIn(t, a, "victor@example.com")
+	mallory := signedIn(t, a, "mallory@example.com")
+	shared := createFolder(t, alice, &model.Folder{Name: "Shared"})
+
+	if _, err := alice.ShareFolder(shared.ID, "victor@example.com", "admin"); !isValidationError(err) {
+		t.Errorf("sharing with an unknown access: err = %v, want a validation error", err)
+	}
+	if _, err := alice.ShareFolder(shared.ID, "alice@example.com", model.AccessRead); !isValidationError(err) {
+		t.Errorf("sharing with the owner: err = %v, want a validation error", err)
+	}
+	if _, err := alice.ShareFolder(shared.ID, "missing@example.com", model.AccessRead); !isStatus(err, http.StatusNotFound) {
+		t.Errorf("sharing with a missing user: err = %v, want not found", err)
+	}
+	if _, err := alice.ShareFolder(shared.ID, "victor@example.com", model.AccessRead); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := alice.ShareFolder(shared.ID, "victor@example.com", model.AccessEdit); !isStatus(err, http.StatusConflict) {
+		t.Errorf("sharing twice: err = %v, want a conflict", err)
+	}
+	if _, err := alice.ShareFolder(shared.ID, "mallory@example.com", model.AccessRead); err != nil {
+		t.Fatal(err)
+	}
+
+	shares, err := victor.GetSharedFolders()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shares) != 1 || shares[0].FolderID != shared.ID || shares[0].Access != model.AccessRead {
+		t.Errorf("shared folders = %+v, want the folder at read access", shares)
+	}
+	if _, err := victor.GetShares(shared.ID); !isStatus(err, http.StatusForbidden) {
+		t.Errorf("listing the shares of a folder shared with the user: err = %v, want forbidden", err)
+	}
+
+	// the owner changes the access; only then can victor change the folder.
+	if write, _ := a.Policy.CanWrite(victor.User, shared); write {
+		t.Error("CanWrite() = true with read access")
+	}
+	if _, err := victor.UpdateShare(shared.ID, victor.User.ID, model.AccessEdit); !isStatus(err, http.StatusForbidden) {
+		t.Errorf("raising one's own access: err = %v, want forbidden", err)
+	}
+	if _, err := alice.UpdateShare(shared.ID, victor.User.ID, model.AccessEdit); err != nil {
+		t.Fatal(err)
+	}
+	if write, _ := a.Policy.CanWrite(victor.User, shared); !write {
+		t.Error("CanWrite() = false with edit access")
+	}
+
+	// users give up their own shares, not those of others.
+	if err := victor.Unshare(shared.ID, mallory.User.ID); !isStatus(err, http.StatusForbidden) {
+		t.Errorf("removing the share of another user: err = %v, want forbidden", err)
+	}
+	if err := victor.Unshare(shared.ID, victor.User.ID); err != nil {
+		t.Errorf("giving up a share: %v", err)
+	}
+	if err := victor.Unshare(shared.ID, victor.User.ID); !isStatus(err, http.StatusNotFound) {
+		t.Errorf("giving up a share twice: err = %v, want not found", err)
+	}
+
+	shares, err = alice.GetShares(shared.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shares) != 1 || shares[0].UserID != mallory.User.ID {
+		t.Errorf("shares = %+v, want only mallory's", shares)
+	}
+}
@@ -0,0 +1,138 @@
+package app
+
+import (
+	"net/http"
+	"strings"
+
+	"leggett.dev/devmarks/api/db"
+	"leggett.dev/devmarks/api/model"
+)
+
+const maxTagNameLength = 50
+
+// GetTagByID returns a Tag model from the tag's ID, if the currently authenticated
+// user can see it.
+func (ctx *Context) GetTagByID(id uint) (*model.Tag, error) {
+	if ctx.User == nil {
+		return nil, ctx.AuthorizationError()
+	}
+
+	tag, err := ctx.Database.GetTagByID(ctx, id)
+	if err != nil {
+		if db.IsNotFound(err) {
+			return nil, ctx.NotFoundError("tag")
+		}
+		return nil, err
+	}
+
+	if err := ctx.canRead(tag); err != nil {
+		return nil, err
+	}
+
+	return tag, nil
+}
+
+// GetUserTags returns all the tags owned by the currently authenticated User
+func (ctx *Context) GetUserTags() ([]*model.Tag, error) {
+	if ctx.User == nil {
+		return nil, ctx.AuthorizationError()
+	}
+
+	return ctx.Database.GetTagsByUserID(ctx, ctx.User.ID)
+}
+
+// CreateTag performs the business logic necessary to create and validate
+// a Tag given an initial instance of one
+func (ctx *Context) CreateTag(tag *model.Tag) error {
+	if ctx.User == nil {
+		return ctx.AuthorizationError()
+	}
+
+	tag.OwnerID = ctx.User.ID
+
+	name, err := validateTagName(tag.Name)
+	if err != nil {
+		return err
+	}
+	tag.Name = name
+
+	return ctx.duplicateTagError(ctx.Database.CreateTag(tag))
+}
+
+// UpdateTag performs the business logic necessary to validate and update
+// a given tag model
+func (ctx *Context) UpdateTag(tag *model.Tag) error {
+	if ctx.User == nil {
+		return ctx.AuthorizationError()
+	}
+
+	if err := ctx.canWrite(tag); err != nil {
+		return err
+	}
+
+	name, err := validateTagName(tag.Name)
+	if err != nil {
+		return err
+	}
+	tag.Name = name
+
+	return ctx.duplicateTagError(ctx.Database.UpdateTag(tag))
+}
+
+// DeleteTagByID performs the necessary business logic to delete a tag
+// owned by the currently authenticated user. Bookmarks carrying the
+// tag are kept.
+func (ctx *Context) DeleteTagByID(id uint) error {
+	if ctx.User == nil {
+		return ctx.AuthorizationError()
+	}
+
+	tag, err := ctx.GetTagByID(id)
+	if err != nil {
+		return err
+	}
+
+	if err := ctx.canManage(tag); err != nil {
+		return err
+	}
+
+	return ctx.Database.DeleteTagByID(id)
+}
+
+func (ctx *Context) duplicateTagError(err error) error {
+	if err == db.ErrDuplicateTag {
+		return &UserError{Message: err.Error(), StatusCode: http.StatusConflict}
+	}
+	return err
+}
+
+// validateTagName trims the given tag name and returns it, or an error
+// describing why it is invalid.
+func validateTagName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return name, &ValidationError{"name is required"}
+	}
+	if len(name) > maxTagNameLength {
+		return name, &ValidationError{"tag name is too long"}
+	}
+	return name, nil
+}
+
+// normalizeTagNames validates a list of tag names, dropping duplicates.
+func normalizeTagNames(names []string) ([]string, error) {
+	normalized := make([]string, 0, len(names))
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name, err := validateTagName(name)
+		if err != nil {
+			return nil, err
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		normalized = append(normalized, name)
+	}
+	return normalized, nil
+}
@@ -0,0 +1,145 @@
+package app
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"leggett.dev/devmarks/api/db"
+	"leggett.dev/devmarks/api/model"
+)
+
+const (
+	defaultTokenName   = "login"
+	maxTokenNameLength = 100
+	// tokenTouchInterval limits how often the last used time of a token is written, so
+	// a busy client does not cause a write on every request.
+	tokenTouchInterval = time.Minute
+)
+
+// ErrInvalidToken is returned when a token does not exist, was revoked or has expired.
+var ErrInvalidToken = &UserError{Message: "invalid token", StatusCode: http.StatusUnauthorized}
+
+// CreateToken issues a new API token for the user, valid for the configured token
+// lifetime. The returned secret is the only time the token itself is available.
+func (a *App) CreateToken(user *model.User, name string) (string, *model.Token, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = defaultTokenName
+	}
+	if len(name) > maxTokenNameLength {
+		return "", nil, &ValidationError{"token name is too long"}
+	}
+
+	expiresAt := time.Now().Add(a.Config.TokenLifetime)
+	secret, token := model.NewToken(name, user.ID, &expiresAt)
+	if err := a.Database.CreateToken(token); err != nil {
+		return "", nil, err
+	}
+	return secret, token, nil
+}
+
+// CreatePersonalToken issues a personal access token for the currently authenticated
+// user, limited to the given scopes. Personal access tokens never expire unless
+// expiresAt is given. The returned secret is the only time the token itself is
+// available.
+func (ctx *Context) CreatePersonalToken(name string, scopes []string, expiresAt *time.Time) (string, *model.Token, error) {
+	if ctx.User == nil {
+		return "", nil, ctx.AuthorizationError()
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, &ValidationError{"name is required"}
+	}
+	if len(name) > maxTokenNameLength {
+		return "", nil, &ValidationError{"token name is too long"}
+	}
+	if len(scopes) == 0 {
+		return "", nil, &ValidationError{"scopes are required"}
+	}
+	for _, scope := range scopes {
+		if !isValidScope(scope) {
+			return "", nil, &ValidationError{"unknown scope " + scope + ", must be one of " + strings.Join(model.ValidScopes(), ", ")}
+		}
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return "", nil, &ValidationError{"expires_at must be in the future"}
+	}
+
+	secret, token := model.NewToken(name, ctx.User.ID, expiresAt)
+	token.Scopes = scopes
+	if err := ctx.Database.CreateToken(token); err != nil {
+		return "", nil, err
+	}
+	return secret, token, nil
+}
+
+// AuthenticateToken returns the token matching the given secret, with its user
+// preloaded, and records that it was used. ErrInvalidToken is returned if the token
+// cannot be used.
+func (a *App) AuthenticateToken(secret string) (*model.Token, error) {
+	token, err := a.Database.GetTokenByHash(model.HashToken(secret))
+	if err != nil {
+		if db.IsNotFound(err) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	if token.Expired() || token.User == nil {
+		return nil, ErrInvalidToken
+	}
+
+	now := time.Now()
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > tokenTouchInterval {
+		if err := a.Database.TouchToken(token.ID, now); err != nil {
+			return nil, err
+		}
+		token.LastUsedAt = &now
+	}
+	return token, nil
+}
+
+// GetUserTokens returns the tokens of the currently authenticated User
+func (ctx *Context) GetUserTokens() ([]*model.Token, error) {
+	if ctx.User == nil {
+		return nil, ctx.AuthorizationError()
+	}
+
+	return ctx.Database.GetTokensByUserID(ctx.User.ID)
+}
+
+// RevokeTokenByID revokes the token with the specified ID if it belongs to the
+// currently authenticated user, so it can no longer be used.
+func (ctx *Context) RevokeTokenByID(id uint) error {
+	if ctx.User == nil {
+		return ctx.AuthorizationError()
+	}
+
+	token, err := ctx.Database.GetTokenByID(id)
+	if err != nil {
+		if db.IsNotFound(err) {
+			return ctx.NotFoundError("token")
+		}
+		return err
+	}
+
+	// other users' tokens are reported as missing rather than forbidden, so token IDs
+	// cannot be probed.
+	if ok, err := ctx.Policy.CanManage(ctx.User, token); err != nil {
+		return err
+	} else if !ok {
+		return ctx.NotFoundError("token")
+	}
+
+	return ctx.Database.DeleteTokenByID(id)
+}
+
+func isValidScope(scope string) bool {
+	for _, valid := range model.ValidScopes() {
+		if scope == valid {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package app
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"leggett.dev/devmarks/api/model"
+)
+
+func TestAuthenticateToken(t *testing.T) {
+	a := newTestApp(t)
+	a.Config.TokenLifetime = time.Hour
+	ctx := signedIn(t, a, "alice@example.com")
+	victor := signedIn(t, a, "victor@example.com")
+
+	secret, token, err := a.CreateToken(ctx.User, "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.Name != defaultTokenName || token.ExpiresAt == nil {
+		t.Errorf("token = %+v, want a login token expiring in an hour", token)
+	}
+	if token.Hash == secret {
+		t.Error("the token is stored in the clear")
+	}
+
+	authenticated, err := a.AuthenticateToken(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if authenticated.ID != token.ID || authenticated.User == nil || authenticated.User.ID != ctx.User.ID {
+		t.Errorf("authenticated token = %+v, want token %d of user %d", authenticated, token.ID, ctx.User.ID)
+	}
+	tokens, err := ctx.GetUserTokens()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 1 || tokens[0].LastUsedAt == nil {
+		t.Errorf("tokens = %+v, want the token with the time it was last used", tokens)
+	}
+
+	if _, err := a.AuthenticateToken(secret + "x"); err != ErrInvalidToken {
+		t.Errorf("unknown token: err = %v, want %v", err, ErrInvalidToken)
+	}
+
+	// other users cannot tell the token exists.
+	if err := victor.RevokeTokenByID(token.ID); !isStatus(err, http.StatusNotFound) {
+		t.Errorf("revoking the token of another user: err = %v, want not found", err)
+	}
+	if _, err := a.AuthenticateToken(secret); err != nil {
+		t.Errorf("token revoked by another user: %v", err)
+	}
+	if err := ctx.RevokeTokenByID(token.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.AuthenticateToken(secret); err != ErrInvalidToken {
+		t.Errorf("revoked token: err = %v, want %v", err, ErrInvalidToken)
+	}
+	if err := ctx.RevokeTokenByID(token.ID); !isStatus(err, http.StatusNotFound) {
+		t.Errorf("revoking the token again: err = %v, want not found", err)
+	}
+}
+
+func TestAuthenticateTokenExpired(t *testing.T) {
+	a := newTestApp(t)
+	a.Config.TokenLifetime = time.Hour
+	ctx := signedIn(t, a, "alice@example.com")
+
+	secret, token, err := a.CreateToken(ctx.User, "script")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Database.Model(token).UpdateColumn("expires_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.AuthenticateToken(secret); err != ErrInvalidToken {
+		t.Errorf("expired token: err = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestCreatePersonalToken(t *testing.T) {
+	a := newTestApp(t)
+	a.Config.TokenLifetime = time.Hour
+	ctx := signedIn(t, a, "alice@example.com")
+	past := time.Now().Add(-time.Minute)
+
+	invalid := []struct {
+		name      string
+		scopes    []string
+		expiresAt *time.Time
+	}{
+		{"", []string{model.ScopeBookmarksRead}, nil},
+		{strings.Repeat("a", maxTokenNameLength+1), []string{model.ScopeBookmarksRead}, nil},
+		{"cli", nil, nil},
+		{"cli", []string{model.ScopeBookmarksRead, "bookmarks:delete"}, nil},
+		{"cli", []string{model.ScopeBookmarksRead}, &past},
+	}
+	for _, test := range invalid {
+		if _, _, err := ctx.CreatePersonalToken(test.name, test.scopes, test.expiresAt); !isValidationError(err) {
+			t.Errorf("CreatePersonalToken(%q, %v, %v): err = %v, want a validation error", test.name, test.scopes, test.expiresAt, err)
+		}
+	}
+	if _, _, err := a.NewContext().CreatePersonalToken("cli", []string{model.ScopeAdmin}, nil); !isStatus(err, http.StatusForbidden) {
+		t.Errorf("CreatePersonalToken() without a user: err = %v, want forbidden", err)
+	}
+
+	secret, token, err := ctx.CreatePersonalToken(" cli ", []string{model.ScopeBookmarksRead, model.ScopeFoldersWrite}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.Name != "cli" || token.ExpiresAt != nil {
+		t.Errorf("token = %+v, want cli without an expiry", token)
+	}
+
+	authenticated, err := a.AuthenticateToken(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !authenticated.Personal() || !reflect.DeepEqual([]string(authenticated.Scopes), []string{model.ScopeBookmarksRead, model.ScopeFoldersWrite}) {
+		t.Errorf("authenticated token scopes = %v, want the scopes it was created with", authenticated.Scopes)
+	}
+
+	// login tokens are not limited to scopes.
+	loginSecret, _, err := a.CreateToken(ctx.User, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if login, err := a.AuthenticateToken(loginSecret); err != nil || login.Personal() {
+		t.Errorf("login token = %+v, %v, want a token without scopes", login, err)
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"strings"
 
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 
+	"leggett.dev/devmarks/api/db"
 	"leggett.dev/devmarks/api/model"
 )
 
@@ -14,8 +16,12 @@ func (a *App) GetUserByEmail(email string) (*model.User, error) {
 }
 
 // CreateUser performs the business logic necessary to create and validate a new
-// User, returning an error if validation fails or a password cannot be set.
-func (a *App) CreateUser(user *model.User, password string) error {
+// User, returning an error if validation fails or a password cannot be set. When
+// registration is invite-only, inviteCode must be a valid invite, which is used once.
+func (a *App) CreateUser(user *model.User, password, inviteCode string) error {
+	if err := a.checkRegistration(inviteCode); err != nil {
+		return err
+	}
 	if err := a.validateUser(user, password); err != nil {
 		return err
 	}
@@ -24,18 +30,45 @@ func (a *App) CreateUser(user *model.User, password string) error {
 		return errors.Wrap(err, "unable to set user password")
 	}
 
-	return a.Database.CreateUser(user)
+	err := a.Database.WithTransaction(func(tx *db.Database) error {
+		if a.Config.Registration == RegistrationInvite {
+			invite, err := redeemInvite(tx, inviteCode)
+			if err != nil {
+				return err
+			}
+			user.InviteID = &invite.ID
+		}
+		return tx.CreateUser(user)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := a.SendEmailVerification(user); err != nil {
+		logrus.WithError(err).WithField("user_id", user.ID).Error("unable to send email verification")
+	}
+	return nil
 }
 
 func (a *App) validateUser(user *model.User, password string) *ValidationError {
-	// naive email validation
-	if !strings.Contains(user.Email, "@") {
-		return &ValidationError{"invalid email"}
+	if err := validateEmail(user.Email); err != nil {
+		return err
 	}
 
+	return validatePassword(password)
+}
+
+func validatePassword(password string) *ValidationError {
 	if password == "" {
 		return &ValidationError{"password is required"}
 	}
+	return nil
+}
 
+func validateEmail(email string) *ValidationError {
+	// naive email validation
+	if !strings.Contains(email, "@") {
+		return &ValidationError{"invalid email"}
+	}
 	return nil
 }
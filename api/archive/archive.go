@@ -0,0 +1,154 @@
+// Package archive reads and writes devmarks account archives: versioned JSON documents
+// holding everything a user owns, used to move an account between devmarks instances.
+package archive
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Version is the version of the archive format written by Write. Read accepts archives
+// of this version and older.
+const Version = 1
+
+// Archive holds a user's folders, tags and bookmarks. The IDs in an archive are those of
+// the instance it was exported from; they only serve to link its entries together.
+type Archive struct {
+	Version    int        `json:"version"`
+	ExportedAt time.Time  `json:"exported_at"`
+	Folders    []Folder   `json:"folders"`
+	Tags       []Tag      `json:"tags"`
+	Bookmarks  []Bookmark `json:"bookmarks"`
+}
+
+// Folder is a folder in an archive. ParentID refers to another folder of the archive.
+type Folder struct {
+	ID        uint      `json:"id"`
+	ParentID  *uint     `json:"parent_id"`
+	Name      string    `json:"name"`
+	Color     string    `json:"color"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Tag is a tag in an archive.
+type Tag struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Bookmark is a bookmark in an archive. FolderIDs and TagIDs refer to the folders it is
+// in and the tags it has.
+type Bookmark struct {
+	ID        uint       `json:"id"`
+	Name      string     `json:"name"`
+	URL       string     `json:"url"`
+	Color     *string    `json:"color"`
+	Notes     string     `json:"notes"`
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	FolderIDs []uint     `json:"folder_ids"`
+	TagIDs    []uint     `json:"tag_ids"`
+}
+
+// Write encodes the archive to w, setting its version.
+func Write(w io.Writer, a *Archive) error {
+	a.Version = Version
+	return json.NewEncoder(w).Encode(a)
+}
+
+// Read decodes an archive from r and checks that it is complete: that it is of a
+// supported version, and that every ID it refers to is in it.
+func Read(r io.Reader) (*Archive, error) {
+	var a Archive
+	if err := json.NewDecoder(r).Decode(&a); err != nil {
+		return nil, fmt.Errorf("invalid archive: %v", err)
+	}
+	if a.Version < 1 || a.Version > Version {
+		return nil, fmt.Errorf("unsupported archive version %d", a.Version)
+	}
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// Validate checks that every ID the archive refers to is in it, and that its folders
+// form a hierarchy without cycles.
+func (a *Archive) Validate() error {
+	folders := make(map[uint]*Folder, len(a.Folders))
+	for i := range a.Folders {
+		f := &a.Folders[i]
+		if _, ok := folders[f.ID]; ok {
+			return fmt.Errorf("duplicate folder id %d", f.ID)
+		}
+		folders[f.ID] = f
+	}
+	for _, f := range a.Folders {
+		// following the parents of a folder must end at the top level within as many
+		// steps as there are folders, or they form a cycle.
+		parent := f.ParentID
+		for steps := 0; parent != nil; steps++ {
+			p, ok := folders[*parent]
+			if !ok {
+				return fmt.Errorf("folder %d has unknown parent %d", f.ID, *parent)
+			}
+			if steps == len(folders) {
+				return fmt.Errorf("folder %d is nested inside itself", f.ID)
+			}
+			parent = p.ParentID
+		}
+	}
+
+	tags := make(map[uint]bool, len(a.Tags))
+	for _, t := range a.Tags {
+		if tags[t.ID] {
+			return fmt.Errorf("duplicate tag id %d", t.ID)
+		}
+		tags[t.ID] = true
+	}
+
+	bookmarks := make(map[uint]bool, len(a.Bookmarks))
+	for _, b := range a.Bookmarks {
+		if bookmarks[b.ID] {
+			return fmt.Errorf("duplicate bookmark id %d", b.ID)
+		}
+		bookmarks[b.ID] = true
+		for _, id := range b.FolderIDs {
+			if _, ok := folders[id]; !ok {
+				return fmt.Errorf("bookmark %d is in unknown folder %d", b.ID, id)
+			}
+		}
+		for _, id := range b.TagIDs {
+			if !tags[id] {
+				return fmt.Errorf("bookmark %d has unknown tag %d", b.ID, id)
+			}
+		}
+	}
+	return nil
+}
+
+// SortedFolders returns the folders of the archive ordered so that every folder comes
+// after its parent.
+func (a *Archive) SortedFolders() []Folder {
+	sorted := make([]Folder, 0, len(a.Folders))
+	placed := make(map[uint]bool, len(a.Folders))
+	for len(sorted) < len(a.Folders) {
+		progress := false
+		for _, f := range a.Folders {
+			if placed[f.ID] || (f.ParentID != nil && !placed[*f.ParentID]) {
+				continue
+			}
+			sorted = append(sorted, f)
+			placed[f.ID] = true
+			progress = true
+		}
+		// validated archives always make progress; this only guards against others.
+		if !progress {
+			break
+		}
+	}
+	return sorted
+}
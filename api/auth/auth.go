@@ -2,12 +2,10 @@ package auth
 
 import (
 	"context"
-	"errors"
-	"fmt"
 	"net/http"
+	"path"
+	"strings"
 
-	"github.com/dgrijalva/jwt-go"
-	"github.com/shaj13/go-guardian/auth/strategies/token"
 	"leggett.dev/devmarks/api/app"
 	"leggett.dev/devmarks/api/log"
 	"leggett.dev/devmarks/api/model"
@@ -21,19 +19,27 @@ type authSvc struct {
 	Logger *log.Logger
 	App app.App
 	ExemptPaths *[]string
+	ScopeRules []ScopeRule
 }
 
-func NewAuth(exemptPaths *[]string, app app.App, logger *log.Logger) AuthService{
+func NewAuth(exemptPaths *[]string, scopeRules []ScopeRule, app app.App, logger *log.Logger) AuthService{
 	return &authSvc{
 		App: app,
 		ExemptPaths: exemptPaths,
+		ScopeRules: scopeRules,
 		Logger: logger,
 	}
 }
 
-func contains(s string, array []string) bool {
-	for _, b := range array {
-		if b == s {
+// isExempt returns true if the path needs no authentication. Exempt paths ending in a
+// slash exempt every path below them, and the others are patterns as understood by
+// path.Match, where * stands for a single path segment.
+func isExempt(p string, exemptPaths []string) bool {
+	for _, exempt := range exemptPaths {
+		if strings.HasSuffix(exempt, "/") && strings.HasPrefix(p, exempt) {
+			return true
+		}
+		if ok, _ := path.Match(exempt, p); ok {
 			return true
 		}
 	}
@@ -43,9 +49,8 @@ func contains(s string, array []string) bool {
 func (a *authSvc) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
-		if !(contains(r.URL.Path, *a.ExemptPaths)) {
-			tokenStrategy := a.App.Authenticator.Strategy(token.CachedStrategyKey)
-			userInfo, err := tokenStrategy.Authenticate(r.Context(), r)
+		if !isExempt(r.URL.Path, *a.ExemptPaths) {
+			userInfo, err := a.App.Authenticator.Authenticate(r)
 			if err != nil {
 				if a.Logger != nil {
 					logger := *a.Logger
@@ -67,7 +72,14 @@ func (a *authSvc) AuthMiddleware(next http.Handler) http.Handler {
 				return
 			}
 
+			if scopes, ok := userInfo.Extensions()[scopesExtension]; ok && !a.hasRequiredScope(r, scopes) {
+				http.Error(w, "insufficient scope", http.StatusForbidden)
+				return
+			}
+
 			setUserInCtx(&ctx, user)
+			ctx = context.WithValue(ctx, tokenIDKey, tokenIDFromInfo(userInfo))
+			ctx = context.WithValue(ctx, sessionKey, sessionFromInfo(userInfo))
 		}
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
@@ -75,48 +87,28 @@ func (a *authSvc) AuthMiddleware(next http.Handler) http.Handler {
 
 type contextKey struct { key string }
 var userKey = &contextKey{"user"}
+var tokenIDKey = &contextKey{"token_id"}
+var sessionKey = &contextKey{"session"}
 
 func setUserInCtx(ctx *context.Context, user *model.User) {
 	*ctx = context.WithValue(*ctx, userKey, user)
 }
 
 func GetUser(ctx context.Context) *model.User {
-	return ctx.Value(userKey).(*model.User)
+	user, _ := ctx.Value(userKey).(*model.User)
+	return user
 }
 
-func AuthenticateToken(ctx context.Context, app app.App) (*model.User, error) {
-	var token, ok = ctx.Value("token").(string)
-	if !ok {
-		return nil, errors.New("bookmarks: no authenticated user in context")
-	}
-
-	user, err := getUserFromToken(token, app)
-	if err != nil {
-		return nil, err
-	}
-
-	return user, nil
+// GetTokenID returns the ID of the API token the current request was authenticated
+// with, or 0 if there is none.
+func GetTokenID(ctx context.Context) uint {
+	id, _ := ctx.Value(tokenIDKey).(uint)
+	return id
 }
 
-func getUserFromToken(tokenString string, app app.App) (*model.User, error) {
-	// decode token with the secret if was encoded with
-	tokenObj, err := jwt.ParseWithClaims(tokenString, jwt.MapClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return app.Config.SecretKey, nil
-	})
-	if err != nil {
-		return nil, err
-	}
-	// get user ID from the map we encoded in the token
-	userID, ok := tokenObj.Claims.(jwt.MapClaims)["ID"].(float64)
-	if !ok {
-		return nil, errors.New("GetUserIDFromToken error: type conversion in claims")
-	}
-
-	user, err := app.Database.GetUserById(uint(userID))
-
-	if err != nil {
-		return nil, errors.New("No user with ID " + fmt.Sprint(userID))
-	}
-
-	return user, nil
-}
\ No newline at end of file
+// GetSession returns the login session of the JWT access token the current request
+// was authenticated with, or an empty string if there is none.
+func GetSession(ctx context.Context) string {
+	session, _ := ctx.Value(sessionKey).(string)
+	return session
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/shaj13/go-guardian/auth"
+	"github.com/shaj13/go-guardian/auth/strategies/token"
+	"leggett.dev/devmarks/api/app"
+	"leggett.dev/devmarks/api/model"
+)
+
+// JWTStrategyKey identifies the strategy authenticating requests with the signed JWT
+// access tokens handed out on login.
+const JWTStrategyKey = auth.StrategyKey("Devmarks.JWT.Strategy")
+
+// sessionExtension is the key of the auth.Info extension holding the login session a
+// JWT access token belongs to.
+const sessionExtension = "session"
+
+type jwtStrategy struct {
+	parser token.Parser
+	app    *app.App
+}
+
+// NewJWTStrategy returns a strategy authenticating bearer JWT access tokens signed with
+// the secret key of the app. Their signature and expiry are checked before the user
+// they were issued to is loaded from the database, so the tokens of deleted users stop
+// working; revoking their login session does not end them before they expire.
+func NewJWTStrategy(a *app.App) auth.Strategy {
+	return &jwtStrategy{
+		parser: token.AuthorizationParser(string(token.Bearer)),
+		app:    a,
+	}
+}
+
+func (s *jwtStrategy) Authenticate(ctx context.Context, r *http.Request) (auth.Info, error) {
+	signed, err := s.parser.Token(r)
+	if err != nil {
+		return nil, err
+	}
+	if model.LooksLikeToken(signed) {
+		return nil, errors.New("not a JWT access token")
+	}
+
+	claims, err := s.app.ParseAccessToken(signed)
+	if err != nil {
+		return nil, err
+	}
+	id, err := strconv.ParseUint(claims.Subject, 10, 0)
+	if err != nil {
+		return nil, errors.New("invalid subject in access token")
+	}
+	user, err := s.app.Database.GetUserById(uint(id))
+	if err != nil {
+		return nil, err
+	}
+
+	extensions := map[string][]string{sessionExtension: {claims.Session}}
+	return auth.NewDefaultUser(user.Email, claims.Subject, nil, extensions), nil
+}
+
+// sessionFromInfo returns the login session of the JWT access token the user was
+// authenticated with, or an empty string.
+func sessionFromInfo(info auth.Info) string {
+	values := info.Extensions()[sessionExtension]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
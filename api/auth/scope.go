@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+
+	"leggett.dev/devmarks/api/model"
+)
+
+// ScopeRule sets the scope personal access tokens need to call the routes under
+// Prefix: Read for GET and HEAD requests, Write for every other method. An empty
+// scope lets any token call them. Routes no rule covers need the admin scope.
+type ScopeRule struct {
+	Prefix string
+	Read   string
+	Write  string
+}
+
+// requiredScope returns the scope a personal access token needs for the request,
+// using the rule with the longest matching prefix.
+func (a *authSvc) requiredScope(r *http.Request) string {
+	var match *ScopeRule
+	for i, rule := range a.ScopeRules {
+		if r.URL.Path != rule.Prefix && !strings.HasPrefix(r.URL.Path, rule.Prefix+"/") {
+			continue
+		}
+		if match == nil || len(rule.Prefix) > len(match.Prefix) {
+			match = &a.ScopeRules[i]
+		}
+	}
+	if match == nil {
+		return model.ScopeAdmin
+	}
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return match.Read
+	}
+	return match.Write
+}
+
+// hasRequiredScope returns true if the scopes, taken from a personal access token,
+// allow the request.
+func (a *authSvc) hasRequiredScope(r *http.Request, scopes []string) bool {
+	required := a.requiredScope(r)
+	return required == "" || model.ScopesAllow(scopes, required)
+}
@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"leggett.dev/devmarks/api/model"
+)
+
+func TestHasRequiredScope(t *testing.T) {
+	a := &authSvc{ScopeRules: []ScopeRule{
+		{Prefix: "/auth/logout"},
+		{Prefix: "/me", Write: model.ScopeAdmin},
+		{Prefix: "/me/export", Read: model.ScopeBookmarksRead, Write: model.ScopeBookmarksRead},
+		{Prefix: "/bookmarks", Read: model.ScopeBookmarksRead, Write: model.ScopeBookmarksWrite},
+		{Prefix: "/folders", Read: model.ScopeBookmarksRead, Write: model.ScopeFoldersWrite},
+	}}
+
+	tests := []struct {
+		method string
+		path   string
+		scopes []string
+		allow  bool
+	}{
+		{"GET", "/bookmarks", []string{model.ScopeBookmarksRead}, true},
+		{"HEAD", "/bookmarks/1", []string{model.ScopeBookmarksRead}, true},
+		{"POST", "/bookmarks", []string{model.ScopeBookmarksRead}, false},
+		{"POST", "/bookmarks", []string{model.ScopeBookmarksWrite}, true},
+		{"DELETE", "/bookmarks/1", []string{model.ScopeFoldersWrite}, false},
+		// broader scopes grant the narrower ones.
+		{"GET", "/bookmarks", []string{model.ScopeBookmarksWrite}, true},
+		{"GET", "/bookmarks", []string{model.ScopeFoldersWrite}, true},
+		{"PUT", "/folders/1", []string{model.ScopeAdmin}, true},
+		// the rule with the longest prefix wins, and prefixes match whole segments.
+		{"GET", "/me", []string{model.ScopeBookmarksRead}, true},
+		{"PUT", "/me", []string{model.ScopeBookmarksWrite}, false},
+		{"POST", "/me/export", []string{model.ScopeBookmarksRead}, true},
+		{"GET", "/bookmarksx", []string{model.ScopeBookmarksRead}, false},
+		// routes without a rule need the admin scope, and empty scopes need none.
+		{"GET", "/admin/users", []string{model.ScopeBookmarksWrite}, false},
+		{"GET", "/admin/users", []string{model.ScopeAdmin}, true},
+		{"POST", "/auth/logout", []string{model.ScopeBookmarksRead}, true},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(test.method, test.path, nil)
+		if got := a.hasRequiredScope(r, test.scopes); got != test.allow {
+			t.Errorf("%s %s with %v: allowed = %v, want %v", test.method, test.path, test.scopes, got, test.allow)
+		}
+	}
+}
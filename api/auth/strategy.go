@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/shaj13/go-guardian/auth"
+	"github.com/shaj13/go-guardian/auth/strategies/token"
+	"leggett.dev/devmarks/api/app"
+	"leggett.dev/devmarks/api/model"
+)
+
+// TokenStrategyKey identifies the strategy authenticating requests with the API tokens
+// stored in the database.
+const TokenStrategyKey = auth.StrategyKey("Devmarks.Token.Strategy")
+
+// tokenIDExtension is the key of the auth.Info extension holding the ID of the token
+// a request was authenticated with.
+const tokenIDExtension = "token_id"
+
+// scopesExtension is the key of the auth.Info extension holding the scopes of the
+// personal access token a request was authenticated with. It is missing for tokens
+// that are not limited to scopes.
+const scopesExtension = "scopes"
+
+type tokenStrategy struct {
+	parser token.Parser
+	app    *app.App
+}
+
+// NewTokenStrategy returns a strategy authenticating bearer tokens against the tokens
+// table, so tokens survive restarts and can be revoked.
+func NewTokenStrategy(a *app.App) auth.Strategy {
+	return &tokenStrategy{
+		parser: token.AuthorizationParser(string(token.Bearer)),
+		app:    a,
+	}
+}
+
+func (s *tokenStrategy) Authenticate(ctx context.Context, r *http.Request) (auth.Info, error) {
+	secret, err := s.parser.Token(r)
+	if err != nil {
+		return nil, err
+	}
+	if !model.LooksLikeToken(secret) {
+		return nil, app.ErrInvalidToken
+	}
+
+	t, err := s.app.AuthenticateToken(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	extensions := map[string][]string{tokenIDExtension: {strconv.Itoa(int(t.ID))}}
+	if t.Personal() {
+		extensions[scopesExtension] = t.Scopes
+	}
+	return auth.NewDefaultUser(t.User.Email, strconv.Itoa(int(t.UserID)), nil, extensions), nil
+}
+
+// tokenIDFromInfo returns the ID of the token the user was authenticated with, or 0.
+func tokenIDFromInfo(info auth.Info) uint {
+	values := info.Extensions()[tokenIDExtension]
+	if len(values) == 0 {
+		return 0
+	}
+	id, err := strconv.ParseUint(values[0], 10, 0)
+	if err != nil {
+		return 0
+	}
+	return uint(id)
+}
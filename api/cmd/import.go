@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"leggett.dev/devmarks/api/app"
+	"leggett.dev/devmarks/api/netscape"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import <bookmarks.html>",
+	Short: "imports a Netscape bookmark file for a user",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		email, _ := cmd.Flags().GetString("user")
+		if email == "" {
+			return errors.New("the --user flag is required")
+		}
+
+		file, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		root, err := netscape.Parse(file)
+		if err != nil {
+			return err
+		}
+
+		a, err := app.New()
+		if err != nil {
+			return err
+		}
+		defer a.Close()
+
+		user, err := a.Database.GetUserByEmail(email)
+		if err != nil {
+			return err
+		}
+
+		result, err := a.NewContext().WithUser(user).ImportNetscape(root)
+		if err != nil {
+			return err
+		}
+		logrus.Infof("successfully imported %d folders and %d bookmarks", result.Folders, result.Bookmarks)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+
+	importCmd.Flags().String("user", "", "the email address of the user to import the bookmarks for")
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"leggett.dev/devmarks/api/app"
+)
+
+var inviteCmd = &cobra.Command{
+	Use:   "invite",
+	Short: "creates an invite code to register with when registration is invite-only",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		note, _ := cmd.Flags().GetString("note")
+		uses, _ := cmd.Flags().GetInt("uses")
+		expires, _ := cmd.Flags().GetDuration("expires")
+
+		a, err := app.New()
+		if err != nil {
+			return err
+		}
+		defer a.Close()
+
+		var expiresAt *time.Time
+		if expires > 0 {
+			at := time.Now().Add(expires)
+			expiresAt = &at
+		}
+		code, _, err := a.CreateInvite(note, uses, expiresAt)
+		if err != nil {
+			return err
+		}
+		fmt.Println(code)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(inviteCmd)
+
+	inviteCmd.Flags().String("note", "", "a reminder of who the invite is for")
+	inviteCmd.Flags().Int("uses", 1, "how many users can register with the invite")
+	inviteCmd.Flags().Duration("expires", 7*24*time.Hour, "how long the invite can be used for, or 0 for ever")
+}
@@ -30,6 +30,7 @@ func serveAPI(ctx context.Context, api *api.API) {
 			handlers.AllowedOrigins(api.Config.AllowedHosts),
 			handlers.AllowedMethods([]string{"GET", "HEAD", "POST", "OPTIONS", "PATCH", "DELETE"}),
 			handlers.AllowedHeaders([]string{"Content-Type", "Authorization"}),
+			handlers.ExposedHeaders([]string{"Link", "X-Total-Count"}),
 		)
 
 		handler = cors(router)
@@ -93,6 +94,12 @@ var serveCmd = &cobra.Command{
 			serveAPI(ctx, api)
 		}()
 
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.CheckLinks(ctx)
+		}()
+
 		wg.Wait()
 		return nil
 	},
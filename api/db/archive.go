@@ -0,0 +1,279 @@
+package db
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+
+	"leggett.dev/devmarks/api/archive"
+	"leggett.dev/devmarks/api/model"
+)
+
+// The ways ImportArchive can handle folders and bookmarks that already exist. A folder
+// exists if the user has one with the same name in the same parent, and a bookmark if
+// the user has one with the same URL. Tags are always matched by name.
+const (
+	// ConflictSkip keeps the existing folder or bookmark as it is. The contents of a
+	// skipped folder are still imported into it.
+	ConflictSkip = "skip"
+	// ConflictOverwrite replaces the existing folder or bookmark with the imported one.
+	ConflictOverwrite = "overwrite"
+	// ConflictDuplicate imports the folder or bookmark next to the existing one.
+	ConflictDuplicate = "duplicate"
+)
+
+// ArchiveImportCounts counts what happened to the entries of one kind in an archive.
+type ArchiveImportCounts struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Skipped int `json:"skipped"`
+}
+
+// ArchiveImportResult counts what happened to the entries of an archive.
+type ArchiveImportResult struct {
+	Folders   ArchiveImportCounts `json:"folders"`
+	Tags      ArchiveImportCounts `json:"tags"`
+	Bookmarks ArchiveImportCounts `json:"bookmarks"`
+}
+
+// ExportArchive builds the archive of everything owned by the specified user.
+func (db *Database) ExportArchive(ownerID uint) (*archive.Archive, error) {
+	var folders []*model.Folder
+	if err := db.Where("organization_id IS NULL").Order("created_at, id").Find(&folders, model.Folder{OwnerID: ownerID}).Error; err != nil {
+		return nil, errors.Wrap(err, "unable to get folders")
+	}
+	var tags []*model.Tag
+	if err := db.Order("name").Find(&tags, model.Tag{OwnerID: ownerID}).Error; err != nil {
+		return nil, errors.Wrap(err, "unable to get tags")
+	}
+	var bookmarks []*model.Bookmark
+	if err := db.Preload("Folders").Preload("Tags").Order("created_at, id").Find(&bookmarks, model.Bookmark{OwnerID: ownerID}).Error; err != nil {
+		return nil, errors.Wrap(err, "unable to get bookmarks")
+	}
+
+	a := &archive.Archive{
+		ExportedAt: time.Now(),
+		Folders:    make([]archive.Folder, 0, len(folders)),
+		Tags:       make([]archive.Tag, 0, len(tags)),
+		Bookmarks:  make([]archive.Bookmark, 0, len(bookmarks)),
+	}
+	exported := make(map[uint]bool, len(folders))
+	for _, folder := range folders {
+		exported[folder.ID] = true
+	}
+	for _, folder := range folders {
+		f := archive.Folder{ID: folder.ID, Name: folder.Name, Color: folder.Color, CreatedAt: folder.CreatedAt}
+		// a parent that was deleted on its own leaves its children at the top level.
+		if folder.ParentID != nil && exported[*folder.ParentID] {
+			f.ParentID = folder.ParentID
+		}
+		a.Folders = append(a.Folders, f)
+	}
+	for _, tag := range tags {
+		a.Tags = append(a.Tags, archive.Tag{ID: tag.ID, Name: tag.Name, CreatedAt: tag.CreatedAt})
+	}
+	for _, bookmark := range bookmarks {
+		b := archive.Bookmark{
+			ID:        bookmark.ID,
+			Name:      bookmark.Name,
+			URL:       bookmark.URL,
+			Color:     bookmark.Color,
+			Notes:     bookmark.Notes,
+			ReadAt:    bookmark.ReadAt,
+			CreatedAt: bookmark.CreatedAt,
+			FolderIDs: []uint{},
+			TagIDs:    []uint{},
+		}
+		for _, folder := range bookmark.Folders {
+			if exported[folder.ID] {
+				b.FolderIDs = append(b.FolderIDs, folder.ID)
+			}
+		}
+		for _, tag := range bookmark.Tags {
+			b.TagIDs = append(b.TagIDs, tag.ID)
+		}
+		a.Bookmarks = append(a.Bookmarks, b)
+	}
+	return a, nil
+}
+
+// ImportArchive restores the archive for the specified user in a single transaction,
+// giving its entries new IDs and handling the ones that already exist according to
+// conflict, one of ConflictSkip, ConflictOverwrite or ConflictDuplicate.
+func (db *Database) ImportArchive(ownerID uint, a *archive.Archive, conflict string) (*ArchiveImportResult, error) {
+	result := &ArchiveImportResult{}
+	err := db.WithTransaction(func(tx *Database) error {
+		folders, err := tx.importArchiveFolders(ownerID, a, conflict, result)
+		if err != nil {
+			return err
+		}
+		tags, err := tx.importArchiveTags(ownerID, a, result)
+		if err != nil {
+			return err
+		}
+		return tx.importArchiveBookmarks(ownerID, a, conflict, folders, tags, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// importArchiveFolders imports the folders of the archive, parents first, and returns
+// the folders they were imported as by their IDs in the archive.
+func (db *Database) importArchiveFolders(ownerID uint, a *archive.Archive, conflict string, result *ArchiveImportResult) (map[uint]*model.Folder, error) {
+	folders := make(map[uint]*model.Folder, len(a.Folders))
+	for _, f := range a.SortedFolders() {
+		var parentID *uint
+		if f.ParentID != nil {
+			parentID = &folders[*f.ParentID].ID
+		}
+
+		if conflict != ConflictDuplicate {
+			existing, err := db.findFolder(ownerID, f.Name, parentID)
+			if err != nil {
+				return nil, err
+			}
+			if existing != nil {
+				folders[f.ID] = existing
+				if conflict == ConflictSkip {
+					result.Folders.Skipped++
+					continue
+				}
+				existing.Color = f.Color
+				if err := db.UpdateFolder(existing); err != nil {
+					return nil, err
+				}
+				result.Folders.Updated++
+				continue
+			}
+		}
+
+		folder := &model.Folder{Name: f.Name, Color: f.Color, ParentID: parentID, OwnerID: ownerID}
+		folder.CreatedAt = f.CreatedAt
+		if err := db.CreateFolder(folder); err != nil {
+			return nil, err
+		}
+		folders[f.ID] = folder
+		result.Folders.Created++
+	}
+	return folders, nil
+}
+
+// findFolder returns the folder of the specified user with the name in the parent, or
+// nil if there is none.
+func (db *Database) findFolder(ownerID uint, name string, parentID *uint) (*model.Folder, error) {
+	query := db.Where("owner_id = ? AND name = ?", ownerID, name)
+	if parentID == nil {
+		query = query.Where("parent_id IS NULL")
+	} else {
+		query = query.Where("parent_id = ?", *parentID)
+	}
+	var folder model.Folder
+	if err := query.Order("id").First(&folder).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "unable to get folder")
+	}
+	return &folder, nil
+}
+
+// importArchiveTags finds or creates the tags of the archive and returns them by their
+// IDs in the archive.
+func (db *Database) importArchiveTags(ownerID uint, a *archive.Archive, result *ArchiveImportResult) (map[uint]model.Tag, error) {
+	tags := make(map[uint]model.Tag, len(a.Tags))
+	for _, t := range a.Tags {
+		var tag model.Tag
+		err := db.First(&tag, model.Tag{Name: t.Name, OwnerID: ownerID}).Error
+		if err != nil && !gorm.IsRecordNotFoundError(err) {
+			return nil, errors.Wrap(err, "unable to get tag")
+		}
+		if err == nil {
+			result.Tags.Skipped++
+		} else {
+			tag = model.Tag{Name: t.Name, OwnerID: ownerID}
+			tag.CreatedAt = t.CreatedAt
+			if err := db.CreateTag(&tag); err != nil {
+				return nil, err
+			}
+			result.Tags.Created++
+		}
+		tags[t.ID] = tag
+	}
+	return tags, nil
+}
+
+// importArchiveBookmarks imports the bookmarks of the archive into the folders and with
+// the tags they were imported as.
+func (db *Database) importArchiveBookmarks(ownerID uint, a *archive.Archive, conflict string, folders map[uint]*model.Folder, tags map[uint]model.Tag, result *ArchiveImportResult) error {
+	for _, b := range a.Bookmarks {
+		var bookmark *model.Bookmark
+		if conflict != ConflictDuplicate {
+			var existing model.Bookmark
+			err := db.Where("owner_id = ? AND url = ?", ownerID, b.URL).Order("id").First(&existing).Error
+			if err != nil && !gorm.IsRecordNotFoundError(err) {
+				return errors.Wrap(err, "unable to get bookmark")
+			}
+			if err == nil {
+				if conflict == ConflictSkip {
+					result.Bookmarks.Skipped++
+					continue
+				}
+				bookmark = &existing
+			}
+		}
+
+		if bookmark == nil {
+			bookmark = &model.Bookmark{Name: b.Name, URL: b.URL, Color: b.Color, Notes: b.Notes, ReadAt: b.ReadAt, OwnerID: ownerID}
+			bookmark.CreatedAt = b.CreatedAt
+			if err := db.CreateBookmark(bookmark); err != nil {
+				return err
+			}
+			result.Bookmarks.Created++
+		} else {
+			bookmark.Name = b.Name
+			bookmark.Color = b.Color
+			bookmark.Notes = b.Notes
+			bookmark.ReadAt = b.ReadAt
+			if err := db.UpdateBookmark(bookmark); err != nil {
+				return err
+			}
+			result.Bookmarks.Updated++
+		}
+
+		// entries of the archive can be imported as the same tag or folder, which the
+		// bookmark must only be attached to once.
+		seen := make(map[uint]bool)
+		bookmarkTags := make([]model.Tag, 0, len(b.TagIDs))
+		for _, id := range b.TagIDs {
+			if tag := tags[id]; !seen[tag.ID] {
+				seen[tag.ID] = true
+				bookmarkTags = append(bookmarkTags, tag)
+			}
+		}
+		if err := db.ReplaceBookmarkTags(bookmark, bookmarkTags); err != nil {
+			return err
+		}
+
+		seen = make(map[uint]bool)
+		bookmarkFolders := make([]model.Folder, 0, len(b.FolderIDs))
+		for _, id := range b.FolderIDs {
+			if folder := folders[id]; !seen[folder.ID] {
+				seen[folder.ID] = true
+				bookmarkFolders = append(bookmarkFolders, *folder)
+			}
+		}
+		association := db.Model(bookmark).Association("Folders")
+		if len(bookmarkFolders) == 0 {
+			association = association.Clear()
+		} else {
+			association = association.Replace(bookmarkFolders)
+		}
+		if association.Error != nil {
+			return errors.Wrap(association.Error, "unable to add bookmark to folders")
+		}
+	}
+	return nil
+}
@@ -2,7 +2,9 @@ package db
 
 import (
 	"context"
+	"time"
 
+	"github.com/jinzhu/gorm"
 	"github.com/pkg/errors"
 
 	"leggett.dev/devmarks/api/helpers"
@@ -18,6 +20,15 @@ func contains(array []string, s string) bool {
 	return false
 }
 
+// linkStatusConditions select the bookmarks whose link has each status, matching
+// model.BookmarkLink.Status.
+var linkStatusConditions = map[string]string{
+	model.LinkStatusUnchecked:  "bookmarks.link_checked_at IS NULL",
+	model.LinkStatusOK:         "bookmarks.link_checked_at IS NOT NULL AND bookmarks.link_failures = 0 AND bookmarks.link_redirect_url = ''",
+	model.LinkStatusRedirected: "bookmarks.link_checked_at IS NOT NULL AND bookmarks.link_failures = 0 AND bookmarks.link_redirect_url <> ''",
+	model.LinkStatusBroken:     "bookmarks.link_checked_at IS NOT NULL AND bookmarks.link_failures > 0",
+}
+
 // GetBookmarkByID queries the database for a bookmark with the specified id
 func (db *Database) GetBookmarkByID(ctx context.Context, id uint) (*model.Bookmark, error) {
 	var bookmark model.Bookmark
@@ -28,15 +39,26 @@ func (db *Database) GetBookmarkByID(ctx context.Context, id uint) (*model.Bookma
 	return &bookmark, errors.Wrap(db.preloadEmbeds(model.BookmarkValidEmbeds(), embeds).First(&bookmark, id).Error, "unable to get bookmark")
 }
 
-// GetBookmarksByUserID returns all the bookmarks from the database that are
-// owned by the user corresponding to the userID provided.
-func (db *Database) GetBookmarksByUserID(ctx context.Context, userID uint) ([]*model.Bookmark, error) {
+// GetBookmarksByUserID returns the page of bookmarks from the database described by
+// opts that are owned by the user corresponding to the userID provided.
+func (db *Database) GetBookmarksByUserID(ctx context.Context, userID uint, opts *ListOptions) ([]*model.Bookmark, *Page, error) {
 	var bookmarks []*model.Bookmark
 	embeds, ok := ctx.Value(helpers.EmbedsKey).([]string)
 	if !ok {
-		return nil, errors.New("embeds parsing error")
+		return nil, nil, errors.New("embeds parsing error")
+	}
+	query := db.preloadEmbeds(model.BookmarkValidEmbeds(), embeds).Where("bookmarks.owner_id = ?", userID).Scopes(opts.filter("bookmarks"))
+	if opts.FolderID != nil {
+		query = query.Where("EXISTS (SELECT 1 FROM bookmark_folder WHERE bookmark_folder.bookmark_id = bookmarks.id AND bookmark_folder.folder_id = ?)", *opts.FolderID)
+	}
+	if opts.LinkStatus != nil {
+		query = query.Where(linkStatusConditions[*opts.LinkStatus])
+	}
+	page, err := paginate(query, "bookmarks", model.BookmarkValidSorts(), opts, &bookmarks)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "unable to get bookmarks")
 	}
-	return bookmarks, errors.Wrap(db.preloadEmbeds(model.BookmarkValidEmbeds(), embeds).Find(&bookmarks, model.Bookmark{OwnerID: userID}).Error, "unable to get bookmarks")
+	return bookmarks, page, nil
 }
 
 // CreateBookmark inserts the specified bookmark into the database.
@@ -49,8 +71,65 @@ func (db *Database) UpdateBookmark(bookmark *model.Bookmark) error {
 	return errors.Wrap(db.Save(bookmark).Error, "unable to update bookmark")
 }
 
+// SetBookmarkMetadata stores the metadata fetched from url for the bookmark with the
+// specified ID, unless its URL changed since. A bookmark without a name is named name.
+func (db *Database) SetBookmarkMetadata(id uint, url string, metadata model.BookmarkMetadata, name string) error {
+	return db.WithTransaction(func(tx *Database) error {
+		query := tx.Model(&model.Bookmark{}).Where("id = ? AND url = ?", id, url)
+		err := query.UpdateColumns(map[string]interface{}{
+			"metadata_title":         metadata.Title,
+			"metadata_description":   metadata.Description,
+			"metadata_favicon_url":   metadata.FaviconURL,
+			"metadata_canonical_url": metadata.CanonicalURL,
+			"metadata_image_url":     metadata.ImageURL,
+			"metadata_error":         metadata.Error,
+			"metadata_fetched_at":    metadata.FetchedAt,
+		}).Error
+		if err != nil || name == "" {
+			return errors.Wrap(err, "unable to update bookmark metadata")
+		}
+		err = query.Where("name = ''").UpdateColumn("name", name).Error
+		return errors.Wrap(err, "unable to update bookmark name")
+	})
+}
+
+// GetBookmarksToCheck returns the bookmarks whose links are due to be checked at the
+// given time, the ones never checked first, at most limit of them.
+func (db *Database) GetBookmarksToCheck(now time.Time, limit int) ([]*model.Bookmark, error) {
+	var bookmarks []*model.Bookmark
+	err := db.Where("link_next_check_at IS NULL OR link_next_check_at <= ?", now).
+		Order("link_next_check_at NULLS FIRST, id").Limit(limit).Find(&bookmarks).Error
+	return bookmarks, errors.Wrap(err, "unable to get bookmarks to check")
+}
+
+// SetBookmarkLink stores the outcome of checking url for the bookmark with the
+// specified ID, unless its URL changed since.
+func (db *Database) SetBookmarkLink(id uint, url string, link model.BookmarkLink) error {
+	err := db.Model(&model.Bookmark{}).Where("id = ? AND url = ?", id, url).UpdateColumns(map[string]interface{}{
+		"link_status_code":   link.StatusCode,
+		"link_error":         link.Error,
+		"link_redirect_url":  link.RedirectURL,
+		"link_failures":      link.Failures,
+		"link_checked_at":    link.CheckedAt,
+		"link_next_check_at": link.NextCheckAt,
+	}).Error
+	return errors.Wrap(err, "unable to update bookmark link")
+}
+
 // DeleteBookmarkByID deletes the bookmark with the specified ID from the
 // databse.
 func (db *Database) DeleteBookmarkByID(id uint) error {
 	return errors.Wrap(db.Delete(&model.Bookmark{}, id).Error, "unable to delete todo")
 }
+
+// SearchBookmarks returns the bookmarks owned by the user corresponding to the userID
+// provided that match the given search scopes.
+func (db *Database) SearchBookmarks(ctx context.Context, userID uint, scopes ...func(*gorm.DB) *gorm.DB) ([]*model.Bookmark, error) {
+	var bookmarks []*model.Bookmark
+	embeds, ok := ctx.Value(helpers.EmbedsKey).([]string)
+	if !ok {
+		return nil, errors.New("embeds parsing error")
+	}
+	query := db.preloadEmbeds(model.BookmarkValidEmbeds(), embeds).Scopes(scopes...).Order("bookmarks.created_at DESC")
+	return bookmarks, errors.Wrap(query.Find(&bookmarks, model.Bookmark{OwnerID: userID}).Error, "unable to search bookmarks")
+}
@@ -33,3 +33,28 @@ func (db *Database) preloadEmbeds(valid []string, embeds []string) *gorm.DB {
 	}
 	return instance
 }
+
+// WithTransaction runs fn with a Database bound to a new transaction, committing it
+// if fn succeeds and rolling it back if fn returns an error or panics.
+func (db *Database) WithTransaction(fn func(tx *Database) error) (err error) {
+	tx := db.Begin()
+	if tx.Error != nil {
+		return errors.Wrap(tx.Error, "unable to begin transaction")
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+	if err := fn(&Database{tx}); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return errors.Wrap(tx.Commit().Error, "unable to commit transaction")
+}
+
+// IsNotFound returns true if err was caused by a record not existing in the database.
+func IsNotFound(err error) bool {
+	return gorm.IsRecordNotFoundError(errors.Cause(err))
+}
@@ -0,0 +1,36 @@
+// Package dbtest provides databases for tests that need one, without a PostgreSQL
+// server: they are temporary SQLite databases with tables for the given models.
+package dbtest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	// Blank because it is needed for gorm but never directly used
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+
+	"leggett.dev/devmarks/api/db"
+)
+
+// New returns an empty database with tables for the given models, closed once the
+// test is done. Queries relying on PostgreSQL features do not work on it. The test is
+// skipped if SQLite is unavailable, as it is when cgo is disabled.
+func New(t testing.TB, models ...interface{}) *db.Database {
+	t.Helper()
+	// the database is a file rather than in memory, as every connection to an in-memory
+	// database gets a database of its own. Like with PostgreSQL, queries outside of a
+	// transaction do not wait for it, and do not see its changes until it commits.
+	path := filepath.Join(t.TempDir(), "devmarks.db")
+	conn, err := gorm.Open("sqlite3", path+"?_journal_mode=WAL&_busy_timeout=5000")
+	if err != nil {
+		// the SQLite driver needs cgo, without which it cannot open any database.
+		t.Skipf("unable to open database: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	if err := conn.AutoMigrate(models...).Error; err != nil {
+		t.Fatalf("unable to create tables: %v", err)
+	}
+	return &db.Database{DB: conn}
+}
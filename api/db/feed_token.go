@@ -0,0 +1,65 @@
+package db
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"leggett.dev/devmarks/api/model"
+)
+
+// ReplaceFeedToken deletes the specified user's feed token for the same folder or tag
+// as the specified token, if there is one, and inserts the new one.
+func (db *Database) ReplaceFeedToken(token *model.FeedToken) error {
+	return db.WithTransaction(func(tx *Database) error {
+		if err := tx.DeleteFeedToken(token.UserID, token.FolderID, token.TagID); err != nil {
+			return err
+		}
+		return errors.Wrap(tx.Create(token).Error, "unable to create feed token")
+	})
+}
+
+// GetFeedTokenByHash returns the feed token with the specified hash, preloading its user.
+func (db *Database) GetFeedTokenByHash(hash string) (*model.FeedToken, error) {
+	var token model.FeedToken
+	return &token, errors.Wrap(db.Preload("User").First(&token, model.FeedToken{Hash: hash}).Error, "unable to get feed token")
+}
+
+// TouchFeedToken records that the feed token with the specified ID was used at the
+// given time.
+func (db *Database) TouchFeedToken(id uint, usedAt time.Time) error {
+	token := model.FeedToken{Model: model.Model{ID: id}}
+	return errors.Wrap(db.Model(&token).UpdateColumn("last_used_at", usedAt).Error, "unable to update feed token")
+}
+
+// DeleteFeedToken deletes the specified user's feed token for the folder or the tag
+// with the specified ID, so its feed URL stops working.
+func (db *Database) DeleteFeedToken(userID uint, folderID, tagID *uint) error {
+	query := db.Where("user_id = ?", userID)
+	if folderID != nil {
+		query = query.Where("folder_id = ?", *folderID)
+	} else {
+		query = query.Where("tag_id = ?", derefID(tagID))
+	}
+	return errors.Wrap(query.Delete(&model.FeedToken{}).Error, "unable to delete feed token")
+}
+
+// GetFolderFeedBookmarks returns the most recently created bookmarks in the folder
+// with the specified ID, at most limit of them, preloading their tags.
+func (db *Database) GetFolderFeedBookmarks(folderID uint, limit int) ([]*model.Bookmark, error) {
+	var bookmarks []*model.Bookmark
+	err := db.Preload("Tags").
+		Where("EXISTS (SELECT 1 FROM bookmark_folder WHERE bookmark_folder.bookmark_id = bookmarks.id AND bookmark_folder.folder_id = ?)", folderID).
+		Order("bookmarks.created_at DESC, bookmarks.id DESC").Limit(limit).Find(&bookmarks).Error
+	return bookmarks, errors.Wrap(err, "unable to get bookmarks")
+}
+
+// GetTagFeedBookmarks returns the most recently created bookmarks with the tag with
+// the specified ID, at most limit of them, preloading their tags.
+func (db *Database) GetTagFeedBookmarks(tagID uint, limit int) ([]*model.Bookmark, error) {
+	var bookmarks []*model.Bookmark
+	err := db.Preload("Tags").
+		Where("EXISTS (SELECT 1 FROM bookmark_tag WHERE bookmark_tag.bookmark_id = bookmarks.id AND bookmark_tag.tag_id = ?)", tagID).
+		Order("bookmarks.created_at DESC, bookmarks.id DESC").Limit(limit).Find(&bookmarks).Error
+	return bookmarks, errors.Wrap(err, "unable to get bookmarks")
+}
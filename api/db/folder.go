@@ -2,7 +2,9 @@ package db
 
 import (
 	"context"
-	
+	"fmt"
+
+	"github.com/jinzhu/gorm"
 	"github.com/pkg/errors"
 	"leggett.dev/devmarks/api/helpers"
 	"leggett.dev/devmarks/api/model"
@@ -12,13 +14,21 @@ func (db *Database) CreateFolder(folder *model.Folder) error {
 	return errors.Wrap(db.Create(folder).Error, "unable to create folder")
 }
 
-func (db *Database) GetFoldersByUserID(ctx context.Context, userID uint) ([]*model.Folder, error) {
+func (db *Database) GetFoldersByUserID(ctx context.Context, userID uint, opts *ListOptions) ([]*model.Folder, *Page, error) {
 	var folders []*model.Folder
 	embeds, ok := ctx.Value(helpers.EmbedsKey).([]string)
 	if !ok {
-		return nil, errors.New("embeds parsing error")
+		return nil, nil, errors.New("embeds parsing error")
 	}
-	return folders, errors.Wrap(db.preloadEmbeds(model.FolderValidEmbeds(), embeds).Find(&folders, model.Folder{OwnerID: userID}).Error, "unable to get folders")
+	query := db.preloadEmbeds(model.FolderValidEmbeds(), embeds).Where("folders.owner_id = ? AND folders.organization_id IS NULL", userID).Scopes(opts.filter("folders"))
+	if opts.ParentID != nil {
+		query = query.Where("folders.parent_id = ?", *opts.ParentID)
+	}
+	page, err := paginate(query, "folders", model.FolderValidSorts(), opts, &folders)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "unable to get folders")
+	}
+	return folders, page, nil
 }
 
 func (db *Database) GetFolderByID(ctx context.Context, id uint) (*model.Folder, error) {
@@ -30,6 +40,22 @@ func (db *Database) GetFolderByID(ctx context.Context, id uint) (*model.Folder,
 	return &folder, errors.Wrap(db.preloadEmbeds(model.FolderValidEmbeds(), embeds).First(&folder, id).Error, "unable to get folder")
 }
 
+// GetFolderByPublicSlug returns the folder published under the specified slug.
+func (db *Database) GetFolderByPublicSlug(slug string) (*model.Folder, error) {
+	var folder model.Folder
+	return &folder, errors.Wrap(db.Where("public_slug = ?", slug).First(&folder).Error, "unable to get folder")
+}
+
+// GetFoldersWithBookmarks returns the folders with the specified IDs, preloading their
+// bookmarks, most recently created first.
+func (db *Database) GetFoldersWithBookmarks(ids []uint) ([]*model.Folder, error) {
+	var folders []*model.Folder
+	err := db.Preload("Bookmarks", func(db *gorm.DB) *gorm.DB {
+		return db.Order("bookmarks.created_at DESC, bookmarks.id")
+	}).Where("id IN (?)", ids).Order("name, id").Find(&folders).Error
+	return folders, errors.Wrap(err, "unable to get folders")
+}
+
 func (db *Database) AddBookmarkToFolder(ctx context.Context, bookmark_id uint, folder_id uint) error {
 	bookmark, err := db.GetBookmarkByID(ctx, bookmark_id)
 	if err != nil {
@@ -45,3 +71,166 @@ func (db *Database) AddBookmarkToFolder(ctx context.Context, bookmark_id uint, f
 	}
 	return nil
 }
+
+// UpdateFolder updates the specified folder in the database.
+func (db *Database) UpdateFolder(folder *model.Folder) error {
+	return errors.Wrap(db.Save(folder).Error, "unable to update folder")
+}
+
+const folderDescendantsSQL = `WITH RECURSIVE descendants AS (
+	SELECT id FROM folders WHERE parent_id = ? AND deleted_at IS NULL
+	UNION
+	SELECT folders.id FROM folders JOIN descendants ON folders.parent_id = descendants.id
+	WHERE folders.deleted_at IS NULL
+) SELECT id FROM descendants`
+
+// GetFolderDescendantIDs returns the IDs of every folder nested anywhere below the
+// folder with the specified ID, not including the folder itself.
+func (db *Database) GetFolderDescendantIDs(id uint) ([]uint, error) {
+	rows, err := db.Raw(folderDescendantsSQL, id).Rows()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get folder descendants")
+	}
+	defer rows.Close()
+
+	var ids []uint
+	for rows.Next() {
+		var descendant uint
+		if err := rows.Scan(&descendant); err != nil {
+			return nil, errors.Wrap(err, "unable to get folder descendants")
+		}
+		ids = append(ids, descendant)
+	}
+	return ids, errors.Wrap(rows.Err(), "unable to get folder descendants")
+}
+
+// DeleteFolder deletes the specified folder and removes its bookmarks from it. If
+// cascade is true every folder below it is deleted as well, otherwise its child
+// folders are moved up to its parent. Bookmarks themselves are never deleted.
+func (db *Database) DeleteFolder(folder *model.Folder, cascade bool) error {
+	return db.WithTransaction(func(tx *Database) error {
+		ids := []uint{folder.ID}
+		if cascade {
+			descendants, err := tx.GetFolderDescendantIDs(folder.ID)
+			if err != nil {
+				return err
+			}
+			ids = append(ids, descendants...)
+		} else {
+			if err := tx.Model(&model.Folder{}).Where("parent_id = ?", folder.ID).Update("parent_id", folder.ParentID).Error; err != nil {
+				return errors.Wrap(err, "unable to reparent child folders")
+			}
+		}
+
+		if err := tx.Exec("DELETE FROM bookmark_folder WHERE folder_id IN (?)", ids).Error; err != nil {
+			return errors.Wrap(err, "unable to remove bookmarks from folder")
+		}
+		return errors.Wrap(tx.Where("id IN (?)", ids).Delete(&model.Folder{}).Error, "unable to delete folder")
+	})
+}
+
+// maxFolderTreeDepth bounds the folder tree query, so it stops even if the data
+// somehow contains a cycle.
+const maxFolderTreeDepth = 100
+
+// folderTreeSQL selects the folders matched by its condition and those nested inside
+// them, as long as they have the same owner.
+const folderTreeSQL = `WITH RECURSIVE tree AS (
+	SELECT id, name, coalesce(color, '') AS color, parent_id, owner_id, 0 AS depth FROM folders
+	WHERE deleted_at IS NULL AND %s
+	UNION ALL
+	SELECT folders.id, folders.name, coalesce(folders.color, ''), folders.parent_id, folders.owner_id, tree.depth + 1
+	FROM folders JOIN tree ON folders.parent_id = tree.id AND folders.owner_id = tree.owner_id
+	WHERE folders.deleted_at IS NULL AND tree.depth < ?
+) SELECT tree.*, (
+	SELECT count(*) FROM bookmark_folder JOIN bookmarks ON bookmarks.id = bookmark_folder.bookmark_id
+	WHERE bookmark_folder.folder_id = tree.id AND bookmarks.deleted_at IS NULL
+) AS bookmark_count FROM tree ORDER BY depth, name, id`
+
+// folderTreeRootsSQL selects a user's top level folders: those without a parent, or
+// whose parent is missing or belongs to someone else. Folders of organizations are
+// left out.
+const folderTreeRootsSQL = `owner_id = ? AND organization_id IS NULL AND (parent_id IS NULL OR NOT EXISTS (
+	SELECT 1 FROM folders AS parents WHERE parents.id = folders.parent_id
+	AND parents.owner_id = folders.owner_id AND parents.deleted_at IS NULL))`
+
+type folderTreeRow struct {
+	ID            uint
+	Name          string
+	Color         string
+	ParentID      *uint
+	Depth         int
+	BookmarkCount int
+}
+
+// GetFolderTree returns the nested folder hierarchy of the user with the specified
+// ID in a single query, along with the number of bookmarks in each folder. A depth
+// of 0 returns only the top level folders, and a negative depth returns every level.
+func (db *Database) GetFolderTree(ownerID uint, depth int) ([]*model.FolderNode, error) {
+	return db.getFolderTree(fmt.Sprintf(folderTreeSQL, folderTreeRootsSQL), ownerID, depth)
+}
+
+// GetFolderSubtree returns the folder with the specified ID and the hierarchy of
+// folders nested inside it, limited to depth levels below it like GetFolderTree.
+func (db *Database) GetFolderSubtree(id uint, depth int) (*model.FolderNode, error) {
+	nodes, err := db.getFolderTree(fmt.Sprintf(folderTreeSQL, "id = ?"), id, depth)
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		return nil, errors.New("unable to get folder tree: folder does not exist")
+	}
+	return nodes[0], nil
+}
+
+func (db *Database) getFolderTree(sql string, arg interface{}, depth int) ([]*model.FolderNode, error) {
+	if depth < 0 || depth > maxFolderTreeDepth {
+		depth = maxFolderTreeDepth
+	}
+	var rows []folderTreeRow
+	if err := db.Raw(sql, arg, depth).Scan(&rows).Error; err != nil {
+		return nil, errors.Wrap(err, "unable to get folder tree")
+	}
+
+	// rows are ordered by depth, so every parent is seen before its children.
+	roots := []*model.FolderNode{}
+	nodes := make(map[uint]*model.FolderNode, len(rows))
+	for _, row := range rows {
+		node := &model.FolderNode{
+			ID:            row.ID,
+			Name:          row.Name,
+			Color:         row.Color,
+			ParentID:      row.ParentID,
+			BookmarkCount: row.BookmarkCount,
+			Children:      []*model.FolderNode{},
+		}
+		nodes[row.ID] = node
+		if parent, ok := nodes[derefID(row.ParentID)]; ok && row.Depth > 0 {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+	return roots, nil
+}
+
+func derefID(id *uint) uint {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
+
+// RemoveBookmarkFromFolder removes the bookmark with the specified ID from the folder
+// with the specified ID. The bookmark itself is kept.
+func (db *Database) RemoveBookmarkFromFolder(bookmarkID uint, folderID uint) error {
+	return errors.Wrap(db.Exec("DELETE FROM bookmark_folder WHERE bookmark_id = ? AND folder_id = ?", bookmarkID, folderID).Error, "unable to remove bookmark from folder")
+}
+
+// GetBookmarkFolders returns the folders the bookmark with the specified ID is in.
+func (db *Database) GetBookmarkFolders(bookmarkID uint) ([]*model.Folder, error) {
+	var folders []*model.Folder
+	err := db.Joins("JOIN bookmark_folder ON bookmark_folder.folder_id = folders.id").
+		Where("bookmark_folder.bookmark_id = ?", bookmarkID).Order("folders.id").Find(&folders).Error
+	return folders, errors.Wrap(err, "unable to get folders of bookmark")
+}
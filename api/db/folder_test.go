@@ -0,0 +1,68 @@
+package db_test
+
+import (
+	"reflect"
+	"testing"
+
+	"leggett.dev/devmarks/api/db"
+	"leggett.dev/devmarks/api/db/dbtest"
+	"leggett.dev/devmarks/api/model"
+)
+
+// createFolder creates a folder with the given owner and parent, which may be nil.
+func createFolder(t *testing.T, database *db.Database, name string, ownerID uint, parent *model.Folder) *model.Folder {
+	t.Helper()
+	folder := &model.Folder{Name: name, OwnerID: ownerID}
+	if parent != nil {
+		folder.ParentID = &parent.ID
+	}
+	if err := database.CreateFolder(folder); err != nil {
+		t.Fatal(err)
+	}
+	return folder
+}
+
+// names returns the names of the nodes and their children, depth first.
+func names(nodes []*model.FolderNode) []string {
+	var result []string
+	for _, node := range nodes {
+		result = append(result, node.Name)
+		result = append(result, names(node.Children)...)
+	}
+	return result
+}
+
+func TestGetFolderTreeOnlyIncludesOwnFolders(t *testing.T) {
+	database := dbtest.New(t, &model.Folder{}, &model.Bookmark{})
+	docs := createFolder(t, database, "Docs", 1, nil)
+	createFolder(t, database, "Go", 1, docs)
+	// a folder of another user can only point into this tree through bad data, but
+	// must not show up in it.
+	foreign := createFolder(t, database, "Foreign", 2, docs)
+	createFolder(t, database, "Nested", 2, foreign)
+
+	tests := []struct {
+		ownerID uint
+		want    []string
+	}{
+		{1, []string{"Docs", "Go"}},
+		{2, []string{"Foreign", "Nested"}},
+	}
+	for _, test := range tests {
+		tree, err := database.GetFolderTree(test.ownerID, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := names(tree); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("GetFolderTree(%d) = %q, want %q", test.ownerID, got, test.want)
+		}
+	}
+
+	subtree, err := database.GetFolderSubtree(docs.ID, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := names([]*model.FolderNode{subtree}), []string{"Docs", "Go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetFolderSubtree() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,19 @@
+package db
+
+import (
+	"github.com/pkg/errors"
+
+	"leggett.dev/devmarks/api/model"
+)
+
+// GetIdentity returns the identity with the specified issuer and subject, preloading
+// its user.
+func (db *Database) GetIdentity(issuer, subject string) (*model.Identity, error) {
+	var identity model.Identity
+	return &identity, errors.Wrap(db.Preload("User").First(&identity, model.Identity{Issuer: issuer, Subject: subject}).Error, "unable to get identity")
+}
+
+// CreateIdentity inserts the specified identity into the database.
+func (db *Database) CreateIdentity(identity *model.Identity) error {
+	return errors.Wrap(db.Create(identity).Error, "unable to create identity")
+}
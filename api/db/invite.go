@@ -0,0 +1,40 @@
+package db
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+
+	"leggett.dev/devmarks/api/model"
+)
+
+// CreateInvite inserts the specified invite into the database.
+func (db *Database) CreateInvite(invite *model.Invite) error {
+	return errors.Wrap(db.Create(invite).Error, "unable to create invite")
+}
+
+// GetInvites returns every invite, most recently created first.
+func (db *Database) GetInvites() ([]*model.Invite, error) {
+	var invites []*model.Invite
+	return invites, errors.Wrap(db.Order("created_at DESC").Find(&invites).Error, "unable to get invites")
+}
+
+// GetInviteByHash returns the invite with the specified hash.
+func (db *Database) GetInviteByHash(hash string) (*model.Invite, error) {
+	var invite model.Invite
+	return &invite, errors.Wrap(db.First(&invite, model.Invite{Hash: hash}).Error, "unable to get invite")
+}
+
+// UseInvite counts one more use of the invite with the specified ID. It returns false
+// if the invite was used up or had expired at the given time, so concurrent
+// registrations cannot use it more often than allowed.
+func (db *Database) UseInvite(id uint, at time.Time) (bool, error) {
+	result := db.Model(&model.Invite{}).
+		Where("id = ? AND uses < max_uses AND (expires_at IS NULL OR expires_at > ?)", id, at).
+		UpdateColumn("uses", gorm.Expr("uses + 1"))
+	if result.Error != nil {
+		return false, errors.Wrap(result.Error, "unable to update invite")
+	}
+	return result.RowsAffected == 1, nil
+}
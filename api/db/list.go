@@ -0,0 +1,186 @@
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultListLimit is the page size used when a list request does not specify one.
+	DefaultListLimit = 50
+	// MaxListLimit is the largest page size a list request may ask for.
+	MaxListLimit = 200
+)
+
+// ErrInvalidCursor is returned for cursors that were not handed out by a list, or
+// that were handed out by a list sorted differently than the one they are used with.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// timeSorts are the sort columns holding timestamps, whose cursor values must parse
+// as one.
+var timeSorts = map[string]bool{"created_at": true, "updated_at": true}
+
+// Cursor marks the position of the last item of a page in a sorted list. It holds
+// the value of the sort column and the ID of that item, so the next page can start
+// right after it even if several items share the same sort value, along with the
+// sort it was handed out for.
+type Cursor struct {
+	Sort       string `json:"s"`
+	Descending bool   `json:"d,omitempty"`
+	Value      string `json:"v"`
+	ID         uint   `json:"id"`
+}
+
+// Encode returns the opaque string form of the cursor handed out to clients.
+func (c *Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a cursor previously returned by Encode.
+func DecodeCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == 0 || cursor.Sort == "" {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// ListOptions describes which page of a list to return, how it is sorted and how it
+// is filtered. Filters left nil are not applied, and not every list supports every
+// filter.
+type ListOptions struct {
+	// Sort is the column to sort by, which must be one of the model's valid sorts.
+	Sort       string
+	Descending bool
+	Limit      int
+	Cursor     *Cursor
+
+	Color        *string
+	CreatedAfter *time.Time
+	FolderID     *uint
+	ParentID     *uint
+	// LinkStatus is one of the model's link statuses, for lists of bookmarks.
+	LinkStatus *string
+}
+
+// Page describes the page of results returned by a list function.
+type Page struct {
+	// Total is the number of items matching the filters across all pages.
+	Total int
+	// Next is the cursor of the following page, or nil if this is the last one.
+	Next *Cursor
+}
+
+// sortColumn returns the column the list is sorted by, created_at by default.
+func (opts *ListOptions) sortColumn() string {
+	if opts.Sort == "" {
+		return "created_at"
+	}
+	return opts.Sort
+}
+
+// ValidateCursor returns ErrInvalidCursor if the cursor was handed out for a list
+// sorted by another column or in the other direction, or if its value does not suit
+// the sort column.
+func (opts *ListOptions) ValidateCursor() error {
+	cursor := opts.Cursor
+	if cursor == nil {
+		return nil
+	}
+	if cursor.Sort != opts.sortColumn() || cursor.Descending != opts.Descending {
+		return ErrInvalidCursor
+	}
+	if timeSorts[cursor.Sort] {
+		if _, err := time.Parse(time.RFC3339Nano, cursor.Value); err != nil {
+			return ErrInvalidCursor
+		}
+	}
+	return nil
+}
+
+// filter applies the filters shared by every list to a query on the given table.
+func (opts *ListOptions) filter(table string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if opts.Color != nil {
+			db = db.Where(table+".color = ?", *opts.Color)
+		}
+		if opts.CreatedAfter != nil {
+			db = db.Where(table+".created_at > ?", *opts.CreatedAfter)
+		}
+		return db
+	}
+}
+
+// paginate counts the items matched by query, then finds the page of them described
+// by opts into out, which must be a pointer to a slice of model pointers. The sort
+// column is validated against validSorts.
+func paginate(query *gorm.DB, table string, validSorts []string, opts *ListOptions, out interface{}) (*Page, error) {
+	sort := opts.sortColumn()
+	if !contains(validSorts, sort) {
+		return nil, fmt.Errorf("invalid sort %q", sort)
+	}
+	if err := opts.ValidateCursor(); err != nil {
+		return nil, err
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+	if limit > MaxListLimit {
+		limit = MaxListLimit
+	}
+
+	page := &Page{}
+	if err := query.Model(out).Count(&page.Total).Error; err != nil {
+		return nil, err
+	}
+
+	column := table + "." + sort
+	direction, comparison := "ASC", ">"
+	if opts.Descending {
+		direction, comparison = "DESC", "<"
+	}
+	if opts.Cursor != nil {
+		query = query.Where(fmt.Sprintf("(%s, %s.id) %s (?, ?)", column, table, comparison), opts.Cursor.Value, opts.Cursor.ID)
+	}
+	query = query.Order(fmt.Sprintf("%s %s, %s.id %s", column, direction, table, direction)).Limit(limit + 1)
+	if err := query.Find(out).Error; err != nil {
+		return nil, err
+	}
+
+	// one extra item is fetched to find out whether there is a next page.
+	items := reflect.ValueOf(out).Elem()
+	if items.Len() > limit {
+		last := items.Index(limit - 1).Interface()
+		items.Set(items.Slice(0, limit))
+		page.Next = cursorFor(query.NewScope(last), sort, opts.Descending)
+	}
+	return page, nil
+}
+
+func cursorFor(scope *gorm.Scope, sort string, descending bool) *Cursor {
+	cursor := &Cursor{Sort: sort, Descending: descending}
+	if field, ok := scope.FieldByName(sort); ok {
+		switch value := field.Field.Interface().(type) {
+		case time.Time:
+			cursor.Value = value.Format(time.RFC3339Nano)
+		default:
+			cursor.Value = fmt.Sprint(value)
+		}
+	}
+	if field, ok := scope.FieldByName("id"); ok {
+		cursor.ID = field.Field.Interface().(uint)
+	}
+	return cursor
+}
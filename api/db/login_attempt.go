@@ -0,0 +1,51 @@
+package db
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+
+	"leggett.dev/devmarks/api/model"
+)
+
+// CreateLoginAttempt inserts the specified login attempt into the database.
+func (db *Database) CreateLoginAttempt(attempt *model.LoginAttempt) error {
+	return errors.Wrap(db.Create(attempt).Error, "unable to record login attempt")
+}
+
+// CountFailedLoginsByIP returns the number of failed logins from the IP address since
+// the specified time.
+func (db *Database) CountFailedLoginsByIP(ip string, since time.Time) (int, error) {
+	var count int
+	err := db.Model(&model.LoginAttempt{}).Where("ip_address = ? AND NOT success AND created_at > ?", ip, since).Count(&count).Error
+	return count, errors.Wrap(err, "unable to count failed logins")
+}
+
+// GetFailedLoginsByEmail returns the number of failed logins for the email address
+// since the specified time or its last successful login, whichever is later, and the
+// time of the latest one.
+func (db *Database) GetFailedLoginsByEmail(email string, since time.Time) (int, time.Time, error) {
+	var success model.LoginAttempt
+	err := db.Where("email = ? AND success", email).Order("created_at DESC").First(&success).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return 0, time.Time{}, errors.Wrap(err, "unable to get last login")
+	}
+	if err == nil && success.CreatedAt.After(since) {
+		since = success.CreatedAt
+	}
+
+	failures := db.Model(&model.LoginAttempt{}).Where("email = ? AND NOT success AND created_at > ?", email, since)
+	var count int
+	if err := failures.Count(&count).Error; err != nil {
+		return 0, time.Time{}, errors.Wrap(err, "unable to count failed logins")
+	}
+	if count == 0 {
+		return 0, time.Time{}, nil
+	}
+	var latest model.LoginAttempt
+	if err := failures.Order("created_at DESC").First(&latest).Error; err != nil {
+		return 0, time.Time{}, errors.Wrap(err, "unable to get latest failed login")
+	}
+	return count, latest.CreatedAt, nil
+}
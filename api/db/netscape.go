@@ -0,0 +1,114 @@
+package db
+
+import (
+	"github.com/pkg/errors"
+
+	"leggett.dev/devmarks/api/model"
+	"leggett.dev/devmarks/api/netscape"
+)
+
+// ImportResult counts the resources created by an import.
+type ImportResult struct {
+	Folders   int `json:"folders"`
+	Bookmarks int `json:"bookmarks"`
+}
+
+// ImportNetscape creates the folders and bookmarks of a parsed Netscape bookmark file
+// for the specified user in a single transaction. Folders keep their nesting through
+// ParentID, and bookmarks keep their add dates and tags.
+func (db *Database) ImportNetscape(ownerID uint, root *netscape.Folder) (*ImportResult, error) {
+	result := &ImportResult{}
+	err := db.WithTransaction(func(tx *Database) error {
+		return tx.importNetscapeFolder(ownerID, root, nil, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (db *Database) importNetscapeFolder(ownerID uint, source *netscape.Folder, folder *model.Folder, result *ImportResult) error {
+	for _, b := range source.Bookmarks {
+		bookmark := &model.Bookmark{Name: b.Name, URL: b.URL, Notes: b.Description, OwnerID: ownerID}
+		bookmark.CreatedAt = b.AddDate
+		if err := db.CreateBookmark(bookmark); err != nil {
+			return err
+		}
+		if len(b.Tags) > 0 {
+			tags, err := db.FindOrCreateTags(ownerID, b.Tags)
+			if err != nil {
+				return err
+			}
+			if err := db.ReplaceBookmarkTags(bookmark, tags); err != nil {
+				return err
+			}
+		}
+		if folder != nil {
+			if err := db.Model(bookmark).Association("Folders").Append(folder).Error; err != nil {
+				return errors.Wrap(err, "unable to add bookmark to folder")
+			}
+		}
+		result.Bookmarks++
+	}
+
+	for _, f := range source.Folders {
+		child := &model.Folder{Name: f.Name, OwnerID: ownerID}
+		child.CreatedAt = f.AddDate
+		if folder != nil {
+			child.ParentID = &folder.ID
+		}
+		if err := db.CreateFolder(child); err != nil {
+			return err
+		}
+		result.Folders++
+		if err := db.importNetscapeFolder(ownerID, f, child, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportNetscape builds the Netscape bookmark file tree of everything owned by the
+// specified user. Bookmarks appear in every folder they belong to, and bookmarks in
+// no folder appear at the top level.
+func (db *Database) ExportNetscape(ownerID uint) (*netscape.Folder, error) {
+	var folders []*model.Folder
+	if err := db.Where("organization_id IS NULL").Order("created_at, id").Find(&folders, model.Folder{OwnerID: ownerID}).Error; err != nil {
+		return nil, errors.Wrap(err, "unable to get folders")
+	}
+	var bookmarks []*model.Bookmark
+	if err := db.Preload("Folders").Preload("Tags").Order("created_at, id").Find(&bookmarks, model.Bookmark{OwnerID: ownerID}).Error; err != nil {
+		return nil, errors.Wrap(err, "unable to get bookmarks")
+	}
+
+	root := &netscape.Folder{}
+	exported := make(map[uint]*netscape.Folder, len(folders))
+	for _, folder := range folders {
+		exported[folder.ID] = &netscape.Folder{Name: folder.Name, AddDate: folder.CreatedAt}
+	}
+	for _, folder := range folders {
+		parent := root
+		if folder.ParentID != nil && exported[*folder.ParentID] != nil {
+			parent = exported[*folder.ParentID]
+		}
+		parent.Folders = append(parent.Folders, exported[folder.ID])
+	}
+
+	for _, bookmark := range bookmarks {
+		b := &netscape.Bookmark{Name: bookmark.Name, URL: bookmark.URL, AddDate: bookmark.CreatedAt, Description: bookmark.Notes}
+		for _, tag := range bookmark.Tags {
+			b.Tags = append(b.Tags, tag.Name)
+		}
+		placed := false
+		for _, folder := range bookmark.Folders {
+			if parent, ok := exported[folder.ID]; ok {
+				parent.Bookmarks = append(parent.Bookmarks, b)
+				placed = true
+			}
+		}
+		if !placed {
+			root.Bookmarks = append(root.Bookmarks, b)
+		}
+	}
+	return root, nil
+}
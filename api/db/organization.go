@@ -0,0 +1,140 @@
+package db
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+
+	"leggett.dev/devmarks/api/model"
+)
+
+// CreateOrganization inserts the specified organization into the database, with its
+// owner as its first member.
+func (db *Database) CreateOrganization(organization *model.Organization) error {
+	return db.WithTransaction(func(tx *Database) error {
+		if err := tx.Create(organization).Error; err != nil {
+			return errors.Wrap(err, "unable to create organization")
+		}
+		return tx.CreateMembership(&model.Membership{OrganizationID: organization.ID, UserID: organization.OwnerID, Role: model.RoleOwner})
+	})
+}
+
+// GetOrganizationByID returns the organization with the specified ID from the database.
+func (db *Database) GetOrganizationByID(id uint) (*model.Organization, error) {
+	var organization model.Organization
+	return &organization, errors.Wrap(db.First(&organization, id).Error, "unable to get organization")
+}
+
+// GetOrganizationsByUserID returns the organizations the specified user is a member of.
+func (db *Database) GetOrganizationsByUserID(userID uint) ([]*model.Organization, error) {
+	var organizations []*model.Organization
+	err := db.Joins("JOIN organization_user ON organization_user.organization_id = organizations.id").
+		Where("organization_user.user_id = ?", userID).Order("organizations.name, organizations.id").Find(&organizations).Error
+	return organizations, errors.Wrap(err, "unable to get organizations")
+}
+
+// CountOwnedOrganizations returns the number of organizations the specified user owns.
+func (db *Database) CountOwnedOrganizations(userID uint) (int, error) {
+	var count int
+	err := db.Model(&model.Organization{}).Where("owner_id = ?", userID).Count(&count).Error
+	return count, errors.Wrap(err, "unable to count organizations")
+}
+
+// UpdateOrganization updates the specified organization in the database.
+func (db *Database) UpdateOrganization(organization *model.Organization) error {
+	return errors.Wrap(db.Save(organization).Error, "unable to update organization")
+}
+
+// DeleteOrganization deletes the specified organization and its folders, and removes
+// its members. The bookmarks in its folders are kept.
+func (db *Database) DeleteOrganization(organization *model.Organization) error {
+	return db.WithTransaction(func(tx *Database) error {
+		if err := tx.Exec("DELETE FROM bookmark_folder WHERE folder_id IN (SELECT id FROM folders WHERE organization_id = ?)", organization.ID).Error; err != nil {
+			return errors.Wrap(err, "unable to remove bookmarks from folders")
+		}
+		if err := tx.Where("organization_id = ?", organization.ID).Delete(&model.Folder{}).Error; err != nil {
+			return errors.Wrap(err, "unable to delete folders")
+		}
+		if err := tx.Where("organization_id = ?", organization.ID).Delete(&model.Membership{}).Error; err != nil {
+			return errors.Wrap(err, "unable to remove members")
+		}
+		return errors.Wrap(tx.Delete(organization).Error, "unable to delete organization")
+	})
+}
+
+// GetOrganizationFolders returns every folder owned by the specified organization.
+func (db *Database) GetOrganizationFolders(organizationID uint) ([]*model.Folder, error) {
+	var folders []*model.Folder
+	err := db.Where("organization_id = ?", organizationID).Order("name, id").Find(&folders).Error
+	return folders, errors.Wrap(err, "unable to get folders")
+}
+
+// GetMembers returns the members of the specified organization, preloading their users.
+func (db *Database) GetMembers(organizationID uint) ([]*model.Membership, error) {
+	var members []*model.Membership
+	err := db.Preload("User").Where("organization_id = ?", organizationID).Order("created_at, user_id").Find(&members).Error
+	return members, errors.Wrap(err, "unable to get members")
+}
+
+// GetMembership returns the membership of the specified user in the specified
+// organization, preloading the user.
+func (db *Database) GetMembership(organizationID, userID uint) (*model.Membership, error) {
+	var member model.Membership
+	err := db.Preload("User").Where("organization_id = ? AND user_id = ?", organizationID, userID).First(&member).Error
+	return &member, errors.Wrap(err, "unable to get member")
+}
+
+// CreateMembership inserts the specified membership into the database.
+func (db *Database) CreateMembership(member *model.Membership) error {
+	return errors.Wrap(db.Create(member).Error, "unable to add member")
+}
+
+// UpdateMembership updates the role of the specified membership in the database.
+func (db *Database) UpdateMembership(member *model.Membership) error {
+	err := db.Model(member).Where("organization_id = ? AND user_id = ?", member.OrganizationID, member.UserID).Update("role", member.Role).Error
+	return errors.Wrap(err, "unable to update member")
+}
+
+// DeleteMembership removes the specified user from the specified organization.
+func (db *Database) DeleteMembership(organizationID, userID uint) error {
+	return errors.Wrap(db.Where("organization_id = ? AND user_id = ?", organizationID, userID).Delete(&model.Membership{}).Error, "unable to remove member")
+}
+
+// GetMemberRole returns the role of the specified user in the specified organization,
+// or an empty string if they are not a member of it.
+func (db *Database) GetMemberRole(organizationID, userID uint) (string, error) {
+	var member model.Membership
+	err := db.Where("organization_id = ? AND user_id = ?", organizationID, userID).First(&member).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "unable to get member")
+	}
+	return member.Role, nil
+}
+
+const bookmarkMemberRolesSQL = `SELECT DISTINCT organization_user.role FROM organization_user
+	JOIN organizations ON organizations.id = organization_user.organization_id AND organizations.deleted_at IS NULL
+	JOIN folders ON folders.organization_id = organizations.id AND folders.deleted_at IS NULL
+	JOIN bookmark_folder ON bookmark_folder.folder_id = folders.id
+	WHERE bookmark_folder.bookmark_id = ? AND organization_user.user_id = ?`
+
+// GetBookmarkMemberRoles returns the roles the specified user has in the organizations
+// owning the folders the specified bookmark is in.
+func (db *Database) GetBookmarkMemberRoles(bookmarkID, userID uint) ([]string, error) {
+	rows, err := db.Raw(bookmarkMemberRolesSQL, bookmarkID, userID).Rows()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get member roles")
+	}
+	defer rows.Close()
+
+	var roles []string
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return nil, errors.Wrap(err, "unable to get member roles")
+		}
+		roles = append(roles, role)
+	}
+	return roles, errors.Wrap(rows.Err(), "unable to get member roles")
+}
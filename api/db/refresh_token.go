@@ -0,0 +1,38 @@
+package db
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"leggett.dev/devmarks/api/model"
+)
+
+// CreateRefreshToken inserts the specified refresh token into the database.
+func (db *Database) CreateRefreshToken(token *model.RefreshToken) error {
+	return errors.Wrap(db.Create(token).Error, "unable to create refresh token")
+}
+
+// GetRefreshTokenByHash returns the refresh token with the specified hash, preloading
+// its user.
+func (db *Database) GetRefreshTokenByHash(hash string) (*model.RefreshToken, error) {
+	var token model.RefreshToken
+	return &token, errors.Wrap(db.Preload("User").First(&token, model.RefreshToken{Hash: hash}).Error, "unable to get refresh token")
+}
+
+// MarkRefreshTokenUsed records that the refresh token with the specified ID was used.
+// It returns false if the token had already been used, so concurrent requests cannot
+// both exchange it.
+func (db *Database) MarkRefreshTokenUsed(id uint, usedAt time.Time) (bool, error) {
+	result := db.Model(&model.RefreshToken{}).Where("id = ? AND used_at IS NULL", id).UpdateColumn("used_at", usedAt)
+	if result.Error != nil {
+		return false, errors.Wrap(result.Error, "unable to update refresh token")
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RevokeRefreshTokenFamily revokes every refresh token of the specified family, if
+// it belongs to the specified user, ending that login session.
+func (db *Database) RevokeRefreshTokenFamily(userID uint, family string, revokedAt time.Time) error {
+	return errors.Wrap(db.Model(&model.RefreshToken{}).Where("user_id = ? AND family = ? AND revoked_at IS NULL", userID, family).UpdateColumn("revoked_at", revokedAt).Error, "unable to revoke refresh tokens")
+}
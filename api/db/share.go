@@ -0,0 +1,107 @@
+package db
+
+import (
+	"github.com/pkg/errors"
+
+	"leggett.dev/devmarks/api/model"
+)
+
+// GetShares returns the users the specified folder is shared with, preloading them.
+func (db *Database) GetShares(folderID uint) ([]*model.Share, error) {
+	var shares []*model.Share
+	err := db.Preload("User").Where("folder_id = ?", folderID).Order("created_at, user_id").Find(&shares).Error
+	return shares, errors.Wrap(err, "unable to get shares")
+}
+
+// GetShare returns the share of the specified folder with the specified user,
+// preloading the user.
+func (db *Database) GetShare(folderID, userID uint) (*model.Share, error) {
+	var share model.Share
+	err := db.Preload("User").Where("folder_id = ? AND user_id = ?", folderID, userID).First(&share).Error
+	return &share, errors.Wrap(err, "unable to get share")
+}
+
+// GetSharesByUserID returns the folders shared with the specified user, preloading
+// them. Shares of deleted folders are left out.
+func (db *Database) GetSharesByUserID(userID uint) ([]*model.Share, error) {
+	var shares []*model.Share
+	err := db.Preload("Folder").
+		Joins("JOIN folders ON folders.id = folder_user.folder_id AND folders.deleted_at IS NULL").
+		Where("folder_user.user_id = ?", userID).
+		Order("folders.name, folders.id").
+		Find(&shares).Error
+	return shares, errors.Wrap(err, "unable to get shares")
+}
+
+// CreateShare inserts the specified share into the database.
+func (db *Database) CreateShare(share *model.Share) error {
+	return errors.Wrap(db.Create(share).Error, "unable to share folder")
+}
+
+// UpdateShare updates the access of the specified share in the database.
+func (db *Database) UpdateShare(share *model.Share) error {
+	err := db.Model(share).Where("folder_id = ? AND user_id = ?", share.FolderID, share.UserID).Update("access", share.Access).Error
+	return errors.Wrap(err, "unable to update share")
+}
+
+// DeleteShare stops sharing the specified folder with the specified user.
+func (db *Database) DeleteShare(folderID, userID uint) error {
+	return errors.Wrap(db.Where("folder_id = ? AND user_id = ?", folderID, userID).Delete(&model.Share{}).Error, "unable to delete share")
+}
+
+// folderShareAccessSQL selects the access the user was given to the folder or any
+// folder above it. The depth bound keeps the query finite even if the data somehow
+// contains a cycle.
+const folderShareAccessSQL = `WITH RECURSIVE ancestors AS (
+	SELECT id, parent_id, 0 AS depth FROM folders WHERE id = ? AND deleted_at IS NULL
+	UNION ALL
+	SELECT folders.id, folders.parent_id, ancestors.depth + 1
+	FROM folders JOIN ancestors ON folders.id = ancestors.parent_id
+	WHERE folders.deleted_at IS NULL AND ancestors.depth < 100
+) SELECT DISTINCT folder_user.access FROM folder_user
+	JOIN ancestors ON ancestors.id = folder_user.folder_id
+	WHERE folder_user.user_id = ?`
+
+// bookmarkShareAccessSQL selects the access the user was given to any folder the
+// bookmark is in, or any folder above those.
+const bookmarkShareAccessSQL = `WITH RECURSIVE ancestors AS (
+	SELECT folders.id, folders.parent_id, 0 AS depth FROM folders
+	JOIN bookmark_folder ON bookmark_folder.folder_id = folders.id
+	WHERE bookmark_folder.bookmark_id = ? AND folders.deleted_at IS NULL
+	UNION ALL
+	SELECT folders.id, folders.parent_id, ancestors.depth + 1
+	FROM folders JOIN ancestors ON folders.id = ancestors.parent_id
+	WHERE folders.deleted_at IS NULL AND ancestors.depth < 100
+) SELECT DISTINCT folder_user.access FROM folder_user
+	JOIN ancestors ON ancestors.id = folder_user.folder_id
+	WHERE folder_user.user_id = ?`
+
+// GetFolderShareAccess returns the access levels the specified user was given to the
+// specified folder, by sharing either it or a folder above it.
+func (db *Database) GetFolderShareAccess(folderID, userID uint) ([]string, error) {
+	return db.getShareAccess(folderShareAccessSQL, folderID, userID)
+}
+
+// GetBookmarkShareAccess returns the access levels the specified user was given to
+// the folders the specified bookmark is in, by sharing them or a folder above them.
+func (db *Database) GetBookmarkShareAccess(bookmarkID, userID uint) ([]string, error) {
+	return db.getShareAccess(bookmarkShareAccessSQL, bookmarkID, userID)
+}
+
+func (db *Database) getShareAccess(sql string, id, userID uint) ([]string, error) {
+	rows, err := db.Raw(sql, id, userID).Rows()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get share access")
+	}
+	defer rows.Close()
+
+	var access []string
+	for rows.Next() {
+		var a string
+		if err := rows.Scan(&a); err != nil {
+			return nil, errors.Wrap(err, "unable to get share access")
+		}
+		access = append(access, a)
+	}
+	return access, errors.Wrap(rows.Err(), "unable to get share access")
+}
@@ -0,0 +1,119 @@
+package db
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"leggett.dev/devmarks/api/helpers"
+	"leggett.dev/devmarks/api/model"
+)
+
+// ErrDuplicateTag is returned when a tag would share its name with another tag
+// owned by the same user.
+var ErrDuplicateTag = errors.New("duplicate tag")
+
+// CreateTag inserts the specified tag into the database.
+func (db *Database) CreateTag(tag *model.Tag) error {
+	if err := db.Create(tag).Error; err != nil {
+		if strings.Contains(err.Error(), "duplicate") {
+			return ErrDuplicateTag
+		}
+		return errors.Wrap(err, "unable to create tag")
+	}
+	return nil
+}
+
+// GetTagsByUserID returns all the tags from the database that are owned by the
+// user corresponding to the userID provided.
+func (db *Database) GetTagsByUserID(ctx context.Context, userID uint) ([]*model.Tag, error) {
+	var tags []*model.Tag
+	embeds, ok := ctx.Value(helpers.EmbedsKey).([]string)
+	if !ok {
+		return nil, errors.New("embeds parsing error")
+	}
+	return tags, errors.Wrap(db.preloadEmbeds(model.TagValidEmbeds(), embeds).Order("name").Find(&tags, model.Tag{OwnerID: userID}).Error, "unable to get tags")
+}
+
+// GetTagByID queries the database for a tag with the specified id
+func (db *Database) GetTagByID(ctx context.Context, id uint) (*model.Tag, error) {
+	var tag model.Tag
+	embeds, ok := ctx.Value(helpers.EmbedsKey).([]string)
+	if !ok {
+		return nil, errors.New("embeds parsing error")
+	}
+	return &tag, errors.Wrap(db.preloadEmbeds(model.TagValidEmbeds(), embeds).First(&tag, id).Error, "unable to get tag")
+}
+
+// UpdateTag updates the specified tag in the database.
+func (db *Database) UpdateTag(tag *model.Tag) error {
+	if err := db.Save(tag).Error; err != nil {
+		if strings.Contains(err.Error(), "duplicate") {
+			return ErrDuplicateTag
+		}
+		return errors.Wrap(err, "unable to update tag")
+	}
+	return nil
+}
+
+// DeleteTagByID detaches the tag with the specified ID from all of its bookmarks
+// and deletes it from the database.
+func (db *Database) DeleteTagByID(id uint) error {
+	tag := model.Tag{Model: model.Model{ID: id}}
+	if err := db.Model(&tag).Association("Bookmarks").Clear().Error; err != nil {
+		return errors.Wrap(err, "unable to detach tag from bookmarks")
+	}
+	return errors.Wrap(db.Delete(&tag).Error, "unable to delete tag")
+}
+
+// FindOrCreateTags returns the tags owned by the specified user with the given names,
+// creating any that do not exist yet.
+func (db *Database) FindOrCreateTags(ownerID uint, names []string) ([]model.Tag, error) {
+	tags := make([]model.Tag, 0, len(names))
+	for _, name := range names {
+		var tag model.Tag
+		if err := db.Where(model.Tag{Name: name, OwnerID: ownerID}).FirstOrCreate(&tag).Error; err != nil {
+			return nil, errors.Wrap(err, "unable to find or create tag")
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// ReplaceBookmarkTags sets the tags of the specified bookmark to exactly the given
+// tags, attaching new ones and detaching any that are no longer present.
+func (db *Database) ReplaceBookmarkTags(bookmark *model.Bookmark, tags []model.Tag) error {
+	association := db.Model(bookmark).Association("Tags")
+	if len(tags) == 0 {
+		return errors.Wrap(association.Clear().Error, "unable to detach tags from bookmark")
+	}
+	return errors.Wrap(association.Replace(tags).Error, "unable to attach tags to bookmark")
+}
+
+// AddTagsToBookmark attaches the given tags to the specified bookmark, keeping the
+// tags it already has.
+func (db *Database) AddTagsToBookmark(bookmark *model.Bookmark, tags []model.Tag) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	return errors.Wrap(db.Model(bookmark).Association("Tags").Append(tags).Error, "unable to attach tags to bookmark")
+}
+
+// RemoveTagsFromBookmark detaches the given tags from the specified bookmark.
+func (db *Database) RemoveTagsFromBookmark(bookmark *model.Bookmark, tags []model.Tag) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	return errors.Wrap(db.Model(bookmark).Association("Tags").Delete(tags).Error, "unable to detach tags from bookmark")
+}
+
+// GetTagsByName returns the tags owned by the specified user with the given names,
+// skipping names that do not exist.
+func (db *Database) GetTagsByName(ownerID uint, names []string) ([]model.Tag, error) {
+	var tags []model.Tag
+	if len(names) == 0 {
+		return tags, nil
+	}
+	return tags, errors.Wrap(db.Where("owner_id = ? AND name IN (?)", ownerID, names).Find(&tags).Error, "unable to get tags")
+}
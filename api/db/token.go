@@ -0,0 +1,45 @@
+package db
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"leggett.dev/devmarks/api/model"
+)
+
+// CreateToken inserts the specified token into the database.
+func (db *Database) CreateToken(token *model.Token) error {
+	return errors.Wrap(db.Create(token).Error, "unable to create token")
+}
+
+// GetTokenByHash returns the token with the specified hash, preloading its user.
+func (db *Database) GetTokenByHash(hash string) (*model.Token, error) {
+	var token model.Token
+	return &token, errors.Wrap(db.Preload("User").First(&token, model.Token{Hash: hash}).Error, "unable to get token")
+}
+
+// GetTokenByID queries the database for a token with the specified id
+func (db *Database) GetTokenByID(id uint) (*model.Token, error) {
+	var token model.Token
+	return &token, errors.Wrap(db.First(&token, id).Error, "unable to get token")
+}
+
+// GetTokensByUserID returns all the tokens of the user corresponding to the userID
+// provided, most recently created first.
+func (db *Database) GetTokensByUserID(userID uint) ([]*model.Token, error) {
+	var tokens []*model.Token
+	return tokens, errors.Wrap(db.Order("created_at DESC").Find(&tokens, model.Token{UserID: userID}).Error, "unable to get tokens")
+}
+
+// TouchToken records that the token with the specified ID was used at the given time.
+func (db *Database) TouchToken(id uint, usedAt time.Time) error {
+	token := model.Token{Model: model.Model{ID: id}}
+	return errors.Wrap(db.Model(&token).UpdateColumn("last_used_at", usedAt).Error, "unable to update token")
+}
+
+// DeleteTokenByID deletes the token with the specified ID from the database, so it
+// can no longer be used.
+func (db *Database) DeleteTokenByID(id uint) error {
+	return errors.Wrap(db.Delete(&model.Token{Model: model.Model{ID: id}}).Error, "unable to delete token")
+}
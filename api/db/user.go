@@ -2,6 +2,7 @@ package db
 
 import (
 	"strings"
+	"time"
 
 	"github.com/jinzhu/gorm"
 	"github.com/pkg/errors"
@@ -41,6 +42,41 @@ func (db *Database) CreateUser(user *model.User) error {
 		if strings.Contains(err.Error(), "duplicate") {
 			return errors.New("duplicate user")
 		}
+		return errors.Wrap(err, "unable to create user")
 	}
 	return nil
 }
+
+// UpdateUser updates the specified user in the database.
+func (db *Database) UpdateUser(user *model.User) error {
+	return errors.Wrap(db.Save(user).Error, "unable to update user")
+}
+
+// RevokeUserLogins deletes every token and revokes every refresh token of the specified
+// user, signing them out everywhere.
+func (db *Database) RevokeUserLogins(userID uint, at time.Time) error {
+	return db.RevokeOtherUserLogins(userID, 0, "", at)
+}
+
+// RevokeOtherUserLogins signs the specified user out everywhere except with the token
+// with the specified ID and the login session with the specified refresh token family.
+func (db *Database) RevokeOtherUserLogins(userID, tokenID uint, family string, at time.Time) error {
+	if err := db.Where("user_id = ? AND id <> ?", userID, tokenID).Delete(&model.Token{}).Error; err != nil {
+		return errors.Wrap(err, "unable to delete tokens")
+	}
+	err := db.Model(&model.RefreshToken{}).Where("user_id = ? AND family <> ? AND revoked_at IS NULL", userID, family).UpdateColumn("revoked_at", at).Error
+	return errors.Wrap(err, "unable to revoke refresh tokens")
+}
+
+// DeleteUser soft-deletes the specified user from the database. Their single sign-on
+// identities and organization memberships are removed, so the provider accounts can be
+// signed in with again and the organizations do not list them.
+func (db *Database) DeleteUser(user *model.User) error {
+	if err := db.Unscoped().Where("user_id = ?", user.ID).Delete(&model.Identity{}).Error; err != nil {
+		return errors.Wrap(err, "unable to delete identities")
+	}
+	if err := db.Where("user_id = ?", user.ID).Delete(&model.Membership{}).Error; err != nil {
+		return errors.Wrap(err, "unable to remove memberships")
+	}
+	return errors.Wrap(db.Delete(user).Error, "unable to delete user")
+}
@@ -0,0 +1,45 @@
+// Package feed writes syndication feeds of bookmarks as Atom, RSS 2.0 and JSON Feed
+// 1.1 documents, so they can be followed in feed readers.
+package feed
+
+import "time"
+
+// Content types of the feed formats, to serve them with.
+const (
+	ContentTypeAtom = "application/atom+xml; charset=utf-8"
+	ContentTypeRSS  = "application/rss+xml; charset=utf-8"
+	ContentTypeJSON = "application/feed+json; charset=utf-8"
+)
+
+// Feed is a feed of items, whatever the format it is written in.
+type Feed struct {
+	// ID is a permanent, unique URL identifying the feed.
+	ID    string
+	Title string
+	// Link is the page of the web client the feed is about.
+	Link string
+	// FeedURL is where the feed itself is served.
+	FeedURL string
+	Updated time.Time
+	Items   []*Item
+}
+
+// Item is an entry of a feed.
+type Item struct {
+	// ID is a permanent, unique URL identifying the item.
+	ID        string
+	Title     string
+	URL       string
+	Content   string
+	Published time.Time
+	Updated   time.Time
+	Tags      []string
+}
+
+// title returns the title of the item, or its URL if it has none.
+func (i *Item) title() string {
+	if i.Title != "" {
+		return i.Title
+	}
+	return i.URL
+}
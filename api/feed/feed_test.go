@@ -0,0 +1,163 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+	"time"
+)
+
+func testFeed() *Feed {
+	published := time.Date(2021, 9, 5, 9, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+	return &Feed{
+		ID:      "https://devmarks.local/folders/1",
+		Title:   "Go & <friends>",
+		Link:    "https://devmarks.local/folders/1",
+		FeedURL: "https://api.devmarks.local/folders/1/feed.atom?token=dmf_x",
+		Updated: published.Add(time.Hour),
+		Items: []*Item{
+			{
+				ID:        "https://devmarks.local/bookmarks/2",
+				Title:     "The Go Programming Language",
+				URL:       "https://golang.org/?a=1&b=2",
+				Content:   "Notes about <Go>",
+				Published: published,
+				Updated:   published.Add(time.Hour),
+				Tags:      []string{"go", "lang"},
+			},
+			{
+				ID:        "https://devmarks.local/bookmarks/3",
+				URL:       "https://go.dev",
+				Published: published,
+				Updated:   published,
+			},
+		},
+	}
+}
+
+func TestWriteAtom(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteAtom(&buf, testFeed()); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		XMLName xml.Name
+		Title   string `xml:"title"`
+		Updated string `xml:"updated"`
+		Entries []struct {
+			Title string `xml:"title"`
+			Link  struct {
+				Href string `xml:"href,attr"`
+			} `xml:"link"`
+			Published  string `xml:"published"`
+			Summary    string `xml:"summary"`
+			Categories []struct {
+				Term string `xml:"term,attr"`
+			} `xml:"category"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid Atom document: %v\n%s", err, buf.String())
+	}
+	if doc.XMLName.Space != atomNamespace || doc.XMLName.Local != "feed" {
+		t.Errorf("root element = %v, want an Atom feed", doc.XMLName)
+	}
+	if doc.Title != "Go & <friends>" || doc.Updated != "2021-09-05T08:00:00Z" {
+		t.Errorf("feed title, updated = %q, %q", doc.Title, doc.Updated)
+	}
+	if len(doc.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(doc.Entries))
+	}
+	first := doc.Entries[0]
+	if first.Link.Href != "https://golang.org/?a=1&b=2" || first.Published != "2021-09-05T07:00:00Z" ||
+		first.Summary != "Notes about <Go>" || len(first.Categories) != 2 || first.Categories[0].Term != "go" {
+		t.Errorf("first entry = %+v", first)
+	}
+	// items without a title are titled with their URL.
+	if doc.Entries[1].Title != "https://go.dev" {
+		t.Errorf("untitled entry title = %q, want its URL", doc.Entries[1].Title)
+	}
+}
+
+func TestWriteRSS(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteRSS(&buf, testFeed()); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		XMLName xml.Name
+		Version string `xml:"version,attr"`
+		Channel struct {
+			Title         string `xml:"title"`
+			LastBuildDate string `xml:"lastBuildDate"`
+			Items         []struct {
+				Title      string   `xml:"title"`
+				Link       string   `xml:"link"`
+				GUID       string   `xml:"guid"`
+				PubDate    string   `xml:"pubDate"`
+				Categories []string `xml:"category"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid RSS document: %v\n%s", err, buf.String())
+	}
+	if doc.XMLName.Local != "rss" || doc.Version != "2.0" {
+		t.Errorf("root element = %v version %q, want RSS 2.0", doc.XMLName, doc.Version)
+	}
+	if doc.Channel.Title != "Go & <friends>" || doc.Channel.LastBuildDate != "Sun, 05 Sep 2021 08:00:00 +0000" {
+		t.Errorf("channel title, last build date = %q, %q", doc.Channel.Title, doc.Channel.LastBuildDate)
+	}
+	if len(doc.Channel.Items) != 2 {
+		t.Fatalf("got %d items, want 2", len(doc.Channel.Items))
+	}
+	first := doc.Channel.Items[0]
+	if first.Link != "https://golang.org/?a=1&b=2" || first.GUID != "https://devmarks.local/bookmarks/2" ||
+		first.PubDate != "Sun, 05 Sep 2021 07:00:00 +0000" || len(first.Categories) != 2 {
+		t.Errorf("first item = %+v", first)
+	}
+	if doc.Channel.Items[1].Title != "https://go.dev" {
+		t.Errorf("untitled item title = %q, want its URL", doc.Channel.Items[1].Title)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, testFeed()); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Version string `json:"version"`
+		Title   string `json:"title"`
+		FeedURL string `json:"feed_url"`
+		Items   []struct {
+			URL           string   `json:"url"`
+			Title         string   `json:"title"`
+			ContentText   string   `json:"content_text"`
+			DatePublished string   `json:"date_published"`
+			Tags          []string `json:"tags"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid JSON Feed: %v\n%s", err, buf.String())
+	}
+	if doc.Version != jsonFeedVersion || doc.Title != "Go & <friends>" || doc.FeedURL != testFeed().FeedURL {
+		t.Errorf("feed = %+v", doc)
+	}
+	if bytes.Contains(buf.Bytes(), []byte(`\u0026`)) {
+		t.Error("WriteJSON() escaped HTML characters")
+	}
+	if len(doc.Items) != 2 {
+		t.Fatalf("got %d items, want 2", len(doc.Items))
+	}
+	first := doc.Items[0]
+	if first.ContentText != "Notes about <Go>" || first.DatePublished != "2021-09-05T07:00:00Z" || len(first.Tags) != 2 {
+		t.Errorf("first item = %+v", first)
+	}
+	// items must have content and a title, so untitled bookmarks without notes use
+	// their URL for both.
+	if second := doc.Items[1]; second.Title != "https://go.dev" || second.ContentText != "https://go.dev" {
+		t.Errorf("second item = %+v, want its URL as title and content", second)
+	}
+}
@@ -0,0 +1,58 @@
+package feed
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+const jsonFeedVersion = "https://jsonfeed.org/version/1.1"
+
+type jsonFeed struct {
+	Version     string      `json:"version"`
+	Title       string      `json:"title"`
+	HomePageURL string      `json:"home_page_url"`
+	FeedURL     string      `json:"feed_url"`
+	Items       []*jsonItem `json:"items"`
+}
+
+type jsonItem struct {
+	ID            string   `json:"id"`
+	URL           string   `json:"url"`
+	Title         string   `json:"title"`
+	ContentText   string   `json:"content_text"`
+	DatePublished string   `json:"date_published"`
+	DateModified  string   `json:"date_modified"`
+	Tags          []string `json:"tags,omitempty"`
+}
+
+// WriteJSON writes the feed to w as a JSON Feed 1.1 document.
+func WriteJSON(w io.Writer, f *Feed) error {
+	doc := &jsonFeed{
+		Version:     jsonFeedVersion,
+		Title:       f.Title,
+		HomePageURL: f.Link,
+		FeedURL:     f.FeedURL,
+		Items:       []*jsonItem{},
+	}
+	for _, item := range f.Items {
+		// items must have content, so bookmarks without notes fall back to their URL.
+		content := item.Content
+		if content == "" {
+			content = item.URL
+		}
+		doc.Items = append(doc.Items, &jsonItem{
+			ID:            item.ID,
+			URL:           item.URL,
+			Title:         item.title(),
+			ContentText:   content,
+			DatePublished: item.Published.UTC().Format(time.RFC3339),
+			DateModified:  item.Updated.UTC().Format(time.RFC3339),
+			Tags:          item.Tags,
+		})
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
@@ -0,0 +1,117 @@
+// Package fetcher fetches web pages and extracts the metadata describing them: their
+// title, description, favicon, canonical URL and Open Graph image.
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Defaults for the Config settings left unset.
+const (
+	DefaultTimeout   = 10 * time.Second
+	DefaultMaxBytes  = 1 << 20
+	DefaultUserAgent = "devmarks (+https://leggett.dev/devmarks)"
+)
+
+// ErrNotHTML is returned when the fetched URL is not an HTML page, so there is no
+// metadata to extract.
+var ErrNotHTML = errors.New("not an html page")
+
+// StatusError is returned when the fetched URL responds with an unsuccessful status.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d", e.StatusCode)
+}
+
+// Config limits what a Fetcher does.
+type Config struct {
+	// Timeout bounds the whole fetch, including reading the page.
+	Timeout time.Duration
+	// MaxBytes is the most of a page that is read; metadata is expected near the top.
+	MaxBytes int64
+	// UserAgent is sent with every request.
+	UserAgent string
+}
+
+// Metadata describes a web page. Fields the page does not provide are empty, and every
+// URL is absolute.
+type Metadata struct {
+	Title        string
+	Description  string
+	FaviconURL   string
+	CanonicalURL string
+	ImageURL     string
+}
+
+// Fetcher fetches pages with an http.Client and extracts their metadata.
+type Fetcher struct {
+	client *http.Client
+	config Config
+}
+
+// New returns a Fetcher making its requests with client, or http.DefaultClient if it
+// is nil, within the limits of config.
+func New(client *http.Client, config Config) *Fetcher {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if config.Timeout == 0 {
+		config.Timeout = DefaultTimeout
+	}
+	if config.MaxBytes == 0 {
+		config.MaxBytes = DefaultMaxBytes
+	}
+	if config.UserAgent == "" {
+		config.UserAgent = DefaultUserAgent
+	}
+	return &Fetcher{client: client, config: config}
+}
+
+// Fetch fetches the page at rawURL and returns its metadata. Only http and https URLs
+// answering with an HTML page are accepted.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*Metadata, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported url scheme %q", u.Scheme)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, f.config.Timeout)
+	defer cancel()
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("User-Agent", f.config.UserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &StatusError{StatusCode: resp.StatusCode}
+	}
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || (mediaType != "text/html" && mediaType != "application/xhtml+xml") {
+		return nil, ErrNotHTML
+	}
+
+	// redirects were followed, so relative URLs are relative to where they ended.
+	return parse(io.LimitReader(resp.Body, f.config.MaxBytes), resp.Request.URL)
+}
@@ -0,0 +1,242 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newServer serves the given pages, each with its content type and body, at their
+// paths.
+func newServer(t *testing.T, pages map[string]page) *httptest.Server {
+	mux := http.NewServeMux()
+	for path, p := range pages {
+		p := p
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			if p.handler != nil {
+				p.handler(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", p.contentType)
+			if p.status != 0 {
+				w.WriteHeader(p.status)
+			}
+			fmt.Fprint(w, p.body)
+		})
+	}
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+type page struct {
+	contentType string
+	status      int
+	body        string
+	handler     http.HandlerFunc
+}
+
+func TestFetch(t *testing.T) {
+	server := newServer(t, map[string]page{
+		"/full": {contentType: "text/html; charset=utf-8", body: `<!doctype html>
+<html><head>
+<title>  Devmarks
+  Bookmarks </title>
+<meta name="description" content="Save your bookmarks">
+<meta property="og:title" content="Devmarks on Open Graph">
+<meta property="og:description" content="Open Graph description">
+<meta property="og:image" content="/images/card.png">
+<link rel="shortcut icon" href="/static/favicon.png">
+<link rel="canonical" href="https://devmarks.app/">
+</head><body><title>Not the title</title></body></html>`},
+		"/open-graph": {contentType: "text/html", body: `<html><head>
+<meta property="og:title" content="Open Graph title">
+<meta property="og:description" content="Open Graph description">
+<meta property="og:url" content="https://devmarks.app/og">
+<meta property="og:image:url" content="https://cdn.example.com/card.png">
+</head></html>`},
+		"/docs/base": {contentType: "application/xhtml+xml", body: `<html><head>
+<base href="/assets/">
+<title>Base</title>
+<meta property="og:image" content="card.png">
+<link rel="icon" href="icon.png">
+<link rel="canonical" href="../docs/base">
+</head></html>`},
+		"/bare": {contentType: "text/html", body: `<p>no head at all`},
+		"/unsafe": {contentType: "text/html", body: `<head>
+<link rel="icon" href="javascript:alert(1)">
+<meta property="og:image" content="data:image/png;base64,AAAA">
+</head>`},
+		"/redirect": {handler: func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "/docs/base", http.StatusFound)
+		}},
+	})
+
+	tests := []struct {
+		path string
+		want Metadata
+	}{
+		{
+			path: "/full",
+			want: Metadata{
+				Title:        "Devmarks Bookmarks",
+				Description:  "Save your bookmarks",
+				FaviconURL:   server.URL + "/static/favicon.png",
+				CanonicalURL: "https://devmarks.app/",
+				ImageURL:     server.URL + "/images/card.png",
+			},
+		},
+		{
+			path: "/open-graph",
+			want: Metadata{
+				Title:        "Open Graph title",
+				Description:  "Open Graph description",
+				FaviconURL:   server.URL + "/favicon.ico",
+				CanonicalURL: "https://devmarks.app/og",
+				ImageURL:     "https://cdn.example.com/card.png",
+			},
+		},
+		{
+			path: "/docs/base",
+			want: Metadata{
+				Title:        "Base",
+				FaviconURL:   server.URL + "/assets/icon.png",
+				CanonicalURL: server.URL + "/docs/base",
+				ImageURL:     server.URL + "/assets/card.png",
+			},
+		},
+		{
+			// relative URLs resolve against the page redirected to.
+			path: "/redirect",
+			want: Metadata{
+				Title:        "Base",
+				FaviconURL:   server.URL + "/assets/icon.png",
+				CanonicalURL: server.URL + "/docs/base",
+				ImageURL:     server.URL + "/assets/card.png",
+			},
+		},
+		{
+			path: "/bare",
+			want: Metadata{FaviconURL: server.URL + "/favicon.ico"},
+		},
+		{
+			// a declared icon that isn't http(s) is dropped rather than guessed.
+			path: "/unsafe",
+			want: Metadata{},
+		},
+	}
+	f := New(server.Client(), Config{})
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			got, err := f.Fetch(context.Background(), server.URL+test.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got != test.want {
+				t.Errorf("Fetch() = %+v, want %+v", *got, test.want)
+			}
+		})
+	}
+}
+
+func TestFetchErrors(t *testing.T) {
+	server := newServer(t, map[string]page{
+		"/json":      {contentType: "application/json", body: `{"title": "json"}`},
+		"/image":     {contentType: "image/png", body: "png"},
+		"/no-type":   {handler: func(w http.ResponseWriter, r *http.Request) { w.Header()["Content-Type"] = nil }},
+		"/not-found": {contentType: "text/html", status: http.StatusNotFound, body: "<title>Not Found</title>"},
+		"/error":     {contentType: "text/html", status: http.StatusInternalServerError},
+	})
+	f := New(server.Client(), Config{})
+
+	for _, path := range []string{"/json", "/image", "/no-type"} {
+		t.Run(path, func(t *testing.T) {
+			if _, err := f.Fetch(context.Background(), server.URL+path); err != ErrNotHTML {
+				t.Errorf("Fetch() error = %v, want ErrNotHTML", err)
+			}
+		})
+	}
+
+	for path, status := range map[string]int{"/not-found": http.StatusNotFound, "/error": http.StatusInternalServerError} {
+		t.Run(path, func(t *testing.T) {
+			_, err := f.Fetch(context.Background(), server.URL+path)
+			var statusErr *StatusError
+			if !errors.As(err, &statusErr) || statusErr.StatusCode != status {
+				t.Errorf("Fetch() error = %v, want a *StatusError with status %d", err, status)
+			}
+		})
+	}
+
+	for _, rawURL := range []string{"ftp://example.com/", "file:///etc/passwd", "javascript:alert(1)"} {
+		t.Run(rawURL, func(t *testing.T) {
+			if _, err := f.Fetch(context.Background(), rawURL); err == nil {
+				t.Error("Fetch() succeeded, want an unsupported scheme error")
+			}
+		})
+	}
+}
+
+func TestFetchMaxBytes(t *testing.T) {
+	padding := strings.Repeat(" ", 200)
+	server := newServer(t, map[string]page{
+		"/long": {contentType: "text/html", body: "<head><title>Early</title>" + padding + `<meta name="description" content="Late"></head>`},
+	})
+
+	f := New(server.Client(), Config{MaxBytes: 100})
+	got, err := f.Fetch(context.Background(), server.URL+"/long")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != "Early" || got.Description != "" {
+		t.Errorf("Fetch() = %+v, want the title but not the description past MaxBytes", *got)
+	}
+
+	f = New(server.Client(), Config{})
+	if got, _ = f.Fetch(context.Background(), server.URL+"/long"); got.Description != "Late" {
+		t.Errorf("Fetch() without a limit = %+v, want the description", *got)
+	}
+}
+
+func TestFetchTruncatesText(t *testing.T) {
+	title := strings.Repeat("é", maxTitleLength)
+	server := newServer(t, map[string]page{
+		"/": {contentType: "text/html", body: "<title>" + title + "</title>"},
+	})
+
+	got, err := New(server.Client(), Config{}).Fetch(context.Background(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// é is two bytes long, so the limit falls in the middle of one.
+	if len(got.Title) != maxTitleLength || !strings.HasPrefix(title, got.Title) {
+		t.Errorf("Fetch() title is %d bytes, want %d bytes of whole characters", len(got.Title), maxTitleLength)
+	}
+}
+
+func TestFetchTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := newServer(t, map[string]page{
+		"/slow": {handler: func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		}},
+	})
+	defer close(release)
+
+	f := New(server.Client(), Config{Timeout: 50 * time.Millisecond})
+	start := time.Now()
+	_, err := f.Fetch(context.Background(), server.URL+"/slow")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Fetch() error = %v, want a deadline exceeded error", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Fetch() took %v, want it to give up after the timeout", elapsed)
+	}
+}
@@ -0,0 +1,135 @@
+package fetcher
+
+import (
+	"io"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// Limits on the length of the text extracted from a page.
+const (
+	maxTitleLength       = 300
+	maxDescriptionLength = 1000
+)
+
+// parse extracts the metadata from the head of an HTML page served at pageURL. It
+// stops at the body, as metadata is only expected in the head.
+func parse(r io.Reader, pageURL *url.URL) (*Metadata, error) {
+	z := html.NewTokenizer(r)
+	base := pageURL
+	var title, ogTitle, description, ogDescription, favicon, canonical, ogURL, image string
+
+loop:
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			if z.Err() == io.EOF {
+				break loop
+			}
+			return nil, z.Err()
+		case html.EndTagToken:
+			if name, _ := z.TagName(); string(name) == "head" {
+				break loop
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+			switch t.Data {
+			case "body":
+				break loop
+			case "base":
+				if u, err := pageURL.Parse(attr(t, "href")); err == nil && attr(t, "href") != "" {
+					base = u
+				}
+			case "title":
+				if title == "" && tt == html.StartTagToken && z.Next() == html.TextToken {
+					title = string(z.Text())
+				}
+			case "meta":
+				content := attr(t, "content")
+				switch strings.ToLower(attr(t, "property") + attr(t, "name")) {
+				case "description":
+					description = content
+				case "og:title":
+					ogTitle = content
+				case "og:description":
+					ogDescription = content
+				case "og:image", "og:image:url":
+					if image == "" {
+						image = content
+					}
+				case "og:url":
+					ogURL = content
+				}
+			case "link":
+				rels := strings.Fields(strings.ToLower(attr(t, "rel")))
+				for _, rel := range rels {
+					switch rel {
+					case "icon":
+						if favicon == "" {
+							favicon = attr(t, "href")
+						}
+					case "canonical":
+						canonical = attr(t, "href")
+					}
+				}
+			}
+		}
+	}
+
+	metadata := &Metadata{
+		Title:        clean(firstNonEmpty(title, ogTitle), maxTitleLength),
+		Description:  clean(firstNonEmpty(description, ogDescription), maxDescriptionLength),
+		FaviconURL:   resolve(base, firstNonEmpty(favicon, "/favicon.ico")),
+		CanonicalURL: resolve(base, firstNonEmpty(canonical, ogURL)),
+		ImageURL:     resolve(base, image),
+	}
+	return metadata, nil
+}
+
+func attr(t html.Token, name string) string {
+	for _, a := range t.Attr {
+		if a.Key == name {
+			return strings.TrimSpace(a.Val)
+		}
+	}
+	return ""
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// clean collapses the whitespace of text taken from a page and shortens it to at most
+// max bytes, without splitting a character.
+func clean(text string, max int) string {
+	text = strings.Join(strings.Fields(strings.ToValidUTF8(text, "")), " ")
+	if len(text) <= max {
+		return text
+	}
+	for max > 0 && !utf8.RuneStart(text[max]) {
+		max--
+	}
+	return text[:max]
+}
+
+// resolve returns the absolute http or https URL ref points to from base, or an empty
+// string if there is none.
+func resolve(base *url.URL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+	u, err := base.Parse(ref)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return u.String()
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/base64"
 	"fmt"
 	"net/http"
+	"net/url"
 	"runtime/debug"
 	"strings"
 	"time"
@@ -16,6 +17,7 @@ import (
 type Logger interface {
 	WithError(error) *logrus.Entry
 	LoggerMiddleware(http.Handler) http.Handler
+	IPAddressForRequest(*http.Request) string
 }
 
 type logSvc struct {
@@ -66,6 +68,12 @@ func (lrw *loggingResponseWriter) WriteHeader(code int) {
 	lrw.wroteHeader = true
 }
 
+// IPAddressForRequest returns the address of the client that made the request, taking
+// the configured number of proxies into account.
+func (l *logSvc) IPAddressForRequest(r *http.Request) string {
+	return l.ipAddressForRequest(r)
+}
+
 func (l *logSvc) ipAddressForRequest(r *http.Request) string {
 	addr := r.RemoteAddr
 	if l.ProxyCount > 0 {
@@ -88,6 +96,29 @@ func (l *logSvc) ipAddressForRequest(r *http.Request) string {
 	return strings.Split(strings.TrimSpace(addr), ":")[0]
 }
 
+// redactedQueryParams are the query parameters holding secrets, such as the tokens of
+// feed URLs, which must not end up in the logs.
+var redactedQueryParams = []string{"token"}
+
+// redactedRequestURI returns the request URI of u with the values of secret query
+// parameters replaced.
+func redactedRequestURI(u *url.URL) string {
+	query := u.Query()
+	redacted := false
+	for _, param := range redactedQueryParams {
+		if query.Get(param) != "" {
+			query.Set(param, "REDACTED")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return u.RequestURI()
+	}
+	ret := *u
+	ret.RawQuery = query.Encode()
+	return ret.RequestURI()
+}
+
 func (l *logSvc) LoggerMiddleware(next http.Handler) http.Handler {
 	fn := func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
@@ -108,7 +139,7 @@ func (l *logSvc) LoggerMiddleware(next http.Handler) http.Handler {
 			"status_code": lrw.status,
 			"remote":      address,
 		})
-		logger.Info(r.Method + " " + r.URL.RequestURI())
+		logger.Info(r.Method + " " + redactedRequestURI(r.URL))
 	}
 	return http.HandlerFunc(fn)
 }
@@ -0,0 +1,149 @@
+// Package mailer sends the emails devmarks needs to reach its users, such as password
+// resets and email address verifications, through a pluggable Mailer.
+package mailer
+
+import (
+	"fmt"
+	"net/mail"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// The kinds of Mailer New can return.
+const (
+	// DriverSMTP delivers messages to an SMTP server.
+	DriverSMTP = "smtp"
+	// DriverFile appends messages to a file.
+	DriverFile = "file"
+	// DriverLog writes messages to the log.
+	DriverLog = "log"
+)
+
+// Config describes how messages are delivered.
+type Config struct {
+	// Driver is one of DriverSMTP, DriverFile or DriverLog.
+	Driver string
+	// From is the address messages are sent from.
+	From string
+	// Host, Port, Username and Password locate the SMTP server. Username can be left
+	// empty for servers that do not need authentication, like MailHog.
+	Host     string
+	Port     int
+	Username string
+	Password string
+	// Path is the file messages are appended to.
+	Path string
+}
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends messages.
+type Mailer interface {
+	Send(Message) error
+}
+
+// New returns the Mailer the config describes.
+func New(config Config) (Mailer, error) {
+	switch config.Driver {
+	case DriverSMTP:
+		if config.Host == "" {
+			return nil, fmt.Errorf("a host is required to send mail over SMTP")
+		}
+		if _, err := mail.ParseAddress(config.From); err != nil {
+			return nil, fmt.Errorf("invalid from address %q: %v", config.From, err)
+		}
+		return NewSMTP(config), nil
+	case DriverFile:
+		if config.Path == "" {
+			return nil, fmt.Errorf("a path is required to write mail to a file")
+		}
+		return NewFile(config.From, config.Path), nil
+	case DriverLog, "":
+		return NewLog(config.From, logrus.StandardLogger()), nil
+	}
+	return nil, fmt.Errorf("mail driver must be one of %s, %s, %s", DriverSMTP, DriverFile, DriverLog)
+}
+
+// format renders the message with the headers of an email.
+func format(from string, message Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", message.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", message.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// validate rejects messages whose headers could be used to inject other headers.
+func validate(message Message) error {
+	if strings.ContainsAny(message.To, "\r\n") || strings.ContainsAny(message.Subject, "\r\n") {
+		return fmt.Errorf("message headers cannot contain line breaks")
+	}
+	return nil
+}
+
+// fileMailer appends messages to a file, separated by blank lines.
+type fileMailer struct {
+	from string
+	path string
+	mu   sync.Mutex
+}
+
+// NewFile returns a Mailer that appends messages to the file at path.
+func NewFile(from, path string) Mailer {
+	return &fileMailer{from: from, path: path}
+}
+
+func (m *fileMailer) Send(message Message) error {
+	if err := validate(message); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, err := os.OpenFile(m.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(format(m.from, message), "\r\n\r\n"...)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// logMailer writes messages to a logger instead of sending them.
+type logMailer struct {
+	from   string
+	logger logrus.FieldLogger
+}
+
+// NewLog returns a Mailer that writes messages to the logger, for development.
+func NewLog(from string, logger logrus.FieldLogger) Mailer {
+	return &logMailer{from: from, logger: logger}
+}
+
+func (m *logMailer) Send(message Message) error {
+	if err := validate(message); err != nil {
+		return err
+	}
+	m.logger.WithFields(logrus.Fields{
+		"from":    m.from,
+		"to":      message.To,
+		"subject": message.Subject,
+	}).Info(message.Body)
+	return nil
+}
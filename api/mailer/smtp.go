@@ -0,0 +1,39 @@
+package mailer
+
+import (
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+)
+
+// smtpMailer delivers messages to an SMTP server.
+type smtpMailer struct {
+	config Config
+}
+
+// NewSMTP returns a Mailer that delivers messages to the SMTP server in the config.
+func NewSMTP(config Config) Mailer {
+	if config.Port == 0 {
+		config.Port = 25
+	}
+	return &smtpMailer{config: config}
+}
+
+func (m *smtpMailer) Send(message Message) error {
+	if err := validate(message); err != nil {
+		return err
+	}
+	// the envelope sender is the bare address; the display name only belongs in the
+	// From header.
+	from, err := mail.ParseAddress(m.config.From)
+	if err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if m.config.Username != "" {
+		auth = smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+	}
+	addr := net.JoinHostPort(m.config.Host, strconv.Itoa(m.config.Port))
+	return smtp.SendMail(addr, auth, from.Address, []string{message.To}, format(m.config.From, message))
+}
@@ -0,0 +1,119 @@
+package mailer
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+)
+
+// smtpServer accepts a single SMTP session and records the commands and the data it
+// receives.
+type smtpServer struct {
+	listener net.Listener
+	commands []string
+	data     string
+	done     chan struct{}
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	s := &smtpServer{listener: listener, done: make(chan struct{})}
+	go s.serve()
+	return s
+}
+
+func (s *smtpServer) serve() {
+	defer close(s.done)
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		s.commands = append(s.commands, line)
+		switch verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); verb {
+		case "EHLO", "HELO":
+			reply("250 localhost")
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			s.data = data.String()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func (s *smtpServer) config(from string) Config {
+	addr := s.listener.Addr().(*net.TCPAddr)
+	return Config{Driver: DriverSMTP, From: from, Host: addr.IP.String(), Port: addr.Port}
+}
+
+func TestSMTPSend(t *testing.T) {
+	server := newSMTPServer(t)
+	m, err := New(server.config("devmarks <no-reply@devmarks.local>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := Message{To: "someone@example.com", Subject: "Hello", Body: "Hi there"}
+	if err := m.Send(message); err != nil {
+		t.Fatal(err)
+	}
+	<-server.done
+
+	if !contains(server.commands, "MAIL FROM:<no-reply@devmarks.local>") {
+		t.Errorf("commands = %q, want the bare address as the envelope sender", server.commands)
+	}
+	if !contains(server.commands, "RCPT TO:<someone@example.com>") {
+		t.Errorf("commands = %q, want the recipient", server.commands)
+	}
+	if !strings.HasPrefix(server.data, "From: devmarks <no-reply@devmarks.local>\r\n") {
+		t.Errorf("data = %q, want the display name in the From header", server.data)
+	}
+}
+
+func TestNewSMTPInvalidFrom(t *testing.T) {
+	for _, from := range []string{"", "devmarks", "devmarks <no-reply"} {
+		config := Config{Driver: DriverSMTP, From: from, Host: "localhost", Port: 25}
+		if _, err := New(config); err == nil {
+			t.Errorf("New() with from %q succeeded, want an error", from)
+		}
+	}
+}
+
+func contains(lines []string, want string) bool {
+	for _, line := range lines {
+		if strings.HasPrefix(line, want) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS tags(
+    id serial PRIMARY KEY,
+    name text NOT NULL,
+    owner_id int NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    deleted_at TIMESTAMP,
+
+    CONSTRAINT tags_owner_id_fkey FOREIGN KEY (owner_id)
+    REFERENCES users(id) MATCH SIMPLE
+    ON UPDATE NO ACTION ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS tags_owner_id_name_key ON tags(owner_id, name) WHERE deleted_at IS NULL;
\ No newline at end of file
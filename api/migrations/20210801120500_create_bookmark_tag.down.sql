@@ -0,0 +1 @@
+DROP TABLE IF EXISTS bookmark_tag;
\ No newline at end of file
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS bookmark_tag(
+    bookmark_id int NOT NULL,
+    tag_id int NOT NULL,
+
+    PRIMARY KEY (bookmark_id, tag_id),
+
+    CONSTRAINT bookmarks_id_fkey FOREIGN KEY (bookmark_id)
+    REFERENCES bookmarks(id) MATCH SIMPLE
+    ON UPDATE NO ACTION ON DELETE CASCADE,
+
+    CONSTRAINT tags_id_fkey FOREIGN KEY (tag_id)
+    REFERENCES tags(id) MATCH SIMPLE
+    ON UPDATE NO ACTION ON DELETE CASCADE
+);
\ No newline at end of file
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS bookmarks_search_idx;
+ALTER TABLE bookmarks DROP COLUMN IF EXISTS read_at;
+ALTER TABLE bookmarks DROP COLUMN IF EXISTS notes;
\ No newline at end of file
@@ -0,0 +1,5 @@
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS notes text;
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS read_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS bookmarks_search_idx ON bookmarks
+USING GIN (to_tsvector('english', coalesce(name, '') || ' ' || coalesce(url, '') || ' ' || coalesce(notes, '')));
\ No newline at end of file
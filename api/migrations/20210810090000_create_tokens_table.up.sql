@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS tokens(
+    id serial PRIMARY KEY,
+    name text NOT NULL,
+    hash text NOT NULL,
+    user_id int NOT NULL,
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    deleted_at TIMESTAMP,
+
+    CONSTRAINT tokens_user_id_fkey FOREIGN KEY (user_id)
+    REFERENCES users(id) MATCH SIMPLE
+    ON UPDATE NO ACTION ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS tokens_hash_key ON tokens(hash);
+CREATE INDEX IF NOT EXISTS tokens_user_id_idx ON tokens(user_id);
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS refresh_tokens(
+    id serial PRIMARY KEY,
+    hash text NOT NULL,
+    user_id int NOT NULL,
+    family text NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    deleted_at TIMESTAMP,
+
+    CONSTRAINT refresh_tokens_user_id_fkey FOREIGN KEY (user_id)
+    REFERENCES users(id) MATCH SIMPLE
+    ON UPDATE NO ACTION ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS refresh_tokens_hash_key ON refresh_tokens(hash);
+CREATE INDEX IF NOT EXISTS refresh_tokens_family_idx ON refresh_tokens(family);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS identities(
+    id serial PRIMARY KEY,
+    user_id int NOT NULL,
+    issuer text NOT NULL,
+    subject text NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    deleted_at TIMESTAMP,
+
+    CONSTRAINT identities_user_id_fkey FOREIGN KEY (user_id)
+    REFERENCES users(id) MATCH SIMPLE
+    ON UPDATE NO ACTION ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS identities_issuer_subject_key ON identities(issuer, subject);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS login_attempts(
+    id serial PRIMARY KEY,
+    email text NOT NULL,
+    user_id int,
+    ip_address text NOT NULL,
+    success boolean NOT NULL,
+    reason text,
+    created_at TIMESTAMP NOT NULL,
+
+    CONSTRAINT login_attempts_user_id_fkey FOREIGN KEY (user_id)
+    REFERENCES users(id) MATCH SIMPLE
+    ON UPDATE NO ACTION ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS login_attempts_email_created_at_idx ON login_attempts(email, created_at);
+CREATE INDEX IF NOT EXISTS login_attempts_ip_address_created_at_idx ON login_attempts(ip_address, created_at);
@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN IF EXISTS default_bookmark_color;
+ALTER TABLE users DROP COLUMN IF EXISTS theme;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE folders DROP COLUMN IF EXISTS organization_id;
+DROP TABLE IF EXISTS organization_user;
+DROP TABLE IF EXISTS organizations;
\ No newline at end of file
@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS organizations(
+    id serial PRIMARY KEY,
+    name text NOT NULL,
+    description text NOT NULL DEFAULT '',
+    owner_id int NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    deleted_at TIMESTAMP,
+
+    CONSTRAINT organizations_owner_id_fkey FOREIGN KEY (owner_id)
+    REFERENCES users(id) MATCH SIMPLE
+    ON UPDATE NO ACTION ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS organization_user(
+    organization_id int NOT NULL,
+    user_id int NOT NULL,
+    role text NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+
+    PRIMARY KEY (organization_id, user_id),
+
+    CONSTRAINT organization_user_organization_id_fkey FOREIGN KEY (organization_id)
+    REFERENCES organizations(id) MATCH SIMPLE
+    ON UPDATE NO ACTION ON DELETE CASCADE,
+
+    CONSTRAINT organization_user_user_id_fkey FOREIGN KEY (user_id)
+    REFERENCES users(id) MATCH SIMPLE
+    ON UPDATE NO ACTION ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS organization_user_user_id_idx ON organization_user(user_id);
+
+ALTER TABLE folders ADD COLUMN IF NOT EXISTS organization_id int
+    REFERENCES organizations(id) MATCH SIMPLE ON UPDATE NO ACTION ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS folders_organization_id_idx ON folders(organization_id);
@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN IF EXISTS invite_id;
+DROP TABLE IF EXISTS invites;
\ No newline at end of file
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS invites(
+    id serial PRIMARY KEY,
+    hash text NOT NULL,
+    note text NOT NULL DEFAULT '',
+    created_by_id int,
+    max_uses int NOT NULL,
+    uses int NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    deleted_at TIMESTAMP,
+
+    CONSTRAINT invites_created_by_id_fkey FOREIGN KEY (created_by_id)
+    REFERENCES users(id) MATCH SIMPLE
+    ON UPDATE NO ACTION ON DELETE SET NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS invites_hash_key ON invites(hash);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS invite_id int
+    REFERENCES invites(id) MATCH SIMPLE ON UPDATE NO ACTION ON DELETE SET NULL;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS folder_user;
\ No newline at end of file
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS folder_user(
+    folder_id int NOT NULL,
+    user_id int NOT NULL,
+    access text NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+
+    PRIMARY KEY (folder_id, user_id),
+
+    CONSTRAINT folder_user_folder_id_fkey FOREIGN KEY (folder_id)
+    REFERENCES folders(id) MATCH SIMPLE
+    ON UPDATE NO ACTION ON DELETE CASCADE,
+
+    CONSTRAINT folder_user_user_id_fkey FOREIGN KEY (user_id)
+    REFERENCES users(id) MATCH SIMPLE
+    ON UPDATE NO ACTION ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS folder_user_user_id_idx ON folder_user(user_id);
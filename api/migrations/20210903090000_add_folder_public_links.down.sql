@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS folders_public_slug_key;
+ALTER TABLE folders DROP COLUMN IF EXISTS public_expires_at;
+ALTER TABLE folders DROP COLUMN IF EXISTS public_password_hash;
+ALTER TABLE folders DROP COLUMN IF EXISTS public_slug;
\ No newline at end of file
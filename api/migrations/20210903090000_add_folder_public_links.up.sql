@@ -0,0 +1,5 @@
+ALTER TABLE folders ADD COLUMN IF NOT EXISTS public_slug text;
+ALTER TABLE folders ADD COLUMN IF NOT EXISTS public_password_hash bytea;
+ALTER TABLE folders ADD COLUMN IF NOT EXISTS public_expires_at TIMESTAMP;
+
+CREATE UNIQUE INDEX IF NOT EXISTS folders_public_slug_key ON folders(public_slug);
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS feed_tokens(
+    id serial PRIMARY KEY,
+    hash text NOT NULL,
+    user_id int NOT NULL,
+    folder_id int,
+    tag_id int,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    deleted_at TIMESTAMP,
+
+    CONSTRAINT feed_tokens_user_id_fkey FOREIGN KEY (user_id)
+    REFERENCES users(id) MATCH SIMPLE
+    ON UPDATE NO ACTION ON DELETE CASCADE,
+
+    CONSTRAINT feed_tokens_folder_id_fkey FOREIGN KEY (folder_id)
+    REFERENCES folders(id) MATCH SIMPLE
+    ON UPDATE NO ACTION ON DELETE CASCADE,
+
+    CONSTRAINT feed_tokens_tag_id_fkey FOREIGN KEY (tag_id)
+    REFERENCES tags(id) MATCH SIMPLE
+    ON UPDATE NO ACTION ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS feed_tokens_hash_key ON feed_tokens(hash);
+CREATE INDEX IF NOT EXISTS feed_tokens_user_id_idx ON feed_tokens(user_id);
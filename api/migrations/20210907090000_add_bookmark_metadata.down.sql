@@ -0,0 +1,7 @@
+ALTER TABLE bookmarks DROP COLUMN IF EXISTS metadata_fetched_at;
+ALTER TABLE bookmarks DROP COLUMN IF EXISTS metadata_error;
+ALTER TABLE bookmarks DROP COLUMN IF EXISTS metadata_image_url;
+ALTER TABLE bookmarks DROP COLUMN IF EXISTS metadata_canonical_url;
+ALTER TABLE bookmarks DROP COLUMN IF EXISTS metadata_favicon_url;
+ALTER TABLE bookmarks DROP COLUMN IF EXISTS metadata_description;
+ALTER TABLE bookmarks DROP COLUMN IF EXISTS metadata_title;
\ No newline at end of file
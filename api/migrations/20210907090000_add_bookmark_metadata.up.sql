@@ -0,0 +1,7 @@
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS metadata_title text NOT NULL DEFAULT '';
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS metadata_description text NOT NULL DEFAULT '';
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS metadata_favicon_url text NOT NULL DEFAULT '';
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS metadata_canonical_url text NOT NULL DEFAULT '';
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS metadata_image_url text NOT NULL DEFAULT '';
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS metadata_error text NOT NULL DEFAULT '';
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS metadata_fetched_at TIMESTAMP;
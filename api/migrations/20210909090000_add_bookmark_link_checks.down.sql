@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS bookmarks_link_next_check_at_idx;
+ALTER TABLE bookmarks DROP COLUMN IF EXISTS link_next_check_at;
+ALTER TABLE bookmarks DROP COLUMN IF EXISTS link_checked_at;
+ALTER TABLE bookmarks DROP COLUMN IF EXISTS link_failures;
+ALTER TABLE bookmarks DROP COLUMN IF EXISTS link_redirect_url;
+ALTER TABLE bookmarks DROP COLUMN IF EXISTS link_error;
+ALTER TABLE bookmarks DROP COLUMN IF EXISTS link_status_code;
\ No newline at end of file
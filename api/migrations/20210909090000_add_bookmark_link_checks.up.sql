@@ -0,0 +1,8 @@
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS link_status_code integer NOT NULL DEFAULT 0;
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS link_error text NOT NULL DEFAULT '';
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS link_redirect_url text NOT NULL DEFAULT '';
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS link_failures integer NOT NULL DEFAULT 0;
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS link_checked_at TIMESTAMP;
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS link_next_check_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS bookmarks_link_next_check_at_idx ON bookmarks(link_next_check_at);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS users_email_key;
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
\ No newline at end of file
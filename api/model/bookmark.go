@@ -1,13 +1,23 @@
 package model
 
+import (
+	"encoding/json"
+	"time"
+)
+
 // Bookmark is a model that represents the bookmarks our app can save. They are owned by one user,
 // Can be in any number of folders, and can have any number of tags.
 type Bookmark struct {
 	Model
 
-	Name  string  `json:"name"`
-	URL   string  `json:"url"`
-	Color *string `json:"color"`
+	Name   string     `json:"name"`
+	URL    string     `json:"url"`
+	Color  *string    `json:"color"`
+	Notes  string     `json:"notes"`
+	ReadAt *time.Time `json:"read_at"`
+
+	Metadata BookmarkMetadata `gorm:"embedded;embedded_prefix:metadata_" json:"metadata"`
+	Link     BookmarkLink     `gorm:"embedded;embedded_prefix:link_" json:"link"`
 
 	OwnerID uint     `json:"-"`
 	Owner   *User    `gorm:"foreignKey:OwnerID" json:"owner"`
@@ -15,8 +25,83 @@ type Bookmark struct {
 	Tags    []Tag    `gorm:"many2many:bookmark_tag;" json:"tags"`
 }
 
+// BookmarkMetadata is what was found out about the page a bookmark points to by fetching
+// it after the bookmark was created.
+type BookmarkMetadata struct {
+	Title        string     `json:"title"`
+	Description  string     `json:"description"`
+	FaviconURL   string     `json:"favicon_url"`
+	CanonicalURL string     `json:"canonical_url"`
+	ImageURL     string     `json:"image_url"`
+	Error        string     `json:"error,omitempty"`
+	FetchedAt    *time.Time `json:"fetched_at"`
+}
+
+// The states the link of a bookmark can be in, which lists of bookmarks can be
+// filtered by.
+const (
+	// LinkStatusUnchecked is the status of links that have not been checked yet.
+	LinkStatusUnchecked = "unchecked"
+	// LinkStatusOK is the status of links that worked when they were last checked.
+	LinkStatusOK = "ok"
+	// LinkStatusRedirected is the status of links that worked, but permanently
+	// redirect to another URL.
+	LinkStatusRedirected = "redirected"
+	// LinkStatusBroken is the status of links that failed when they were last checked.
+	LinkStatusBroken = "broken"
+)
+
+// BookmarkLink is the outcome of the periodic checks of whether the URL of a bookmark
+// still works.
+type BookmarkLink struct {
+	// StatusCode is the HTTP status of the last check, or 0 if no response came.
+	StatusCode int    `json:"status_code"`
+	Error      string `json:"error,omitempty"`
+	// RedirectURL is where the URL permanently redirects to, if it does.
+	RedirectURL string `json:"redirect_url"`
+	// Failures is the number of checks in a row that failed.
+	Failures    int        `json:"failures"`
+	CheckedAt   *time.Time `json:"checked_at"`
+	NextCheckAt *time.Time `json:"-"`
+}
+
+// Status returns the LinkStatus constant describing the link.
+func (l BookmarkLink) Status() string {
+	switch {
+	case l.CheckedAt == nil:
+		return LinkStatusUnchecked
+	case l.Failures > 0:
+		return LinkStatusBroken
+	case l.RedirectURL != "":
+		return LinkStatusRedirected
+	default:
+		return LinkStatusOK
+	}
+}
+
+// MarshalJSON adds the status of the link to its json representation.
+func (l BookmarkLink) MarshalJSON() ([]byte, error) {
+	type link BookmarkLink
+	return json.Marshal(struct {
+		link
+		Status string `json:"status"`
+	}{link(l), l.Status()})
+}
+
 // Add strings to the array to allow embedding that resource through the
 // embed query paramter.
 func BookmarkValidEmbeds() []string {
-	return []string{"owner", "folders"}
+	return []string{"owner", "folders", "tags"}
+}
+
+// Add strings to the array to allow sorting lists of this resource by that
+// column through the sort query parameter.
+func BookmarkValidSorts() []string {
+	return []string{"name", "url", "created_at", "updated_at"}
+}
+
+// Add strings to the array to allow filtering lists of bookmarks by the status of
+// their link through the status query parameter.
+func BookmarkValidLinkStatuses() []string {
+	return []string{LinkStatusUnchecked, LinkStatusOK, LinkStatusRedirected, LinkStatusBroken}
 }
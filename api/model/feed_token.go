@@ -0,0 +1,35 @@
+package model
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+)
+
+// feedTokenPrefix marks feed tokens, so they are not mistaken for API tokens.
+const feedTokenPrefix = "dmf_"
+
+// FeedToken is a model representing the secret in the URL of the feed of a folder or a
+// tag, as feed readers cannot send an Authorization header. It only gives access to
+// that one feed, as the user it belongs to. Only a hash of the token is stored.
+type FeedToken struct {
+	Model
+
+	Hash       string     `json:"-"`
+	UserID     uint       `json:"-"`
+	User       *User      `json:"-"`
+	FolderID   *uint      `json:"folder_id"`
+	TagID      *uint      `json:"tag_id"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// NewFeedToken generates a random feed token and returns it, along with a FeedToken
+// model holding its hash. Either folderID or tagID is set.
+func NewFeedToken(userID uint, folderID, tagID *uint) (string, *FeedToken) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		panic(err)
+	}
+	secret := feedTokenPrefix + base64.RawURLEncoding.EncodeToString(raw)
+	return secret, &FeedToken{Hash: HashToken(secret), UserID: userID, FolderID: folderID, TagID: tagID}
+}
@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 // Folder is a model that represents folders of bookmarks that can exist in our app,
 // containing bookmarks, owned by one user, and accessed by individual users and or
 // users belonging to a certain organization.
@@ -13,9 +15,18 @@ type Folder struct {
 	Parent   *Folder `gorm:"association_foreignkey:ParentID" json:"parent"`
 	OwnerID  uint    `json:"-"`
 	Owner    *User   `gorm:"foreignkey:OwnerID" json:"owner"`
-	//Organizations []Organization `gorm:"many2many:folder_organization;"`
+	// OrganizationID is set on folders owned by an organization, whose members can
+	// access them according to their role. They are owned by the organization's owner.
+	OrganizationID *uint         `json:"organization_id"`
+	Organization   *Organization `gorm:"foreignkey:OrganizationID" json:"-"`
 	Bookmarks []Bookmark `gorm:"many2many:bookmark_folder;" json:"bookmarks"`
-	//Users     []User     `gorm:"many2many:folder_user;" json:"users"`
+	// PublicSlug is set when the folder is published, and is the unguessable part of
+	// the link anyone can see it at without signing in.
+	PublicSlug         *string    `json:"public_slug"`
+	PublicPasswordHash []byte     `json:"-"`
+	PublicExpiresAt    *time.Time `json:"public_expires_at"`
+	// Shares are the users the folder is shared with individually.
+	Shares []Share `gorm:"foreignkey:FolderID" json:"-"`
 }
 
 // Add strings to the array to allow embedding that resource through the
@@ -23,3 +34,31 @@ type Folder struct {
 func FolderValidEmbeds() []string {
 	return []string{"owner", "bookmarks"}
 }
+
+// Add strings to the array to allow sorting lists of this resource by that
+// column through the sort query parameter.
+func FolderValidSorts() []string {
+	return []string{"name", "created_at", "updated_at"}
+}
+
+// Public returns true if the folder is published and its link has not expired.
+func (f *Folder) Public() bool {
+	return f.PublicSlug != nil && (f.PublicExpiresAt == nil || f.PublicExpiresAt.After(time.Now()))
+}
+
+// PublicPasswordProtected returns true if visitors need a password to see the
+// published folder.
+func (f *Folder) PublicPasswordProtected() bool {
+	return len(f.PublicPasswordHash) > 0
+}
+
+// FolderNode is a folder together with the folders nested inside it, as returned by
+// the folder tree endpoints.
+type FolderNode struct {
+	ID            uint          `json:"id"`
+	Name          string        `json:"name"`
+	Color         string        `json:"color"`
+	ParentID      *uint         `json:"parent_id"`
+	BookmarkCount int           `json:"bookmark_count"`
+	Children      []*FolderNode `json:"children"`
+}
@@ -0,0 +1,12 @@
+package model
+
+// Identity is a model linking a User to their account with a single sign-on provider,
+// identified by the provider's issuer and the subject it gives the account.
+type Identity struct {
+	Model
+
+	UserID  uint
+	User    *User
+	Issuer  string
+	Subject string
+}
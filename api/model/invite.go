@@ -0,0 +1,37 @@
+package model
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+)
+
+// invitePrefix marks invite codes, so they are not mistaken for API tokens.
+const invitePrefix = "dmi_"
+
+// Invite is a model representing a code that lets people register when registration
+// is invite-only. Only a hash of the code is stored, like for tokens, so the code
+// itself is only known when the invite is created.
+type Invite struct {
+	Model
+
+	Hash string `json:"-"`
+	// Note reminds admins who the invite was made for.
+	Note        string `json:"note"`
+	CreatedByID *uint  `json:"created_by_id"`
+	// MaxUses is how many users can register with the invite; 1 for a single-use invite.
+	MaxUses   int        `json:"max_uses"`
+	Uses      int        `json:"uses"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// NewInvite generates a random invite code and returns it, along with an Invite model
+// holding its hash.
+func NewInvite(createdByID *uint, maxUses int, expiresAt *time.Time) (string, *Invite) {
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		panic(err)
+	}
+	code := invitePrefix + base64.RawURLEncoding.EncodeToString(raw)
+	return code, &Invite{Hash: HashToken(code), CreatedByID: createdByID, MaxUses: maxUses, ExpiresAt: expiresAt}
+}
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// Reasons a LoginAttempt failed.
+const (
+	LoginFailedUnknownUser    = "unknown_user"
+	LoginFailedPassword       = "wrong_password"
+	LoginFailedMFA            = "wrong_mfa_code"
+	LoginFailedPublicPassword = "wrong_public_password"
+)
+
+// LoginAttempt is a model recording a single attempt to sign in, successful or not. It
+// serves as the audit trail of logins and to throttle repeated failures. UserID is nil
+// when the email address matches no user. Passwords given for public folders are
+// recorded too, with a key derived from the folder's slug as their email address.
+type LoginAttempt struct {
+	ID        uint      `gorm:"primary_key" json:"id"`
+	Email     string    `json:"email"`
+	UserID    *uint     `json:"user_id"`
+	IPAddress string    `json:"ip_address"`
+	Success   bool      `json:"success"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
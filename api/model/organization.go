@@ -1,15 +1,62 @@
 package model
 
+import "time"
+
+// The roles members can have in an organization, from the most to the least access.
+const (
+	// RoleOwner is the role of the user who owns the organization. It can delete the
+	// organization, and only belongs to its owner.
+	RoleOwner = "owner"
+	// RoleAdmin can change the organization, its members and its folders.
+	RoleAdmin = "admin"
+	// RoleEditor can create folders in the organization and change them.
+	RoleEditor = "editor"
+	// RoleViewer can see the folders of the organization and their bookmarks.
+	RoleViewer = "viewer"
+)
+
+// roleRanks orders the roles; a role with a higher rank can do everything a lower one can.
+var roleRanks = map[string]int{RoleViewer: 1, RoleEditor: 2, RoleAdmin: 3, RoleOwner: 4}
+
+// ValidRole returns true if the role is one members can have.
+func ValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// RoleAtLeast returns true if role grants everything minimum does. It is false for
+// an empty role, which means the user is not a member.
+func RoleAtLeast(role, minimum string) bool {
+	return roleRanks[role] > 0 && roleRanks[role] >= roleRanks[minimum]
+}
+
 // Organization is a model representing the organizations our app can save. Organizations provide
-// a way to group any number of users together, can have access to any number of folders,
+// a way to group any number of users together, can own any number of folders,
 // and are owned by a single user.
 type Organization struct {
 	Model
 
-	Name        string
-	Description string
+	Name        string `json:"name"`
+	Description string `json:"description"`
+
+	OwnerID uint         `json:"owner_id"`
+	Owner   *User        `gorm:"foreignkey:OwnerID" json:"-"`
+	Folders []Folder     `gorm:"foreignkey:OrganizationID" json:"-"`
+	Members []Membership `gorm:"foreignkey:OrganizationID" json:"-"`
+}
+
+// Membership is a model representing a user's membership of an organization and the
+// role they have in it.
+type Membership struct {
+	OrganizationID uint      `gorm:"primary_key;auto_increment:false" json:"organization_id"`
+	UserID         uint      `gorm:"primary_key;auto_increment:false" json:"user_id"`
+	User           *User     `gorm:"foreignkey:UserID" json:"-"`
+	Role           string    `json:"role"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
 
-	Folders []Folder `gorm:"many2many:folder_organization;"`
-	Users   []User   `gorm:"many2many:organization_user;"`
-	Owner   User     `gorm:"association_foreignkey:UserID"`
+// TableName implements gorm's tabler interface, as the table predates the model.
+func (Membership) TableName() string {
+	return "organization_user"
 }
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+)
+
+// NewPublicSlug generates a random, unguessable slug for the link of a published folder.
+func NewPublicSlug() string {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// PublicFolder is a published folder as shown to anyone with its link: its bookmarks
+// and the folders below it, without anything identifying its owner.
+type PublicFolder struct {
+	Name      string            `json:"name"`
+	Color     string            `json:"color"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	Bookmarks []*PublicBookmark `json:"bookmarks"`
+	Folders   []*PublicFolder   `json:"folders"`
+}
+
+// PublicBookmark is a bookmark in a published folder.
+type PublicBookmark struct {
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	Notes     string    `json:"notes"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+)
+
+// refreshTokenPrefix marks the refresh tokens issued by devmarks.
+const refreshTokenPrefix = "dmr_"
+
+// RefreshToken is a model representing a single-use token exchanged for a new JWT
+// access token. Every refresh token is replaced by a new one of the same family when
+// it is used, so a family is one login session. Only a hash of the token is stored.
+type RefreshToken struct {
+	Model
+
+	Hash      string
+	UserID    uint
+	User      *User
+	Family    string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+}
+
+// NewRefreshToken generates a random refresh token of the specified family and returns
+// it, along with a RefreshToken model holding its hash.
+func NewRefreshToken(userID uint, family string, expiresAt time.Time) (string, *RefreshToken) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		panic(err)
+	}
+	secret := refreshTokenPrefix + base64.RawURLEncoding.EncodeToString(raw)
+	return secret, &RefreshToken{Hash: HashToken(secret), UserID: userID, Family: family, ExpiresAt: expiresAt}
+}
+
+// NewTokenFamily generates the identifier of a new family of refresh tokens.
+func NewTokenFamily() string {
+	return hex.EncodeToString(NewID()[:16])
+}
+
+// Active returns true if the refresh token has neither expired nor been revoked. An
+// active token that was already used is being reused, which must not be mistaken for
+// an invalid one.
+func (t *RefreshToken) Active() bool {
+	return t.RevokedAt == nil && t.ExpiresAt.After(time.Now())
+}
@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// The access a folder can be shared with at.
+const (
+	// AccessRead lets the user see the folder, the folders below it and their bookmarks.
+	AccessRead = "read"
+	// AccessEdit also lets the user change the folders and bookmarks, add their own
+	// bookmarks and remove bookmarks.
+	AccessEdit = "edit"
+)
+
+// ValidAccess returns true if a folder can be shared at the access level.
+func ValidAccess(access string) bool {
+	return access == AccessRead || access == AccessEdit
+}
+
+// Share is a model representing a folder shared with a single user. The share covers
+// every folder below the shared folder as well.
+type Share struct {
+	FolderID  uint      `gorm:"primary_key;auto_increment:false" json:"folder_id"`
+	Folder    *Folder   `gorm:"foreignkey:FolderID" json:"-"`
+	UserID    uint      `gorm:"primary_key;auto_increment:false" json:"user_id"`
+	User      *User     `gorm:"foreignkey:UserID" json:"-"`
+	Access    string    `json:"access"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName implements gorm's tabler interface, as the table is named after the
+// many2many relation it was planned as.
+func (Share) TableName() string {
+	return "folder_user"
+}
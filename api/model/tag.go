@@ -1,11 +1,19 @@
 package model
 
 // Tag is a model representing tags that our app can save. They can be used to group any number of
-// bookmarks together.
+// bookmarks together, and are owned by one user. Tag names are unique per owner.
 type Tag struct {
 	Model
 
-	Name string
+	Name string `json:"name"`
 
-	Bookmarks []Bookmark `gorm:"many2many:bookmark_tag;"`
+	OwnerID   uint       `json:"-"`
+	Owner     *User      `gorm:"foreignkey:OwnerID" json:"owner"`
+	Bookmarks []Bookmark `gorm:"many2many:bookmark_tag;" json:"bookmarks"`
+}
+
+// Add strings to the array to allow embedding that resource through the
+// embed query paramter.
+func TagValidEmbeds() []string {
+	return []string{"owner", "bookmarks"}
 }
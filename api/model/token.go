@@ -0,0 +1,102 @@
+package model
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// tokenPrefix marks the API tokens issued by devmarks, so they are easy to recognize
+// in configuration files and secret scanners.
+const tokenPrefix = "dm_"
+
+// The scopes a personal access token can be limited to.
+const (
+	ScopeBookmarksRead  = "bookmarks:read"
+	ScopeBookmarksWrite = "bookmarks:write"
+	ScopeFoldersWrite   = "folders:write"
+	ScopeAdmin          = "admin"
+)
+
+// ValidScopes returns the scopes a personal access token can be given.
+func ValidScopes() []string {
+	return []string{ScopeBookmarksRead, ScopeBookmarksWrite, ScopeFoldersWrite, ScopeAdmin}
+}
+
+// impliedScopes lists, for each scope, the scopes granting it as well.
+var impliedScopes = map[string][]string{
+	ScopeBookmarksRead:  {ScopeBookmarksWrite, ScopeFoldersWrite, ScopeAdmin},
+	ScopeBookmarksWrite: {ScopeAdmin},
+	ScopeFoldersWrite:   {ScopeAdmin},
+}
+
+// Token is a model representing an API token a User authenticates with. Only a hash
+// of the token is stored, so the token itself is only known when it is created.
+//
+// Tokens handed out on login have no scopes and can do anything the user can;
+// personal access tokens are limited to their scopes.
+type Token struct {
+	Model
+
+	Name       string         `json:"name"`
+	Hash       string         `json:"-"`
+	UserID     uint           `json:"-"`
+	User       *User          `json:"-"`
+	Scopes     pq.StringArray `gorm:"type:text[]" json:"scopes"`
+	ExpiresAt  *time.Time     `json:"expires_at"`
+	LastUsedAt *time.Time     `json:"last_used_at"`
+}
+
+// NewToken generates a random token and returns it, along with a Token model holding
+// its hash.
+func NewToken(name string, userID uint, expiresAt *time.Time) (string, *Token) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		panic(err)
+	}
+	secret := tokenPrefix + base64.RawURLEncoding.EncodeToString(raw)
+	return secret, &Token{Name: name, Hash: HashToken(secret), UserID: userID, ExpiresAt: expiresAt}
+}
+
+// LooksLikeToken returns true if the secret has the shape of an API token, as opposed
+// to a JWT access token for instance.
+func LooksLikeToken(secret string) bool {
+	return strings.HasPrefix(secret, tokenPrefix)
+}
+
+// HashToken returns the hash under which the given token is stored.
+func HashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Expired returns true if the token can no longer be used.
+func (t *Token) Expired() bool {
+	return t.ExpiresAt != nil && !t.ExpiresAt.After(time.Now())
+}
+
+// Personal returns true if the token is a personal access token limited to its scopes.
+func (t *Token) Personal() bool {
+	return t.Scopes != nil
+}
+
+// ScopesAllow returns true if the list of scopes grants the specified scope, either
+// directly or through a broader scope.
+func ScopesAllow(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+		for _, implied := range impliedScopes[scope] {
+			if s == implied {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -1,6 +1,9 @@
 package model
 
 import (
+	"time"
+
+	"github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -17,13 +20,44 @@ func ComparePasswordHash(hashedPassword, givenPassword []byte) bool {
 	return err == nil
 }
 
+// The themes the web client can be shown in.
+const (
+	ThemeSystem = "system"
+	ThemeLight  = "light"
+	ThemeDark   = "dark"
+)
+
+// Preferences are the settings a user chose for how devmarks behaves for them.
+type Preferences struct {
+	// DefaultBookmarkColor is given to new bookmarks created without a color.
+	DefaultBookmarkColor *string `json:"default_bookmark_color"`
+	// Theme is one of ThemeSystem, ThemeLight or ThemeDark.
+	Theme string `gorm:"default:'system'" json:"theme"`
+}
+
 // User is a model representing the Users our app can save. They can own any number of bookmarks,
 // any number of folders, and be a part of any number of organizations.
 type User struct {
 	Model
 
-	Email          string `json:"email"`
-	HashedPassword []byte `json:"-"`
+	Email          string  `json:"email"`
+	HashedPassword []byte  `json:"-"`
+	Name           *string `json:"name"`
+	// EmailVerifiedAt is when the user confirmed they own Email, or nil if they have not.
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	// InviteID is the invite the user registered with, if registration was invite-only.
+	InviteID *uint `json:"-"`
+
+	// TOTPSecret is the encrypted secret of the user's authenticator app. It is set
+	// before two-factor authentication is enabled, while enrollment is confirmed.
+	TOTPSecret    string     `json:"-"`
+	TOTPEnabledAt *time.Time `json:"totp_enabled_at"`
+	// TOTPLastStep is the time step of the last code used, so codes cannot be replayed.
+	TOTPLastStep int64 `json:"-"`
+	// RecoveryCodes are the hashes of the unused recovery codes.
+	RecoveryCodes pq.StringArray `gorm:"type:text[]" json:"-"`
+
+	Preferences Preferences `gorm:"embedded" json:"preferences"`
 
 	Bookmarks []Bookmark `gorm:"foreignkey:OwnerID"`
 }
@@ -42,3 +76,13 @@ func (u *User) SetPassword(password string) error {
 func (u *User) CheckPassword(password string) bool {
 	return ComparePasswordHash(u.HashedPassword, []byte(password))
 }
+
+// EmailVerified returns true if the user confirmed they own their email address.
+func (u *User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// TOTPEnabled returns true if the user has to give a one-time code to sign in.
+func (u *User) TOTPEnabled() bool {
+	return u.TOTPEnabledAt != nil
+}
@@ -0,0 +1,224 @@
+// Package netscape reads and writes the Netscape bookmark file format, the
+// `bookmarks.html` file every browser can import and export.
+package netscape
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	nethtml "golang.org/x/net/html"
+)
+
+// Folder is a folder in a bookmark file, holding bookmarks and other folders. The
+// root folder of a file has no name.
+type Folder struct {
+	Name      string
+	AddDate   time.Time
+	Folders   []*Folder
+	Bookmarks []*Bookmark
+}
+
+// Bookmark is a single link in a bookmark file.
+type Bookmark struct {
+	Name    string
+	URL     string
+	AddDate time.Time
+	Tags    []string
+	// Description is the text of the optional <DD> element following the link.
+	Description string
+}
+
+// Parse reads a bookmark file and returns its root folder. The format is loose
+// HTML, so anything that is not part of the <DL>/<DT>/<H3>/<A> structure is ignored.
+func Parse(r io.Reader) (*Folder, error) {
+	z := nethtml.NewTokenizer(r)
+	root := &Folder{}
+	var stack []*Folder
+	var pending *Folder
+	var last *Bookmark
+	// description is set while reading the text of a <DD>, which is never closed.
+	var description *Bookmark
+	seenList := false
+
+	current := func() *Folder {
+		if len(stack) == 0 {
+			return root
+		}
+		return stack[len(stack)-1]
+	}
+
+	for {
+		tt := z.Next()
+		if tt != nethtml.TextToken {
+			description = nil
+		}
+		switch tt {
+		case nethtml.TextToken:
+			if description != nil {
+				description.Description = strings.TrimSpace(description.Description + string(z.Text()))
+			}
+		case nethtml.ErrorToken:
+			if z.Err() == io.EOF {
+				if !seenList {
+					return nil, fmt.Errorf("not a netscape bookmark file: no <DL> element found")
+				}
+				return root, nil
+			}
+			return nil, z.Err()
+		case nethtml.StartTagToken:
+			t := z.Token()
+			switch t.Data {
+			case "dl":
+				// the first list is the root; every later one belongs to the folder
+				// heading just before it.
+				if !seenList {
+					seenList = true
+					stack = append(stack, root)
+				} else if pending != nil {
+					stack = append(stack, pending)
+				} else {
+					stack = append(stack, current())
+				}
+				pending, last = nil, nil
+			case "h3":
+				folder := &Folder{Name: readText(z, "h3"), AddDate: parseDate(attr(t, "add_date"))}
+				current().Folders = append(current().Folders, folder)
+				pending, last = folder, nil
+			case "a":
+				bookmark := &Bookmark{
+					URL:     attr(t, "href"),
+					AddDate: parseDate(attr(t, "add_date")),
+					Tags:    splitTags(attr(t, "tags")),
+				}
+				bookmark.Name = readText(z, "a")
+				if bookmark.URL == "" {
+					continue
+				}
+				if bookmark.Name == "" {
+					bookmark.Name = bookmark.URL
+				}
+				current().Bookmarks = append(current().Bookmarks, bookmark)
+				pending, last = nil, bookmark
+			case "dd":
+				description = last
+			}
+		case nethtml.EndTagToken:
+			if t := z.Token(); t.Data == "dl" && len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+}
+
+// readText collects the text up to the end of the named element.
+func readText(z *nethtml.Tokenizer, name string) string {
+	var text strings.Builder
+	for {
+		switch z.Next() {
+		case nethtml.TextToken:
+			text.WriteString(z.Token().Data)
+		case nethtml.EndTagToken:
+			if z.Token().Data == name {
+				return strings.TrimSpace(text.String())
+			}
+		case nethtml.ErrorToken:
+			return strings.TrimSpace(text.String())
+		}
+	}
+}
+
+func attr(t nethtml.Token, key string) string {
+	for _, a := range t.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// parseDate parses an ADD_DATE attribute. Browsers write seconds since the epoch,
+// but some write milliseconds or microseconds instead.
+func parseDate(value string) time.Time {
+	n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || n <= 0 {
+		return time.Time{}
+	}
+	switch {
+	case n > 1e14:
+		return time.Unix(0, n*int64(time.Microsecond)).UTC()
+	case n > 1e11:
+		return time.Unix(0, n*int64(time.Millisecond)).UTC()
+	}
+	return time.Unix(n, 0).UTC()
+}
+
+func splitTags(value string) []string {
+	var tags []string
+	for _, tag := range strings.Split(value, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+const header = `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<!-- This is an automatically generated file.
+     It will be read and overwritten.
+     DO NOT EDIT! -->
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks</H1>
+`
+
+// Write writes root and everything below it to w as a bookmark file.
+func Write(w io.Writer, root *Folder) error {
+	ew := &errWriter{w: w}
+	ew.printf("%s", header)
+	writeFolder(ew, root, 0)
+	return ew.err
+}
+
+func writeFolder(ew *errWriter, folder *Folder, depth int) {
+	indent := strings.Repeat("    ", depth)
+	ew.printf("%s<DL><p>\n", indent)
+	for _, child := range folder.Folders {
+		ew.printf("%s    <DT><H3%s>%s</H3>\n", indent, dateAttr(child.AddDate), html.EscapeString(child.Name))
+		writeFolder(ew, child, depth+1)
+	}
+	for _, bookmark := range folder.Bookmarks {
+		ew.printf("%s    <DT><A HREF=\"%s\"%s", indent, html.EscapeString(bookmark.URL), dateAttr(bookmark.AddDate))
+		if len(bookmark.Tags) > 0 {
+			ew.printf(" TAGS=\"%s\"", html.EscapeString(strings.Join(bookmark.Tags, ",")))
+		}
+		ew.printf(">%s</A>\n", html.EscapeString(bookmark.Name))
+		if bookmark.Description != "" {
+			ew.printf("%s    <DD>%s\n", indent, html.EscapeString(bookmark.Description))
+		}
+	}
+	ew.printf("%s</DL><p>\n", indent)
+}
+
+func dateAttr(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf(" ADD_DATE=\"%d\"", t.Unix())
+}
+
+// errWriter remembers the first error writing to w and skips every write after it.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, args ...interface{}) {
+	if ew.err != nil {
+		return
+	}
+	_, ew.err = fmt.Fprintf(ew.w, format, args...)
+}
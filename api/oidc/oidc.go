@@ -0,0 +1,206 @@
+// Package oidc implements the parts of OpenID Connect devmarks needs to sign users in
+// through a single sign-on provider: discovery, the authorization code flow and the
+// verification of ID tokens against the provider's published keys.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxResponseSize is the largest response accepted from the provider.
+const maxResponseSize = 1 << 20
+
+// Config describes the provider and the client registered with it.
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the URL of the callback endpoint, as registered with the provider.
+	RedirectURL string
+	Scopes      []string
+}
+
+// metadata is the subset of the provider's discovery document that is used.
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider is an OpenID Connect provider. Its discovery document and keys are fetched
+// the first time they are needed.
+type Provider struct {
+	config Config
+	client *http.Client
+
+	mu       sync.Mutex
+	metadata *metadata
+	keys     *keySet
+}
+
+// NewProvider returns a Provider for the given configuration. client is used for every
+// request to the provider; a client with a short timeout is used if it is nil.
+func NewProvider(config Config, client *http.Client) *Provider {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "email", "profile"}
+	}
+	return &Provider{config: config, client: client}
+}
+
+// Issuer returns the issuer identifier of the provider.
+func (p *Provider) Issuer() string {
+	return p.config.Issuer
+}
+
+// AuthCodeURL returns the URL of the provider's login page, which redirects back to the
+// callback with a code once the user has signed in.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce string) (string, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	scopes := p.config.Scopes
+	if !contains(scopes, "openid") {
+		scopes = append([]string{"openid"}, scopes...)
+	}
+	query := url.Values{
+		"response_type": {"code"},
+		"client_id":     {p.config.ClientID},
+		"redirect_uri":  {p.config.RedirectURL},
+		"scope":         {strings.Join(scopes, " ")},
+		"state":         {state},
+		"nonce":         {nonce},
+	}
+	separator := "?"
+	if strings.Contains(md.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return md.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// tokenResponse is the response of the token endpoint.
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Exchange trades the code the provider redirected back with for an ID token, and
+// returns its verified claims.
+func (p *Provider) Exchange(ctx context.Context, code, nonce string) (*Claims, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {p.config.RedirectURL},
+	}
+	req, err := http.NewRequest(http.MethodPost, md.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+
+	var token tokenResponse
+	status, err := p.do(ctx, req, &token)
+	if err != nil {
+		return nil, err
+	}
+	if token.Error != "" {
+		return nil, fmt.Errorf("oidc: token endpoint: %s %s", token.Error, token.ErrorDescription)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("oidc: token endpoint responded with status %d", status)
+	}
+	if token.IDToken == "" {
+		return nil, fmt.Errorf("oidc: token endpoint returned no id_token")
+	}
+
+	return p.Verify(ctx, token.IDToken, nonce)
+}
+
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	wellKnown := strings.TrimSuffix(p.config.Issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequest(http.MethodGet, wellKnown, nil)
+	if err != nil {
+		return nil, err
+	}
+	var md metadata
+	status, err := p.do(ctx, req, &md)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("oidc: discovery responded with status %d", status)
+	}
+	if md.Issuer != p.config.Issuer {
+		return nil, fmt.Errorf("oidc: discovery document is for issuer %q, expected %q", md.Issuer, p.config.Issuer)
+	}
+	if md.AuthorizationEndpoint == "" || md.TokenEndpoint == "" || md.JWKSURI == "" {
+		return nil, fmt.Errorf("oidc: discovery document is missing endpoints")
+	}
+	p.metadata = &md
+	return p.metadata, nil
+}
+
+// do sends the request and decodes its json response into out, returning the status.
+func (p *Provider) do(ctx context.Context, req *http.Request, out interface{}) (int, error) {
+	resp, err := p.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("oidc: %s", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return 0, fmt.Errorf("oidc: %s", err)
+	}
+	if err := json.Unmarshal(body, out); err != nil && resp.StatusCode == http.StatusOK {
+		return 0, fmt.Errorf("oidc: invalid response from %s: %s", req.URL.Host, err)
+	}
+	return resp.StatusCode, nil
+}
+
+// RandomString returns a random URL-safe string, to use as a state or nonce.
+func RandomString() string {
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func contains(array []string, s string) bool {
+	for _, a := range array {
+		if a == s {
+			return true
+		}
+	}
+	return false
+}
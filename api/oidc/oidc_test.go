@@ -0,0 +1,200 @@
+package oidc_test
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+
+	"leggett.dev/devmarks/api/oidc"
+	"leggett.dev/devmarks/api/oidc/oidctest"
+)
+
+const nonce = "nonce"
+
+func newProvider(t *testing.T) (*oidctest.Provider, *oidc.Provider) {
+	mock := oidctest.NewProvider("devmarks", "secret")
+	t.Cleanup(mock.Close)
+	provider := oidc.NewProvider(oidc.Config{
+		Issuer:       mock.Issuer(),
+		ClientID:     mock.ClientID,
+		ClientSecret: mock.ClientSecret,
+		RedirectURL:  "https://api.local/auth/oidc/callback",
+	}, mock.Client())
+	return mock, provider
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	mock, provider := newProvider(t)
+
+	raw, err := provider.AuthCodeURL(context.Background(), "state", nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := u.Scheme + "://" + u.Host + u.Path; got != mock.URL+"/authorize" {
+		t.Errorf("authorization endpoint = %q, want %q", got, mock.URL+"/authorize")
+	}
+	want := map[string]string{
+		"response_type": "code",
+		"client_id":     "devmarks",
+		"redirect_uri":  "https://api.local/auth/oidc/callback",
+		"scope":         "openid email profile",
+		"state":         "state",
+		"nonce":         nonce,
+	}
+	for name, value := range want {
+		if got := u.Query().Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+}
+
+func TestExchange(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		want   oidc.Claims
+	}{
+		{
+			name:   "verified email",
+			claims: jwt.MapClaims{"sub": "1", "nonce": nonce, "email": "jane@example.com", "email_verified": true, "name": "Jane"},
+			want:   oidc.Claims{Subject: "1", Email: "jane@example.com", EmailVerified: true, Name: "Jane"},
+		},
+		{
+			name:   "unverified email",
+			claims: jwt.MapClaims{"sub": "2", "nonce": nonce, "email": "jane@example.com", "email_verified": false},
+			want:   oidc.Claims{Subject: "2", Email: "jane@example.com"},
+		},
+		{
+			name:   "email_verified as a string",
+			claims: jwt.MapClaims{"sub": "3", "nonce": nonce, "email": "jane@example.com", "email_verified": "true"},
+			want:   oidc.Claims{Subject: "3", Email: "jane@example.com", EmailVerified: true},
+		},
+		{
+			name:   "audience list",
+			claims: jwt.MapClaims{"sub": "4", "nonce": nonce, "aud": []string{"other", "devmarks"}},
+			want:   oidc.Claims{Subject: "4"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mock, provider := newProvider(t)
+			claims, err := provider.Exchange(context.Background(), mock.Code(test.claims), nonce)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *claims != test.want {
+				t.Errorf("claims = %+v, want %+v", *claims, test.want)
+			}
+		})
+	}
+}
+
+func TestExchangeRejects(t *testing.T) {
+	other := oidctest.NewProvider("devmarks", "secret")
+	defer other.Close()
+
+	tests := []struct {
+		name string
+		code func(mock *oidctest.Provider) string
+		want string
+	}{
+		{
+			name: "nonce mismatch",
+			code: func(mock *oidctest.Provider) string {
+				return mock.Code(jwt.MapClaims{"sub": "1", "nonce": "another nonce"})
+			},
+			want: "nonce does not match",
+		},
+		{
+			name: "missing nonce",
+			code: func(mock *oidctest.Provider) string { return mock.Code(jwt.MapClaims{"sub": "1"}) },
+			want: "nonce does not match",
+		},
+		{
+			name: "other audience",
+			code: func(mock *oidctest.Provider) string {
+				return mock.Code(jwt.MapClaims{"sub": "1", "nonce": nonce, "aud": "other"})
+			},
+			want: "not meant for this client",
+		},
+		{
+			name: "other issuer",
+			code: func(mock *oidctest.Provider) string {
+				return mock.Code(jwt.MapClaims{"sub": "1", "nonce": nonce, "iss": "https://evil.example.com"})
+			},
+			want: "issued by",
+		},
+		{
+			name: "expired",
+			code: func(mock *oidctest.Provider) string {
+				return mock.Code(jwt.MapClaims{"sub": "1", "nonce": nonce, "exp": time.Now().Add(-time.Minute).Unix()})
+			},
+			want: "expired",
+		},
+		{
+			name: "no expiry",
+			code: func(mock *oidctest.Provider) string {
+				return mock.Code(jwt.MapClaims{"sub": "1", "nonce": nonce, "exp": nil})
+			},
+			want: "does not expire",
+		},
+		{
+			name: "no subject",
+			code: func(mock *oidctest.Provider) string { return mock.Code(jwt.MapClaims{"nonce": nonce}) },
+			want: "no subject",
+		},
+		{
+			name: "signed by another key",
+			code: func(mock *oidctest.Provider) string {
+				return mock.CodeForIDToken(other.IDToken(jwt.MapClaims{"sub": "1", "nonce": nonce, "iss": mock.Issuer()}))
+			},
+			want: "invalid id token",
+		},
+		{
+			name: "unknown code",
+			code: func(mock *oidctest.Provider) string { return "unknown" },
+			want: "invalid_grant",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mock, provider := newProvider(t)
+			_, err := provider.Exchange(context.Background(), test.code(mock), nonce)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("error = %v, want one containing %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestExchangeRejectsCodeReuse(t *testing.T) {
+	mock, provider := newProvider(t)
+	code := mock.Code(jwt.MapClaims{"sub": "1", "nonce": nonce})
+	if _, err := provider.Exchange(context.Background(), code, nonce); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Exchange(context.Background(), code, nonce); err == nil {
+		t.Error("exchanging a code twice succeeded")
+	}
+}
+
+func TestDiscoveryRejectsOtherIssuer(t *testing.T) {
+	mock := oidctest.NewProvider("devmarks", "secret")
+	defer mock.Close()
+	// the discovery document is found under the configured issuer, but names the
+	// issuer without the trailing slash.
+	provider := oidc.NewProvider(oidc.Config{Issuer: mock.Issuer() + "/", ClientID: "devmarks"}, mock.Client())
+
+	_, err := provider.AuthCodeURL(context.Background(), "state", nonce)
+	if err == nil || !strings.Contains(err.Error(), "discovery document is for issuer") {
+		t.Errorf("error = %v, want a discovery issuer mismatch", err)
+	}
+}
@@ -0,0 +1,160 @@
+// Package oidctest provides a mock OpenID Connect provider for tests. It serves the
+// discovery document, the signing keys and a token endpoint exchanging the codes it
+// was given for ID tokens signed with its own key.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// KeyID is the ID of the key the provider signs ID tokens with.
+const KeyID = "oidctest"
+
+// Provider is a mock OpenID Connect provider running on a local httptest.Server.
+type Provider struct {
+	*httptest.Server
+	ClientID     string
+	ClientSecret string
+
+	key *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]string
+	next  int
+}
+
+// NewProvider starts a provider for the client with the given ID and secret. It must
+// be closed once done with.
+func NewProvider(clientID, clientSecret string) *Provider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	p := &Provider{ClientID: clientID, ClientSecret: clientSecret, key: key, codes: map[string]string{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/jwks", p.jwks)
+	mux.HandleFunc("/token", p.token)
+	p.Server = httptest.NewServer(mux)
+	return p
+}
+
+// Issuer returns the issuer identifier of the provider.
+func (p *Provider) Issuer() string {
+	return p.URL
+}
+
+// IDToken returns an ID token with the given claims signed by the provider. The iss,
+// aud, iat and exp claims are filled in for the client unless they are given; claims
+// set to nil are left out.
+func (p *Provider) IDToken(claims jwt.MapClaims) string {
+	now := time.Now()
+	full := jwt.MapClaims{
+		"iss": p.Issuer(),
+		"aud": p.ClientID,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}
+	for name, value := range claims {
+		if value == nil {
+			delete(full, name)
+			continue
+		}
+		full[name] = value
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, full)
+	token.Header["kid"] = KeyID
+	raw, err := token.SignedString(p.key)
+	if err != nil {
+		panic(err)
+	}
+	return raw
+}
+
+// Code returns an authorization code the token endpoint exchanges, once, for an ID
+// token with the given claims, as described by IDToken.
+func (p *Provider) Code(claims jwt.MapClaims) string {
+	return p.CodeForIDToken(p.IDToken(claims))
+}
+
+// CodeForIDToken returns an authorization code the token endpoint exchanges, once,
+// for the given raw ID token.
+func (p *Provider) CodeForIDToken(raw string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.next++
+	code := fmt.Sprintf("code-%d", p.next)
+	p.codes[code] = raw
+	return code
+}
+
+func (p *Provider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 p.Issuer(),
+		"authorization_endpoint": p.URL + "/authorize",
+		"token_endpoint":         p.URL + "/token",
+		"jwks_uri":               p.URL + "/jwks",
+	})
+}
+
+func (p *Provider) jwks(w http.ResponseWriter, r *http.Request) {
+	public := p.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kid": KeyID,
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+		}},
+	})
+}
+
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if id, secret, ok := r.BasicAuth(); !ok || id != p.ClientID || secret != p.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	if r.PostFormValue("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	p.mu.Lock()
+	code := r.PostFormValue("code")
+	raw, ok := p.codes[code]
+	delete(p.codes, code)
+	p.mu.Unlock()
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     raw,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
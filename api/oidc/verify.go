@@ -0,0 +1,179 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// keysRefreshInterval is the least time between two fetches of the provider's keys, so
+// tokens signed with unknown keys cannot make us hammer the provider.
+const keysRefreshInterval = time.Minute
+
+// Claims are the claims of a verified ID token that identify the user.
+type Claims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// jwk is a single key of a JSON Web Key Set. Only RSA keys are supported.
+type jwk struct {
+	KeyID     string `json:"kid"`
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Modulus   string `json:"n"`
+	Exponent  string `json:"e"`
+	Algorithm string `json:"alg"`
+}
+
+type keySet struct {
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+// Verify checks the signature, issuer, audience, expiry and nonce of a raw ID token and
+// returns its claims.
+func (p *Provider) Verify(ctx context.Context, raw, nonce string) (*Claims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("oidc: invalid id token: %s", err)
+	}
+
+	if !claims.VerifyIssuer(p.config.Issuer, true) {
+		return nil, fmt.Errorf("oidc: id token was issued by %v", claims["iss"])
+	}
+	if !verifyAudience(claims["aud"], p.config.ClientID) {
+		return nil, fmt.Errorf("oidc: id token is not meant for this client")
+	}
+	if _, ok := claims["exp"]; !ok {
+		return nil, fmt.Errorf("oidc: id token does not expire")
+	}
+	if got, _ := claims["nonce"].(string); got != nonce {
+		return nil, fmt.Errorf("oidc: id token nonce does not match")
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, fmt.Errorf("oidc: id token has no subject")
+	}
+	result := &Claims{Subject: subject}
+	result.Email, _ = claims["email"].(string)
+	result.Name, _ = claims["name"].(string)
+	// some providers send email_verified as a string.
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		result.EmailVerified = verified
+	case string:
+		result.EmailVerified = verified == "true"
+	}
+	return result, nil
+}
+
+func verifyAudience(aud interface{}, clientID string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == clientID
+	case []interface{}:
+		for _, a := range aud {
+			if a == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// key returns the provider's public key with the specified ID, fetching the key set
+// again if the key is unknown, for instance after the provider rotated its keys.
+func (p *Provider) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.keys != nil {
+		if key := p.keys.find(kid); key != nil {
+			return key, nil
+		}
+		if time.Since(p.keys.fetchedAt) < keysRefreshInterval {
+			return nil, fmt.Errorf("unknown key %q", kid)
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodGet, md.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	status, err := p.do(ctx, req, &set)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("oidc: jwks endpoint responded with status %d", status)
+	}
+
+	keys := &keySet{keys: map[string]*rsa.PublicKey{}, fetchedAt: time.Now()}
+	for _, k := range set.Keys {
+		if k.KeyType != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		key, err := k.rsaKey()
+		if err != nil {
+			return nil, err
+		}
+		keys.keys[k.KeyID] = key
+	}
+	p.keys = keys
+
+	if key := keys.find(kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key %q", kid)
+}
+
+// find returns the key with the specified ID. Tokens without a key ID can only be
+// verified if the provider has a single key.
+func (s *keySet) find(kid string) *rsa.PublicKey {
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key
+		}
+	}
+	return s.keys[kid]
+}
+
+func (k jwk) rsaKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.Modulus)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: invalid key %q: %s", k.KeyID, err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.Exponent)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: invalid key %q: %s", k.KeyID, err)
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+		return nil, fmt.Errorf("oidc: invalid key %q: exponent too large", k.KeyID)
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
@@ -0,0 +1,128 @@
+package outbound
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+)
+
+// blockedNets are the ranges, besides the loopback, link-local, multicast and
+// unspecified addresses, that are not reachable on the public internet.
+var blockedNets = mustParseCIDRs(
+	"0.0.0.0/8",          // "this" network
+	"10.0.0.0/8",         // private
+	"100.64.0.0/10",      // carrier-grade NAT
+	"172.16.0.0/12",      // private
+	"192.0.0.0/24",       // IETF protocol assignments
+	"192.168.0.0/16",     // private
+	"198.18.0.0/15",      // benchmarking
+	"240.0.0.0/4",        // reserved
+	"255.255.255.255/32", // broadcast
+	"64:ff9b::/96",       // IPv4/IPv6 translation
+	"fc00::/7",           // unique local
+)
+
+// blocked reports whether ip is not on the public internet.
+func blocked(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return true
+	}
+	for _, n := range blockedNets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowlist holds the hosts and networks that can be reached even though they are
+// blocked.
+type allowlist struct {
+	hosts []string
+	nets  []*net.IPNet
+}
+
+func parseAllowlist(entries []string) (*allowlist, error) {
+	allow := &allowlist{}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		switch {
+		case entry == "":
+		case strings.Contains(entry, "/"):
+			_, n, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid allowed network %q: %v", entry, err)
+			}
+			allow.nets = append(allow.nets, n)
+		case net.ParseIP(entry) != nil:
+			ip := net.ParseIP(entry)
+			bits := 8 * len(ip)
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			allow.nets = append(allow.nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		default:
+			allow.hosts = append(allow.hosts, strings.ToLower(strings.TrimSuffix(entry, ".")))
+		}
+	}
+	return allow, nil
+}
+
+func (allow *allowlist) allowsHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, h := range allow.hosts {
+		if h == host {
+			return true
+		}
+	}
+	return false
+}
+
+func (allow *allowlist) allowsIP(ip net.IP) bool {
+	for _, n := range allow.nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// dialContext returns a DialContext function dialing with dialer, which refuses to
+// connect to blocked addresses unless they are allowed. The address is checked when
+// connecting, after the host was resolved, so a host cannot be made to resolve to a
+// public address when checked and a private one when dialed.
+func (allow *allowlist) dialContext(dialer *net.Dialer) func(ctx context.Context, network, address string) (net.Conn, error) {
+	checked := *dialer
+	checked.Control = func(network, address string, _ syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		ip := net.ParseIP(host)
+		if ip == nil || (blocked(ip) && !allow.allowsIP(ip)) {
+			return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+		}
+		return nil
+	}
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		if host, _, err := net.SplitHostPort(address); err == nil && allow.allowsHost(host) {
+			return dialer.DialContext(ctx, network, address)
+		}
+		return checked.DialContext(ctx, network, address)
+	}
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets[i] = n
+	}
+	return nets
+}
@@ -0,0 +1,88 @@
+package outbound
+
+import (
+	"net"
+	"testing"
+)
+
+func TestBlocked(t *testing.T) {
+	tests := []struct {
+		ip      string
+		blocked bool
+	}{
+		{"127.0.0.1", true},
+		{"127.1.2.3", true},
+		{"::1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"172.31.255.255", true},
+		{"192.168.1.1", true},
+		{"fd00::1", true},
+		{"169.254.169.254", true},
+		{"fe80::1", true},
+		{"100.64.0.1", true},
+		{"100.127.255.255", true},
+		{"0.0.0.0", true},
+		{"::", true},
+		{"224.0.0.1", true},
+		{"255.255.255.255", true},
+		{"::ffff:127.0.0.1", true},
+		{"::ffff:10.0.0.1", true},
+		{"::ffff:169.254.169.254", true},
+		{"64:ff9b::a00:1", true},
+		{"8.8.8.8", false},
+		{"100.128.0.1", false},
+		{"172.32.0.1", false},
+		{"::ffff:8.8.8.8", false},
+		{"2001:4860:4860::8888", false},
+	}
+	for _, test := range tests {
+		if got := blocked(net.ParseIP(test.ip)); got != test.blocked {
+			t.Errorf("blocked(%s) = %v, want %v", test.ip, got, test.blocked)
+		}
+	}
+}
+
+func TestAllowlist(t *testing.T) {
+	allow, err := parseAllowlist([]string{"Intranet.Example.com.", "10.0.0.0/8", "192.168.1.5", "fd00::1", " "})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hosts := []struct {
+		host    string
+		allowed bool
+	}{
+		{"intranet.example.com", true},
+		{"INTRANET.example.com.", true},
+		{"example.com", false},
+		{"other.intranet.example.com", false},
+	}
+	for _, test := range hosts {
+		if got := allow.allowsHost(test.host); got != test.allowed {
+			t.Errorf("allowsHost(%q) = %v, want %v", test.host, got, test.allowed)
+		}
+	}
+
+	ips := []struct {
+		ip      string
+		allowed bool
+	}{
+		{"10.20.30.40", true},
+		{"::ffff:10.0.0.1", true},
+		{"192.168.1.5", true},
+		{"192.168.1.6", false},
+		{"fd00::1", true},
+		{"fd00::2", false},
+		{"127.0.0.1", false},
+	}
+	for _, test := range ips {
+		if got := allow.allowsIP(net.ParseIP(test.ip)); got != test.allowed {
+			t.Errorf("allowsIP(%s) = %v, want %v", test.ip, got, test.allowed)
+		}
+	}
+
+	if _, err := parseAllowlist([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("parseAllowlist accepted an invalid network")
+	}
+}
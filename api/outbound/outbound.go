@@ -0,0 +1,146 @@
+// Package outbound provides the http.Client devmarks fetches URLs chosen by its users
+// with, such as the pages bookmarks point to. It refuses to reach the private,
+// loopback and link-local addresses of the network devmarks runs in, checking the
+// address it actually connects to after DNS resolution, and limits the schemes,
+// redirects and response sizes it accepts.
+package outbound
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Defaults for the Config settings left unset.
+const (
+	DefaultTimeout      = 30 * time.Second
+	DefaultMaxRedirects = 5
+	DefaultMaxBytes     = 10 << 20
+)
+
+// DefaultSchemes are the URL schemes allowed when Config.Schemes is empty.
+var DefaultSchemes = []string{"http", "https"}
+
+// Errors returned, wrapped, by the requests of the client when they are refused.
+var (
+	ErrForbiddenAddress = errors.New("address not allowed")
+	ErrForbiddenScheme  = errors.New("url scheme not allowed")
+	ErrTooManyRedirects = errors.New("too many redirects")
+	ErrResponseTooLarge = errors.New("response too large")
+)
+
+// Config limits what the client does.
+type Config struct {
+	// Timeout bounds each request, including following its redirects and reading
+	// the response.
+	Timeout time.Duration
+	// MaxRedirects is how many redirects a request follows.
+	MaxRedirects int
+	// MaxBytes is the largest response body read; reading more fails with
+	// ErrResponseTooLarge.
+	MaxBytes int64
+	// Schemes are the URL schemes requests and redirects can use.
+	Schemes []string
+	// Allow lists the hosts, IP addresses and CIDR ranges that can be reached even
+	// though they are private, such as an intranet whose pages are bookmarked.
+	Allow []string
+}
+
+// New returns a client that follows config.
+func New(config Config) (*http.Client, error) {
+	if config.Timeout == 0 {
+		config.Timeout = DefaultTimeout
+	}
+	if config.MaxRedirects == 0 {
+		config.MaxRedirects = DefaultMaxRedirects
+	}
+	if config.MaxBytes == 0 {
+		config.MaxBytes = DefaultMaxBytes
+	}
+	if len(config.Schemes) == 0 {
+		config.Schemes = DefaultSchemes
+	}
+	allow, err := parseAllowlist(config.Allow)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// a proxy would be dialed instead of the hosts requested, so they would go unchecked.
+	transport.Proxy = nil
+	transport.DialContext = allow.dialContext(&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	})
+
+	return &http.Client{
+		Transport: &roundTripper{
+			transport: transport,
+			schemes:   config.Schemes,
+			maxBytes:  config.MaxBytes,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > config.MaxRedirects {
+				return fmt.Errorf("%w: stopped after %d", ErrTooManyRedirects, config.MaxRedirects)
+			}
+			return nil
+		},
+		Timeout: config.Timeout,
+	}, nil
+}
+
+// roundTripper refuses requests using schemes that are not allowed, including the
+// ones redirects lead to, and caps the size of the responses.
+type roundTripper struct {
+	transport http.RoundTripper
+	schemes   []string
+	maxBytes  int64
+}
+
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !containsFold(rt.schemes, req.URL.Scheme) {
+		return nil, fmt.Errorf("%w: %q", ErrForbiddenScheme, req.URL.Scheme)
+	}
+	resp, err := rt.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = &limitedBody{ReadCloser: resp.Body, remaining: rt.maxBytes}
+	return resp, nil
+}
+
+// limitedBody fails with ErrResponseTooLarge once more than its remaining bytes
+// have been read.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, ErrResponseTooLarge
+	}
+	// read one byte past the limit to tell a body of exactly the limit from a larger one.
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n + int(b.remaining), ErrResponseTooLarge
+	}
+	return n, err
+}
+
+func containsFold(values []string, s string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, s) {
+			return true
+		}
+	}
+	return false
+}
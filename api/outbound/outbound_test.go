@@ -0,0 +1,127 @@
+package outbound
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newServer serves body at every path, redirecting the paths in redirects to their
+// targets.
+func newServer(t *testing.T, body string, redirects map[string]string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if target, ok := redirects[r.URL.Path]; ok {
+			http.Redirect(w, r, target, http.StatusFound)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// localhostURL returns the URL of the server with the host localhost instead of its
+// IP address.
+func localhostURL(t *testing.T, server *httptest.Server) string {
+	_, port, err := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "http://localhost:" + port
+}
+
+func get(t *testing.T, config Config, url string) (string, error) {
+	t.Helper()
+	client, err := New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	return string(body), err
+}
+
+func TestClientRefusesBlockedAddresses(t *testing.T) {
+	server := newServer(t, "ok", nil)
+
+	if _, err := get(t, Config{}, server.URL); !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("loopback server: err = %v, want %v", err, ErrForbiddenAddress)
+	}
+	// the host resolves to a blocked address, which is checked once it is resolved.
+	if _, err := get(t, Config{}, localhostURL(t, server)); !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("localhost: err = %v, want %v", err, ErrForbiddenAddress)
+	}
+
+	for _, allow := range []string{"127.0.0.1", "127.0.0.0/8"} {
+		body, err := get(t, Config{Allow: []string{allow}}, server.URL)
+		if err != nil || body != "ok" {
+			t.Errorf("allowing %s: body = %q, err = %v, want ok", allow, body, err)
+		}
+	}
+	body, err := get(t, Config{Allow: []string{"localhost"}}, localhostURL(t, server))
+	if err != nil || body != "ok" {
+		t.Errorf("allowing localhost: body = %q, err = %v, want ok", body, err)
+	}
+}
+
+func TestClientRefusesRedirectsToBlockedHosts(t *testing.T) {
+	blockedServer := newServer(t, "secret", nil)
+	server := newServer(t, "ok", map[string]string{"/redirect": blockedServer.URL + "/"})
+
+	// the allowed host redirects to the address of another server, which is not allowed.
+	config := Config{Allow: []string{"localhost"}}
+	if _, err := get(t, config, localhostURL(t, server)+"/redirect"); !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("err = %v, want %v", err, ErrForbiddenAddress)
+	}
+}
+
+func TestClientRefusesSchemesAndRedirectLoops(t *testing.T) {
+	server := newServer(t, "ok", map[string]string{"/ftp": "ftp://example.com/", "/loop": "/loop"})
+	config := Config{Allow: []string{"127.0.0.1"}}
+
+	if _, err := get(t, config, server.URL+"/ftp"); !errors.Is(err, ErrForbiddenScheme) {
+		t.Errorf("redirect to ftp: err = %v, want %v", err, ErrForbiddenScheme)
+	}
+	if _, err := get(t, config, server.URL+"/loop"); !errors.Is(err, ErrTooManyRedirects) {
+		t.Errorf("redirect loop: err = %v, want %v", err, ErrTooManyRedirects)
+	}
+}
+
+func TestLimitedBody(t *testing.T) {
+	tests := []struct {
+		body     string
+		maxBytes int64
+		err      error
+	}{
+		{"", 4, nil},
+		{"abc", 4, nil},
+		{"abcd", 4, nil},
+		{"abcde", 4, ErrResponseTooLarge},
+		{strings.Repeat("a", 10000), 4, ErrResponseTooLarge},
+	}
+	for _, test := range tests {
+		body := &limitedBody{ReadCloser: ioutil.NopCloser(strings.NewReader(test.body)), remaining: test.maxBytes}
+		read, err := ioutil.ReadAll(body)
+		if !errors.Is(err, test.err) {
+			t.Errorf("reading %d bytes with a limit of %d: err = %v, want %v", len(test.body), test.maxBytes, err, test.err)
+		}
+		if int64(len(read)) > test.maxBytes {
+			t.Errorf("reading %d bytes with a limit of %d: read %d bytes", len(test.body), test.maxBytes, len(read))
+		}
+	}
+
+	// the cap applies to the responses of the client.
+	server := newServer(t, strings.Repeat("a", 100), nil)
+	config := Config{Allow: []string{"127.0.0.1"}, MaxBytes: 10}
+	if _, err := get(t, config, server.URL); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("large response: err = %v, want %v", err, ErrResponseTooLarge)
+	}
+}
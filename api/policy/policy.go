@@ -0,0 +1,119 @@
+// Package policy decides what each user is allowed to do with each resource. Every
+// access check in the app goes through a Policy, so the rules live in one place.
+package policy
+
+import (
+	"reflect"
+
+	"leggett.dev/devmarks/api/model"
+)
+
+// Level is the access a user has to a resource. Levels are ordered, so a user with
+// a higher level can do everything a lower one allows.
+type Level int
+
+const (
+	// None means the user cannot see the resource at all.
+	None Level = iota
+	// Read means the user can see the resource.
+	Read
+	// Write means the user can change the resource.
+	Write
+	// Owner means the user owns the resource, and can delete or share it.
+	Owner
+)
+
+// Granter grants access to resources other than through ownership, for instance
+// through a shared folder or membership of an organization. It returns None for
+// resources it knows nothing about.
+type Granter interface {
+	Grant(user *model.User, resource interface{}) (Level, error)
+}
+
+// Policy computes the access level of users on resources: owners have full access,
+// and every registered Granter can grant more.
+type Policy struct {
+	granters []Granter
+}
+
+// New returns a Policy that consults the given granters in addition to ownership.
+func New(granters ...Granter) *Policy {
+	return &Policy{granters: granters}
+}
+
+// Register adds a granter to the policy.
+func (p *Policy) Register(granter Granter) {
+	p.granters = append(p.granters, granter)
+}
+
+// Access returns the highest access level the user has to the resource.
+func (p *Policy) Access(user *model.User, resource interface{}) (Level, error) {
+	if user == nil || isNil(resource) {
+		return None, nil
+	}
+	if ownerID, ok := OwnerID(resource); ok && ownerID == user.ID {
+		return Owner, nil
+	}
+
+	level := None
+	for _, granter := range p.granters {
+		granted, err := granter.Grant(user, resource)
+		if err != nil {
+			return None, err
+		}
+		if granted > level {
+			level = granted
+		}
+	}
+	return level, nil
+}
+
+// CanRead returns true if the user can see the resource.
+func (p *Policy) CanRead(user *model.User, resource interface{}) (bool, error) {
+	return p.can(user, resource, Read)
+}
+
+// CanWrite returns true if the user can change the resource.
+func (p *Policy) CanWrite(user *model.User, resource interface{}) (bool, error) {
+	return p.can(user, resource, Write)
+}
+
+// CanManage returns true if the user can delete or share the resource.
+func (p *Policy) CanManage(user *model.User, resource interface{}) (bool, error) {
+	return p.can(user, resource, Owner)
+}
+
+func (p *Policy) can(user *model.User, resource interface{}, required Level) (bool, error) {
+	level, err := p.Access(user, resource)
+	if err != nil {
+		return false, err
+	}
+	return level >= required, nil
+}
+
+// isNil returns true if the resource is nil, including a nil pointer to a model.
+func isNil(resource interface{}) bool {
+	if resource == nil {
+		return true
+	}
+	v := reflect.ValueOf(resource)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
+
+// OwnerID returns the ID of the user owning the resource, and false if the resource
+// is not something users can own.
+func OwnerID(resource interface{}) (uint, bool) {
+	switch r := resource.(type) {
+	case *model.Bookmark:
+		return r.OwnerID, true
+	case *model.Folder:
+		return r.OwnerID, true
+	case *model.Tag:
+		return r.OwnerID, true
+	case *model.Token:
+		return r.UserID, true
+	case *model.Organization:
+		return r.OwnerID, true
+	}
+	return 0, false
+}
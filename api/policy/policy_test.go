@@ -0,0 +1,172 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"leggett.dev/devmarks/api/model"
+)
+
+// fakeGranter grants the same level, or fails with the same error, for every resource.
+type fakeGranter struct {
+	level Level
+	err   error
+}
+
+func (g fakeGranter) Grant(user *model.User, resource interface{}) (Level, error) {
+	return g.level, g.err
+}
+
+// ownedBy returns a resource of each type users can own, owned by the user with the
+// specified ID.
+func ownedBy(id uint) map[string]interface{} {
+	return map[string]interface{}{
+		"bookmark":     &model.Bookmark{OwnerID: id},
+		"folder":       &model.Folder{OwnerID: id},
+		"tag":          &model.Tag{OwnerID: id},
+		"token":        &model.Token{UserID: id},
+		"organization": &model.Organization{OwnerID: id},
+	}
+}
+
+func TestAccess(t *testing.T) {
+	errGrant := errors.New("grant failed")
+	owner := &model.User{Model: model.Model{ID: 1}}
+	other := &model.User{Model: model.Model{ID: 2}}
+
+	tests := []struct {
+		name     string
+		user     *model.User
+		ownerID  uint
+		granters []Granter
+		want     Level
+		wantErr  error
+	}{
+		{name: "owner", user: owner, ownerID: 1, want: Owner},
+		{name: "owner is not limited by granters", user: owner, ownerID: 1, granters: []Granter{fakeGranter{level: Read}}, want: Owner},
+		{name: "owner skips failing granters", user: owner, ownerID: 1, granters: []Granter{fakeGranter{err: errGrant}}, want: Owner},
+		{name: "non-owner", user: other, ownerID: 1, want: None},
+		{name: "non-owner granted read", user: other, ownerID: 1, granters: []Granter{fakeGranter{level: Read}}, want: Read},
+		{name: "highest granter wins", user: other, ownerID: 1, granters: []Granter{fakeGranter{level: Write}, fakeGranter{level: None}, fakeGranter{level: Read}}, want: Write},
+		{name: "granters can grant ownership", user: other, ownerID: 1, granters: []Granter{fakeGranter{level: Owner}}, want: Owner},
+		{name: "granter error", user: other, ownerID: 1, granters: []Granter{fakeGranter{level: Write}, fakeGranter{err: errGrant}}, want: None, wantErr: errGrant},
+		{name: "nil user", user: nil, ownerID: 0, granters: []Granter{fakeGranter{level: Write}}, want: None},
+		{name: "nil user without granters", user: nil, ownerID: 0, want: None},
+	}
+	for _, test := range tests {
+		for kind, resource := range ownedBy(test.ownerID) {
+			t.Run(fmt.Sprintf("%s/%s", test.name, kind), func(t *testing.T) {
+				got, err := New(test.granters...).Access(test.user, resource)
+				if err != test.wantErr {
+					t.Fatalf("Access() error = %v, want %v", err, test.wantErr)
+				}
+				if got != test.want {
+					t.Errorf("Access() = %v, want %v", got, test.want)
+				}
+			})
+		}
+	}
+}
+
+func TestAccessNilResource(t *testing.T) {
+	user := &model.User{Model: model.Model{ID: 1}}
+	granters := []Granter{fakeGranter{level: Write}}
+
+	resources := map[string]interface{}{
+		"nil":                  nil,
+		"nil bookmark":         (*model.Bookmark)(nil),
+		"nil folder":           (*model.Folder)(nil),
+		"nil tag":              (*model.Tag)(nil),
+		"nil token":            (*model.Token)(nil),
+		"nil organization":     (*model.Organization)(nil),
+		"nil unknown resource": (*model.User)(nil),
+	}
+	for name, resource := range resources {
+		t.Run(name, func(t *testing.T) {
+			got, err := New(granters...).Access(user, resource)
+			if err != nil || got != None {
+				t.Errorf("Access() = %v, %v, want None", got, err)
+			}
+		})
+	}
+}
+
+func TestAccessUnownableResource(t *testing.T) {
+	// users are not owned by anyone, so only granters decide.
+	user := &model.User{Model: model.Model{ID: 1}}
+	resource := &model.User{Model: model.Model{ID: 1}}
+
+	if got, _ := New().Access(user, resource); got != None {
+		t.Errorf("Access() without granters = %v, want None", got)
+	}
+	if got, _ := New(fakeGranter{level: Read}).Access(user, resource); got != Read {
+		t.Errorf("Access() with a granter = %v, want Read", got)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	user := &model.User{Model: model.Model{ID: 2}}
+	resource := &model.Bookmark{OwnerID: 1}
+	p := New()
+	p.Register(fakeGranter{level: Write})
+
+	if got, _ := p.Access(user, resource); got != Write {
+		t.Errorf("Access() = %v, want the registered granter's Write", got)
+	}
+}
+
+func TestCan(t *testing.T) {
+	errGrant := errors.New("grant failed")
+	user := &model.User{Model: model.Model{ID: 2}}
+	resource := &model.Folder{OwnerID: 1}
+
+	tests := []struct {
+		name                string
+		granter             Granter
+		read, write, manage bool
+		wantErr             error
+	}{
+		{name: "none", granter: fakeGranter{level: None}},
+		{name: "read", granter: fakeGranter{level: Read}, read: true},
+		{name: "write", granter: fakeGranter{level: Write}, read: true, write: true},
+		{name: "owner", granter: fakeGranter{level: Owner}, read: true, write: true, manage: true},
+		{name: "error", granter: fakeGranter{err: errGrant}, wantErr: errGrant},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := New(test.granter)
+			checks := []struct {
+				name string
+				can  func(*model.User, interface{}) (bool, error)
+				want bool
+			}{
+				{"CanRead", p.CanRead, test.read},
+				{"CanWrite", p.CanWrite, test.write},
+				{"CanManage", p.CanManage, test.manage},
+			}
+			for _, check := range checks {
+				got, err := check.can(user, resource)
+				if err != test.wantErr {
+					t.Errorf("%s() error = %v, want %v", check.name, err, test.wantErr)
+				}
+				if got != check.want {
+					t.Errorf("%s() = %v, want %v", check.name, got, check.want)
+				}
+			}
+		})
+	}
+}
+
+func TestOwnerID(t *testing.T) {
+	for kind, resource := range ownedBy(7) {
+		t.Run(kind, func(t *testing.T) {
+			if id, ok := OwnerID(resource); !ok || id != 7 {
+				t.Errorf("OwnerID() = %d, %v, want 7, true", id, ok)
+			}
+		})
+	}
+	if _, ok := OwnerID(&model.User{}); ok {
+		t.Error("OwnerID() of a user is ok, want users to be unownable")
+	}
+}
@@ -0,0 +1,179 @@
+// Package search implements the small query language used to search bookmarks,
+// e.g. `tag:go folder:work is:unread site:github.com "exact phrase" -excluded`,
+// and turns parsed queries into gorm scopes the db package can apply.
+package search
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Field names that can be used as filters, in the form `field:value`.
+const (
+	FieldTag    = "tag"
+	FieldFolder = "folder"
+	FieldIs     = "is"
+	FieldSite   = "site"
+)
+
+// Values accepted by the `is:` filter.
+const (
+	IsRead   = "read"
+	IsUnread = "unread"
+)
+
+// Filter is a `field:value` restriction in a query. Negated filters were written
+// with a leading dash, e.g. `-tag:go`.
+type Filter struct {
+	Field   string
+	Value   string
+	Negated bool
+}
+
+// Query is the parsed form of a search string.
+type Query struct {
+	// Terms are bare words that must all match the full-text index.
+	Terms []string
+	// Phrases are quoted strings that must match the full-text index in order.
+	Phrases []string
+	// Excluded are words or phrases that must not match the full-text index.
+	Excluded []string
+	// Filters are the field restrictions in the query.
+	Filters []Filter
+}
+
+// IsEmpty returns true if the query places no restrictions on the results.
+func (q *Query) IsEmpty() bool {
+	return len(q.Terms) == 0 && len(q.Phrases) == 0 && len(q.Excluded) == 0 && len(q.Filters) == 0
+}
+
+// ParseError describes why a search string could not be parsed.
+type ParseError struct {
+	Position int
+	Message  string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("invalid search query at position %d: %s", e.Position, e.Message)
+}
+
+// token is a single whitespace separated element of a search string.
+type token struct {
+	position int
+	negated  bool
+	quoted   bool
+	field    string
+	value    string
+}
+
+// Parse parses a search string into a Query.
+func Parse(input string) (*Query, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+
+	query := &Query{}
+	for _, t := range tokens {
+		if t.field != "" {
+			filter, err := newFilter(t)
+			if err != nil {
+				return nil, err
+			}
+			query.Filters = append(query.Filters, filter)
+			continue
+		}
+		switch {
+		case t.negated:
+			query.Excluded = append(query.Excluded, t.value)
+		case t.quoted:
+			query.Phrases = append(query.Phrases, t.value)
+		default:
+			query.Terms = append(query.Terms, t.value)
+		}
+	}
+	return query, nil
+}
+
+func isField(name string) bool {
+	switch strings.ToLower(name) {
+	case FieldTag, FieldFolder, FieldIs, FieldSite:
+		return true
+	}
+	return false
+}
+
+func newFilter(t token) (Filter, error) {
+	filter := Filter{Field: t.field, Value: t.value, Negated: t.negated}
+	switch filter.Field {
+	case FieldSite:
+		filter.Value = strings.TrimPrefix(strings.ToLower(filter.Value), "www.")
+	case FieldIs:
+		filter.Value = strings.ToLower(filter.Value)
+		if filter.Value != IsRead && filter.Value != IsUnread {
+			return filter, &ParseError{t.position, fmt.Sprintf("unknown value for is: %q", t.value)}
+		}
+	}
+	return filter, nil
+}
+
+// tokenize splits a search string on whitespace, keeping quoted strings together
+// and separating out negation and `field:` prefixes.
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	runes := []rune(input)
+	i := 0
+	for i < len(runes) {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		t := token{position: i}
+		if runes[i] == '-' {
+			t.negated = true
+			i++
+		}
+
+		// a field prefix is a known field name directly followed by a colon. Anything
+		// else, like the scheme of a URL, is left as part of the value.
+		j := i
+		for j < len(runes) && unicode.IsLetter(runes[j]) {
+			j++
+		}
+		if j < len(runes) && runes[j] == ':' && isField(string(runes[i:j])) {
+			t.field = strings.ToLower(string(runes[i:j]))
+			i = j + 1
+		}
+
+		if i < len(runes) && runes[i] == '"' {
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, &ParseError{i, "unterminated quote"}
+			}
+			t.quoted = true
+			t.value = strings.TrimSpace(string(runes[i+1 : end]))
+			i = end + 1
+		} else {
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) {
+				i++
+			}
+			t.value = string(runes[start:i])
+		}
+
+		if t.value == "" {
+			if t.field != "" {
+				return nil, &ParseError{t.position, fmt.Sprintf("missing value for %s:", t.field)}
+			}
+			// a lone dash or empty quotes carries no meaning, so skip it.
+			continue
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, nil
+}
@@ -0,0 +1,161 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  Query
+	}{
+		{
+			name:  "empty",
+			input: "",
+			want:  Query{},
+		},
+		{
+			name:  "whitespace only",
+			input: " \t\n ",
+			want:  Query{},
+		},
+		{
+			name:  "terms",
+			input: "go  generics",
+			want:  Query{Terms: []string{"go", "generics"}},
+		},
+		{
+			name:  "tag",
+			input: "tag:go",
+			want:  Query{Filters: []Filter{{Field: FieldTag, Value: "go"}}},
+		},
+		{
+			name:  "field names are case insensitive",
+			input: "TAG:Go",
+			want:  Query{Filters: []Filter{{Field: FieldTag, Value: "Go"}}},
+		},
+		{
+			name:  "quoted folder",
+			input: `folder:"side projects"`,
+			want:  Query{Filters: []Filter{{Field: FieldFolder, Value: "side projects"}}},
+		},
+		{
+			name:  "is unread",
+			input: "is:unread",
+			want:  Query{Filters: []Filter{{Field: FieldIs, Value: IsUnread}}},
+		},
+		{
+			name:  "is read",
+			input: "is:READ",
+			want:  Query{Filters: []Filter{{Field: FieldIs, Value: IsRead}}},
+		},
+		{
+			name:  "site drops www and case",
+			input: "site:WWW.GitHub.com",
+			want:  Query{Filters: []Filter{{Field: FieldSite, Value: "github.com"}}},
+		},
+		{
+			name:  "phrase",
+			input: `"exact phrase"`,
+			want:  Query{Phrases: []string{"exact phrase"}},
+		},
+		{
+			name:  "phrase is trimmed",
+			input: `"  padded  "`,
+			want:  Query{Phrases: []string{"padded"}},
+		},
+		{
+			name:  "negated term",
+			input: "-excluded",
+			want:  Query{Excluded: []string{"excluded"}},
+		},
+		{
+			name:  "negated phrase",
+			input: `-"not this"`,
+			want:  Query{Excluded: []string{"not this"}},
+		},
+		{
+			name:  "negated filter",
+			input: "-tag:go",
+			want:  Query{Filters: []Filter{{Field: FieldTag, Value: "go", Negated: true}}},
+		},
+		{
+			name:  "unknown field is a term",
+			input: "https://example.com",
+			want:  Query{Terms: []string{"https://example.com"}},
+		},
+		{
+			name:  "lone dash and empty quotes are skipped",
+			input: `- "" go`,
+			want:  Query{Terms: []string{"go"}},
+		},
+		{
+			name:  "quote inside a word is literal",
+			input: `go"`,
+			want:  Query{Terms: []string{`go"`}},
+		},
+		{
+			name:  "dash inside a word",
+			input: "x-ray",
+			want:  Query{Terms: []string{"x-ray"}},
+		},
+		{
+			name:  "everything",
+			input: `tag:go folder:work is:unread site:github.com "exact phrase" -excluded generics`,
+			want: Query{
+				Terms:    []string{"generics"},
+				Phrases:  []string{"exact phrase"},
+				Excluded: []string{"excluded"},
+				Filters: []Filter{
+					{Field: FieldTag, Value: "go"},
+					{Field: FieldFolder, Value: "work"},
+					{Field: FieldIs, Value: IsUnread},
+					{Field: FieldSite, Value: "github.com"},
+				},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Parse(test.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) returned error %v", test.input, err)
+			}
+			if !reflect.DeepEqual(*got, test.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", test.input, *got, test.want)
+			}
+			if got.IsEmpty() != reflect.DeepEqual(test.want, Query{}) {
+				t.Errorf("Parse(%q).IsEmpty() = %v", test.input, got.IsEmpty())
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		position int
+	}{
+		{name: "unterminated quote", input: `"exact phrase`, position: 0},
+		{name: "unterminated quote after terms", input: `go "exact`, position: 3},
+		{name: "unterminated quoted filter", input: `folder:"side`, position: 7},
+		{name: "missing filter value", input: "tag:", position: 0},
+		{name: "empty quoted filter value", input: `go folder:""`, position: 3},
+		{name: "unknown is value", input: "is:starred", position: 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse(test.input)
+			parseErr, ok := err.(*ParseError)
+			if !ok {
+				t.Fatalf("Parse(%q) error = %v, want a *ParseError", test.input, err)
+			}
+			if parseErr.Position != test.position {
+				t.Errorf("Parse(%q) error at position %d, want %d", test.input, parseErr.Position, test.position)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package search
+
+import (
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+// DocumentSQL is the tsvector expression searched for bookmarks. It must match the
+// expression of the bookmarks_search_idx index exactly for the index to be used.
+const DocumentSQL = "to_tsvector('english', coalesce(bookmarks.name, '') || ' ' || coalesce(bookmarks.url, '') || ' ' || coalesce(bookmarks.notes, ''))"
+
+// hostSQL extracts the lowercased host name from a bookmark's URL. The question mark
+// is written as \x3f because gorm treats every literal one as a bind variable.
+const hostSQL = "substring(lower(bookmarks.url) from '^[a-z][a-z0-9+.-]*://([^/:\\x3f#]+)')"
+
+const tagExistsSQL = `EXISTS (SELECT 1 FROM bookmark_tag JOIN tags ON tags.id = bookmark_tag.tag_id
+	WHERE bookmark_tag.bookmark_id = bookmarks.id AND tags.deleted_at IS NULL AND lower(tags.name) = lower(?))`
+
+const folderExistsSQL = `EXISTS (SELECT 1 FROM bookmark_folder JOIN folders ON folders.id = bookmark_folder.folder_id
+	WHERE bookmark_folder.bookmark_id = bookmarks.id AND folders.deleted_at IS NULL AND lower(folders.name) = lower(?))`
+
+// Scopes returns the gorm scopes that restrict a query on the bookmarks table to the
+// results of the search, ordered by relevance when there are any search terms.
+func (q *Query) Scopes() []func(*gorm.DB) *gorm.DB {
+	var scopes []func(*gorm.DB) *gorm.DB
+
+	for _, term := range q.Terms {
+		scopes = append(scopes, where(DocumentSQL+" @@ plainto_tsquery('english', ?)", term))
+	}
+	for _, phrase := range q.Phrases {
+		scopes = append(scopes, where(DocumentSQL+" @@ phraseto_tsquery('english', ?)", phrase))
+	}
+	for _, excluded := range q.Excluded {
+		scopes = append(scopes, where("NOT ("+DocumentSQL+" @@ phraseto_tsquery('english', ?))", excluded))
+	}
+	for _, filter := range q.Filters {
+		scopes = append(scopes, filter.scope())
+	}
+
+	if text := strings.Join(append(append([]string{}, q.Terms...), q.Phrases...), " "); text != "" {
+		scopes = append(scopes, func(db *gorm.DB) *gorm.DB {
+			order := gorm.Expr("ts_rank("+DocumentSQL+", plainto_tsquery('english', ?)) DESC", text)
+			return db.Order(order)
+		})
+	}
+	return scopes
+}
+
+func (f Filter) scope() func(*gorm.DB) *gorm.DB {
+	var sql string
+	var args []interface{}
+	switch f.Field {
+	case FieldTag:
+		sql, args = tagExistsSQL, []interface{}{f.Value}
+	case FieldFolder:
+		sql, args = folderExistsSQL, []interface{}{f.Value}
+	case FieldSite:
+		sql, args = "("+hostSQL+" = ? OR "+hostSQL+" LIKE ?)", []interface{}{f.Value, "%." + f.Value}
+	case FieldIs:
+		if f.Value == IsRead {
+			sql = "bookmarks.read_at IS NOT NULL"
+		} else {
+			sql = "bookmarks.read_at IS NULL"
+		}
+	}
+	if f.Negated {
+		sql = "NOT (" + sql + ")"
+	}
+	return where(sql, args...)
+}
+
+func where(sql string, args ...interface{}) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(sql, args...)
+	}
+}
@@ -0,0 +1,77 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 secret of the test vectors of RFC 6238, base32 encoded.
+var rfcSecret = encoding.EncodeToString([]byte("12345678901234567890"))
+
+// TestCode checks the test vectors of RFC 6238 for SHA-1. The RFC gives eight digit
+// codes; the six digit codes are their last six digits.
+func TestCode(t *testing.T) {
+	tests := []struct {
+		time int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, test := range tests {
+		code, err := Code(rfcSecret, Step(time.Unix(test.time, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != test.code {
+			t.Errorf("code at %d = %s, want %s", test.time, code, test.code)
+		}
+	}
+
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("Code accepted an invalid secret")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+	code := func(step int64) string {
+		code, err := Code(rfcSecret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name  string
+		code  string
+		after int64
+		step  int64
+		ok    bool
+	}{
+		{"current", code(current), 0, current, true},
+		{"spaced", " 050 471 ", 0, current, true},
+		{"previous", code(current - 1), 0, current - 1, true},
+		{"next", code(current + 1), 0, current + 1, true},
+		{"too old", code(current - 2), 0, 0, false},
+		{"too new", code(current + 2), 0, 0, false},
+		{"wrong", "000000", 0, 0, false},
+		{"too short", "05047", 0, 0, false},
+		{"used", code(current), current, 0, false},
+		{"before used", code(current - 1), current, 0, false},
+		{"after used", code(current + 1), current, current + 1, true},
+		{"after older use", code(current), current - 1, current, true},
+	}
+	for _, test := range tests {
+		step, ok := Validate(rfcSecret, test.code, now, test.after)
+		if ok != test.ok || step != test.step {
+			t.Errorf("%s: Validate = %d, %v, want %d, %v", test.name, step, ok, test.step, test.ok)
+		}
+	}
+}